@@ -67,8 +67,49 @@ func migrate() {
         &models.User{},
         &models.PrintJob{},
         &models.Payment{},
+        &models.JobEvent{},
+        &models.PriceList{},
+        &models.PriceTier{},
+        &models.PaperSurcharge{},
+        &models.RevokedToken{},
+        &models.Refund{},
+        &models.PaymentEvent{},
+        &models.LedgerEntry{},
+        &models.PrinterSupply{},
+        &models.PrinterEvent{},
+        &models.PrinterCapabilities{},
+        &models.PrinterPool{},
+        &models.KioskCredential{},
+        &models.ReleaseFailure{},
+        &models.SchemaMigration{},
     )
 	if err != nil {
 		log.Fatal("Ошибка миграции: ", err)
 	}
+
+	// Задания, созданные до появления жизненного цикла, имели статус "pending"
+	err = migrateOnce("job_status_pending_to_created", func(tx *gorm.DB) error {
+		return tx.Model(&models.PrintJob{}).Where("status = ?", "pending").Update("status", models.JobStatusCreated).Error
+	})
+	if err != nil {
+		log.Fatal("Ошибка миграции статусов заданий: ", err)
+	}
+}
+
+// migrateOnce выполняет разовую миграцию данных id, если она ещё не записана
+// в schema_migrations. Миграция и отметка о ней идут в одной транзакции.
+func migrateOnce(id string, apply func(tx *gorm.DB) error) error {
+	var count int64
+	if err := DB.Model(&models.SchemaMigration{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := apply(tx); err != nil {
+			return err
+		}
+		return tx.Create(&models.SchemaMigration{ID: id, AppliedAt: time.Now()}).Error
+	})
 }
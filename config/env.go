@@ -0,0 +1,50 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnv возвращает переменную окружения или значение по умолчанию
+func GetEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// GetEnvInt читает целочисленную переменную окружения
+func GetEnvInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Некорректное значение %s=%q, используем %d", key, v, fallback)
+		return fallback
+	}
+	return n
+}
+
+// GetEnvDuration читает длительность в формате time.ParseDuration ("30s", "5m")
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Некорректное значение %s=%q, используем %s", key, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+// IsDevMode сообщает, что сервер запущен для локальной разработки (APP_ENV=development).
+// Только в этом режиме доступны тестовые заглушки вроде платёжного провайдера mock.
+func IsDevMode() bool {
+	return GetEnv("APP_ENV", "production") == "development"
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+    "log"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "print-automation/config"
+    "print-automation/models"
+    "print-automation/services"
+)
+
+// Найти принтеры в сети: mDNS/DNS-SD и, если указана подсеть (cidr), сканирование портов 631/9100/515.
+// partial в ответе означает, что сканирование не успело проверить все адреса.
+func DiscoverPrinters(c *gin.Context) {
+    opts := services.DefaultDiscoveryOptions()
+    if cidr, ok := c.GetQuery("cidr"); ok {
+        opts.CIDR = cidr
+    }
+    if mdns := c.Query("mdns"); mdns != "" {
+        enabled, err := strconv.ParseBool(mdns)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр mdns должен быть true или false"})
+            return
+        }
+        opts.MDNS = enabled
+    }
+    if timeout := c.Query("timeout"); timeout != "" {
+        d, err := time.ParseDuration(timeout)
+        if err != nil || d <= 0 || d > 30*time.Second {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр timeout должен быть длительностью до 30s"})
+            return
+        }
+        opts.Timeout = d
+    }
+
+    found, err := services.DiscoverPrinters(c.Request.Context(), opts)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, found)
+}
+
+// Зарегистрировать найденный принтер. Если протокол или порт не указаны,
+// они определяются сканированием адреса.
+func RegisterDiscoveredPrinter(c *gin.Context) {
+    var input struct {
+        Name      string `json:"name"`
+        IPAddress string `json:"ip_address" binding:"required"`
+        Port      int    `json:"port"`
+        Protocol  string `json:"protocol"`
+        Queue     string `json:"queue"`
+        Pool      string `json:"pool"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if input.Protocol == "" || input.Port == 0 {
+        opts := services.DefaultDiscoveryOptions()
+        opts.MDNS = false
+        opts.CIDR = input.IPAddress + "/32"
+        found, err := services.DiscoverPrinters(c.Request.Context(), opts)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if len(found.Printers) == 0 {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "По адресу не отвечает ни IPP, ни RAW, ни LPD"})
+            return
+        }
+        if input.Protocol == "" {
+            input.Protocol = found.Printers[0].Protocol
+            input.Port = found.Printers[0].Port
+        } else if input.Port == 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Не указан порт"})
+            return
+        }
+    }
+    if _, err := services.DriverFor(input.Protocol); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    var existing models.Printer
+    if err := config.DB.Where("ip_address = ? AND port = ?", input.IPAddress, input.Port).First(&existing).Error; err == nil {
+        c.JSON(http.StatusConflict, gin.H{"error": "Принтер уже зарегистрирован", "printer_id": existing.ID})
+        return
+    }
+
+    if input.Name == "" {
+        input.Name = input.IPAddress
+    }
+    printer := models.Printer{
+        Name:      input.Name,
+        IPAddress: input.IPAddress,
+        Port:      input.Port,
+        Protocol:  input.Protocol,
+        Queue:     input.Queue,
+        Pool:      input.Pool,
+        Status:    services.PrinterStatusUnknown,
+    }
+    if err := config.DB.Create(&printer).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    // Возможности IPP-принтера сразу запрашиваем у него самого
+    if printer.Protocol == "ipp" || printer.Protocol == "ipps" {
+        if _, err := services.RefreshPrinterCapabilities(c.Request.Context(), printer); err != nil {
+            log.Printf("Принтер %s: не удалось получить возможности: %v", printer.ID, err)
+        }
+    }
+    c.JSON(http.StatusCreated, printer)
+}
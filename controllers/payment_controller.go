@@ -1,65 +1,238 @@
 package controllers
 
 import (
+    "errors"
+    "io"
+    "log"
     "net/http"
 
     "github.com/gin-gonic/gin"
     "print-automation/config"
+    "print-automation/middleware"
     "print-automation/models"
+    "print-automation/services"
 )
 
-// Создать платёжную запись
+// Создать платёж за задание: сумма берётся из стоимости задания, платёж регистрируется у провайдера
 func CreatePayment(c *gin.Context) {
-    var payment models.Payment
-    if err := c.ShouldBindJSON(&payment); err != nil {
+    var input struct {
+        PrintJobID    string `json:"print_job_id" binding:"required"`
+        Provider      string `json:"provider" binding:"required"`
+        PaymentMethod string `json:"payment_method"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    if err := config.DB.Create(&payment).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    // Оплатить можно только своё задание
+    job, ok := findUserPrintJob(c, input.PrintJobID)
+    if !ok {
+        return
+    }
+
+    payment, intent, err := services.StartPayment(c.Request.Context(), job, input.Provider, input.PaymentMethod)
+    if err != nil {
+        if errors.Is(err, services.ErrUnknownPaymentProvider) || errors.Is(err, services.ErrMockPaymentsDisabled) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if errors.Is(err, services.ErrPaymentNotRequired) {
+            c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
         return
     }
-    c.JSON(http.StatusCreated, payment)
+
+    c.JSON(http.StatusCreated, gin.H{"payment": payment, "payment_url": intent.PaymentURL})
 }
 
-// Получить все платежи
+// Получить платежи: по своим заданиям, а операторам и администраторам — все
 func GetAllPayments(c *gin.Context) {
     var payments []models.Payment
-    if err := config.DB.Find(&payments).Error; err != nil {
+    query := config.DB
+    if user := middleware.CurrentUser(c); !services.HasPermission(user.Role, services.PermViewAllJobs) {
+        userJobs := config.DB.Model(&models.PrintJob{}).Select("id").Where("user_id = ?", user.ID)
+        query = query.Where("user_id = ? OR print_job_id IN (?)", user.ID, userJobs)
+    }
+    if err := query.Find(&payments).Error; err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
     c.JSON(http.StatusOK, payments)
 }
 
-// Обновить статус платежа
+// Запросить списание по платежу (оператор; для провайдеров с двухстадийной оплатой).
+// Клиент подтвердить свой платёж не может: успех приходит уведомлением провайдера.
+func CapturePayment(c *gin.Context) {
+    payment, ok := findUserPayment(c, c.Param("id"))
+    if !ok {
+        return
+    }
+
+    if err := services.CapturePayment(c.Request.Context(), payment); err != nil {
+        respondPaymentError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, payment)
+}
+
+// Изменить статус платежа вручную (оператор или администратор).
+// Возврат проводится через провайдера.
 func UpdatePayment(c *gin.Context) {
-    id := c.Param("id")
-    var payment models.Payment
-    if err := config.DB.First(&payment, "id = ?", id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Платёж не найден"})
+    payment, ok := findUserPayment(c, c.Param("id"))
+    if !ok {
         return
     }
 
     var input struct {
         Status        string `json:"status"`
         PaymentMethod string `json:"payment_method"`
-        TransactionID string `json:"transaction_id"`
     }
     if err := c.ShouldBindJSON(&input); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    payment.Status = input.Status
-    payment.PaymentMethod = input.PaymentMethod
-    payment.TransactionID = input.TransactionID
+    if input.PaymentMethod != "" && input.PaymentMethod != payment.PaymentMethod {
+        payment.PaymentMethod = input.PaymentMethod
+        if err := config.DB.Model(payment).Select("PaymentMethod", "UpdatedAt").Updates(payment).Error; err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+    }
+
+    if input.Status != "" && input.Status != payment.Status {
+        if !services.IsPaymentStatus(input.Status) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный статус платежа"})
+            return
+        }
+        var err error
+        if input.Status == models.PaymentStatusRefunded {
+            err = services.RefundPayment(c.Request.Context(), payment)
+        } else {
+            err = services.SetPaymentStatus(payment, input.Status)
+        }
+        if err != nil {
+            respondPaymentError(c, err)
+            return
+        }
+    }
+
+    c.JSON(http.StatusOK, payment)
+}
 
-    if err := config.DB.Save(&payment).Error; err != nil {
+// Список возвратов (оператор); фильтр по платежу или заданию
+func GetAllRefunds(c *gin.Context) {
+    var refunds []models.Refund
+    query := config.DB.Order("created_at DESC")
+    if paymentID := c.Query("payment_id"); paymentID != "" {
+        query = query.Where("payment_id = ?", paymentID)
+    }
+    if jobID := c.Query("print_job_id"); jobID != "" {
+        query = query.Where("print_job_id = ?", jobID)
+    }
+    if err := query.Find(&refunds).Error; err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
+    c.JSON(http.StatusOK, refunds)
+}
 
-    c.JSON(http.StatusOK, payment)
+// Повторить неудавшийся возврат (оператор)
+func RetryRefund(c *gin.Context) {
+    id := c.Param("id")
+    var refund models.Refund
+    if err := config.DB.First(&refund, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Возврат не найден"})
+        return
+    }
+
+    if err := services.RetryRefund(c.Request.Context(), &refund); err != nil {
+        if refund.Status == models.RefundStatusFailed && !errors.Is(err, services.ErrNothingToRefund) {
+            c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "refund": refund})
+            return
+        }
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, refund)
+}
+
+// Уведомление платёжного провайдера. Подлинность проверяется подписью, а не токеном.
+func PaymentWebhook(c *gin.Context) {
+    name := c.Param("provider")
+    provider, err := services.PaymentProviderFor(name)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
+
+    body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    event, err := provider.VerifyWebhook(c.Request.Header, body)
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidWebhookSignature) {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    payment, err := services.ApplyPaymentEvent(name, event)
+    switch {
+    case errors.Is(err, services.ErrPaymentNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    case errors.Is(err, services.ErrPaymentEventReplayed):
+        c.JSON(http.StatusOK, gin.H{"message": "Уведомление уже обработано", "status": payment.Status})
+    case errors.Is(err, services.ErrIllegalPaymentTransition):
+        // Устаревшее уведомление: повтор не поможет, поэтому отвечаем 200
+        log.Printf("Уведомление %s/%s пропущено: %v", name, event.EventID, err)
+        c.JSON(http.StatusOK, gin.H{"message": "Уведомление пропущено", "status": payment.Status})
+    case err != nil:
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    default:
+        c.JSON(http.StatusOK, gin.H{"message": "Уведомление обработано", "status": payment.Status})
+    }
+}
+
+// findUserPayment ищет платёж текущего пользователя: пополнение его кошелька
+// или оплату доступного ему задания
+func findUserPayment(c *gin.Context, id string) (*models.Payment, bool) {
+    var payment models.Payment
+    if err := config.DB.First(&payment, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Платёж не найден"})
+        return nil, false
+    }
+    if payment.Purpose == models.PaymentPurposeTopUp {
+        if !canViewUser(c, payment.UserID) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Платёж не найден"})
+            return nil, false
+        }
+        return &payment, true
+    }
+    if _, ok := findUserPrintJob(c, payment.PrintJobID); !ok {
+        return nil, false
+    }
+    return &payment, true
+}
+
+// respondPaymentError отвечает 409 на недопустимый переход платежа, 502 на отказ провайдера
+func respondPaymentError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, services.ErrIllegalPaymentTransition):
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+    case errors.Is(err, services.ErrUnknownPaymentProvider):
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    case errors.Is(err, services.ErrNothingToRefund), errors.Is(err, services.ErrInsufficientFunds):
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+    default:
+        c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+    }
 }
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+    "errors"
+    "net/http"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+    "print-automation/services"
+)
+
+// poolPendingStatuses — статусы, в которых задание пула ещё ждёт выбора принтера
+var poolPendingStatuses = []string{
+    models.JobStatusCreated,
+    models.JobStatusAwaitingPayment,
+    models.JobStatusQueued,
+    models.JobStatusHeld,
+}
+
+type poolInput struct {
+    Name        string `json:"name" binding:"required"`
+    Description string `json:"description"`
+    Policy      string `json:"policy"`
+}
+
+// validate проверяет политику пула; пустая означает least_pages
+func (in *poolInput) validate() error {
+    in.Name = strings.TrimSpace(in.Name)
+    if in.Name == "" {
+        return errors.New("Название пула не может быть пустым")
+    }
+    if in.Policy == "" {
+        in.Policy = models.PoolPolicyLeastPages
+    }
+    if !services.IsPoolPolicy(in.Policy) {
+        return errors.New("Неизвестная политика пула: " + in.Policy)
+    }
+    return nil
+}
+
+// Получить все пулы принтеров
+func GetAllPools(c *gin.Context) {
+    var pools []models.PrinterPool
+    if err := config.DB.Order("name").Find(&pools).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, pools)
+}
+
+// Получить пул вместе с его принтерами
+func GetPoolByID(c *gin.Context) {
+    id := c.Param("id")
+    var pool models.PrinterPool
+    if err := config.DB.First(&pool, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Пул не найден"})
+        return
+    }
+
+    var printers []models.Printer
+    if err := config.DB.Where("pool = ?", pool.Name).Order("name").Find(&printers).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"pool": pool, "printers": printers})
+}
+
+// Создать пул; принтеры входят в него по полю Pool
+func CreatePool(c *gin.Context) {
+    var input poolInput
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := input.validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    var count int64
+    if err := config.DB.Model(&models.PrinterPool{}).Where("name = ?", input.Name).Count(&count).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    if count > 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Пул с таким названием уже существует"})
+        return
+    }
+
+    pool := models.PrinterPool{Name: input.Name, Description: input.Description, Policy: input.Policy}
+    if err := config.DB.Create(&pool).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusCreated, pool)
+}
+
+// Обновить пул; при переименовании принтеры и ожидающие задания переходят на новое название
+func UpdatePool(c *gin.Context) {
+    id := c.Param("id")
+    var pool models.PrinterPool
+    if err := config.DB.First(&pool, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Пул не найден"})
+        return
+    }
+
+    var input poolInput
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := input.validate(); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    oldName := pool.Name
+    if input.Name != oldName {
+        var count int64
+        if err := config.DB.Model(&models.PrinterPool{}).Where("name = ? AND id <> ?", input.Name, pool.ID).Count(&count).Error; err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        if count > 0 {
+            c.JSON(http.StatusConflict, gin.H{"error": "Пул с таким названием уже существует"})
+            return
+        }
+    }
+
+    pool.Name = input.Name
+    pool.Description = input.Description
+    pool.Policy = input.Policy
+
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Save(&pool).Error; err != nil {
+            return err
+        }
+        if pool.Name == oldName {
+            return nil
+        }
+        if err := tx.Model(&models.Printer{}).Where("pool = ?", oldName).Update("pool", pool.Name).Error; err != nil {
+            return err
+        }
+        return tx.Model(&models.PrintJob{}).
+            Where("pool = ? AND status IN ?", oldName, poolPendingStatuses).
+            Update("pool", pool.Name).Error
+    })
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, pool)
+}
+
+// Удалить пул: принтеры остаются, но выходят из пула. Пока пулу адресованы
+// незавершённые задания, удалить его нельзя.
+func DeletePool(c *gin.Context) {
+    id := c.Param("id")
+    var pool models.PrinterPool
+    if err := config.DB.First(&pool, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Пул не найден"})
+        return
+    }
+
+    var pending int64
+    err := config.DB.Model(&models.PrintJob{}).
+        Where("pool = ? AND status IN ?", pool.Name, poolPendingStatuses).
+        Count(&pending).Error
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    if pending > 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Пулу адресованы незавершённые задания"})
+        return
+    }
+
+    err = config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(&models.Printer{}).Where("pool = ?", pool.Name).Update("pool", "").Error; err != nil {
+            return err
+        }
+        return tx.Delete(&models.PrinterPool{}, "id = ?", pool.ID).Error
+    })
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "Пул удалён"})
+}
+
+// respondPoolError отвечает 422, если в пуле нет подходящего принтера, и 500 на прочие ошибки
+func respondPoolError(c *gin.Context, err error) {
+    if errors.Is(err, services.ErrPoolEmpty) || errors.Is(err, services.ErrPoolUnavailable) {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+    "print-automation/services"
+)
+
+// Получить все прайс-листы
+func GetAllPriceLists(c *gin.Context) {
+    var lists []models.PriceList
+    if err := config.DB.Preload("Tiers").Preload("PaperSurcharges").Find(&lists).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, lists)
+}
+
+// Создать прайс-лист (для принтера или общий, если PrinterID пуст)
+func CreatePriceList(c *gin.Context) {
+    var list models.PriceList
+    if err := c.ShouldBindJSON(&list); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := services.ValidatePriceList(&list); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if err := config.DB.Create(&list).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusCreated, list)
+}
+
+// Обновить прайс-лист; ступени скидок и доплаты за формат заменяются целиком
+func UpdatePriceList(c *gin.Context) {
+    id := c.Param("id")
+    var list models.PriceList
+    if err := config.DB.First(&list, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Прайс-лист не найден"})
+        return
+    }
+
+    var input models.PriceList
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := services.ValidatePriceList(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    list.PrinterID = input.PrinterID
+    list.Name = input.Name
+    list.PerPage = input.PerPage
+    list.ColorPerPage = input.ColorPerPage
+    list.PerCopy = input.PerCopy
+    list.DuplexDiscount = input.DuplexDiscount
+    list.MinCharge = input.MinCharge
+
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Omit("Tiers", "PaperSurcharges").Save(&list).Error; err != nil {
+            return err
+        }
+        if err := tx.Delete(&models.PriceTier{}, "price_list_id = ?", list.ID).Error; err != nil {
+            return err
+        }
+        if err := tx.Delete(&models.PaperSurcharge{}, "price_list_id = ?", list.ID).Error; err != nil {
+            return err
+        }
+        for i := range input.Tiers {
+            input.Tiers[i].PriceListID = list.ID
+            if err := tx.Create(&input.Tiers[i]).Error; err != nil {
+                return err
+            }
+        }
+        for i := range input.PaperSurcharges {
+            input.PaperSurcharges[i].PriceListID = list.ID
+            if err := tx.Create(&input.PaperSurcharges[i]).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    list.Tiers = input.Tiers
+    list.PaperSurcharges = input.PaperSurcharges
+    c.JSON(http.StatusOK, list)
+}
+
+// Удалить прайс-лист
+func DeletePriceList(c *gin.Context) {
+    id := c.Param("id")
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Delete(&models.PriceTier{}, "price_list_id = ?", id).Error; err != nil {
+            return err
+        }
+        if err := tx.Delete(&models.PaperSurcharge{}, "price_list_id = ?", id).Error; err != nil {
+            return err
+        }
+        return tx.Delete(&models.PriceList{}, "id = ?", id).Error
+    })
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "Прайс-лист удалён"})
+}
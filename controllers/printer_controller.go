@@ -1,6 +1,7 @@
 package controllers
 
 import (
+    "errors"
     "net/http"
 
     "github.com/gin-gonic/gin"
@@ -28,6 +29,11 @@ func CreatePrinter(c *gin.Context) {
         return
     }
 
+    // Доступность и состояние определит монитор принтеров
+    printer.IsOnline = false
+    printer.Status = services.PrinterStatusUnknown
+    printer.LastSeenAt = nil
+
     if err := config.DB.Create(&printer).Error; err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -45,14 +51,153 @@ func CheckPrinterConnectionHandler(c *gin.Context) {
         return
     }
 
-    // 2. Пытаемся открыть TCP-соединение 
-    err := services.CheckPrinterConnection(printer.IPAddress, printer.Port)
+    // 2. Проверяем доступность драйвером протокола и состояние по SNMP
+    health, err := services.CheckPrinter(c.Request.Context(), &printer)
+    if err != nil {
+        if health == nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    status := "online"
+    switch {
+    case !health.Online:
+        status = "offline"
+    case !services.IsPrinterReady(health.Status):
+        status = "error"
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "status":     status,
+        "message":    health.Message,
+        "snmp":       health.SNMP,
+        "snmp_error": health.SNMPError,
+    })
+}
+
+// История доступности и состояния принтера
+func GetPrinterEvents(c *gin.Context) {
+    id := c.Param("id")
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
+        return
+    }
+
+    var events []models.PrinterEvent
+    if err := config.DB.Where("printer_id = ?", printer.ID).Order("created_at DESC").Limit(200).Find(&events).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, events)
+}
+
+// Расходные материалы принтера по данным последнего опроса SNMP
+func GetPrinterSupplies(c *gin.Context) {
+    id := c.Param("id")
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
+        return
+    }
+
+    var supplies []models.PrinterSupply
+    if err := config.DB.Where("printer_id = ?", printer.ID).Order("supply_index").Find(&supplies).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, supplies)
+}
+
+// Возможности принтера (форматы, цвет, дуплекс, бумага, лотки)
+func GetPrinterCapabilities(c *gin.Context) {
+    id := c.Param("id")
+    caps, err := services.PrinterCapabilitiesFor(id)
     if err != nil {
-        c.JSON(http.StatusOK, gin.H{"status": "offline", "message": err.Error()})
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    if caps == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Возможности принтера не заданы"})
+        return
+    }
+    c.JSON(http.StatusOK, caps)
+}
+
+// Задать возможности принтера вручную (для принтеров без IPP)
+func UpdatePrinterCapabilities(c *gin.Context) {
+    id := c.Param("id")
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
+        return
+    }
+
+    var input struct {
+        MakeAndModel    string   `json:"make_and_model"`
+        DocumentFormats []string `json:"document_formats"`
+        ColorSupported  bool     `json:"color_supported"`
+        DuplexModes     []string `json:"duplex_modes"`
+        MediaSizes      []string `json:"media_sizes"`
+        Trays           []string `json:"trays"`
+        MaxCopies       int      `json:"max_copies"`
+        Resolutions     []string `json:"resolutions"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if input.MaxCopies < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Максимальное число копий не может быть отрицательным"})
+        return
+    }
+    for _, mode := range input.DuplexModes {
+        if mode != models.SidesOneSided && mode != models.SidesTwoSidedLongEdge && mode != models.SidesTwoSidedShortEdge {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный режим печати сторон: " + mode})
+            return
+        }
+    }
+
+    caps := models.PrinterCapabilities{
+        PrinterID:       printer.ID,
+        Source:          models.CapabilitiesSourceManual,
+        MakeAndModel:    input.MakeAndModel,
+        DocumentFormats: services.JoinList(input.DocumentFormats),
+        ColorSupported:  input.ColorSupported,
+        DuplexModes:     services.JoinList(input.DuplexModes),
+        MediaSizes:      services.JoinList(input.MediaSizes),
+        Trays:           services.JoinList(input.Trays),
+        MaxCopies:       input.MaxCopies,
+        Resolutions:     services.JoinList(input.Resolutions),
+    }
+    if err := services.SavePrinterCapabilities(&caps); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, caps)
+}
+
+// Запросить возможности принтера по IPP (Get-Printer-Attributes) и сохранить их
+func RefreshPrinterCapabilities(c *gin.Context) {
+    id := c.Param("id")
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"status": "online", "message": "Успешное соединение"})
+    caps, err := services.RefreshPrinterCapabilities(c.Request.Context(), printer)
+    if err != nil {
+        if errors.Is(err, services.ErrCapabilitiesNotQueryable) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, caps)
 }
 
 // Получить конкретный принтер
@@ -85,8 +230,17 @@ func UpdatePrinter(c *gin.Context) {
     printer.IPAddress = input.IPAddress
     printer.Port = input.Port
     printer.Protocol = input.Protocol
-    printer.IsOnline = input.IsOnline
-    printer.Status = input.Status
+    printer.Queue = input.Queue
+    printer.BannerPage = input.BannerPage
+    printer.Pool = input.Pool
+    printer.Location = input.Location
+    if input.SNMPVersion != "" {
+        printer.SNMPVersion = input.SNMPVersion
+    }
+    if input.SNMPCommunity != "" {
+        printer.SNMPCommunity = input.SNMPCommunity
+    }
+    // IsOnline и Status ведёт монитор принтеров
 
     if err := config.DB.Save(&printer).Error; err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -99,6 +253,22 @@ func UpdatePrinter(c *gin.Context) {
 // Удалить принтер
 func DeletePrinter(c *gin.Context) {
     id := c.Param("id")
+    if err := config.DB.Delete(&models.PrinterSupply{}, "printer_id = ?", id).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    if err := config.DB.Delete(&models.PrinterEvent{}, "printer_id = ?", id).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    if err := config.DB.Delete(&models.PrinterCapabilities{}, "printer_id = ?", id).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    if err := config.DB.Delete(&models.KioskCredential{}, "printer_id = ?", id).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
     if err := config.DB.Delete(&models.Printer{}, "id = ?", id).Error; err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
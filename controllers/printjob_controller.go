@@ -1,151 +1,607 @@
 package controllers
 
 import (
+    "errors"
+    "io"
+    "log"
     "net/http"
-	"fmt"
-    "os"
-	"io"
     "path/filepath"
+    "strings"
+    "time"
     "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
     "print-automation/config"
+    "print-automation/middleware"
     "print-automation/models"
 	"print-automation/services"
 
 )
 
-// DownloadAndSendToPrinter скачивает файл по URL и отправляет на принтер
-func DownloadAndSendToPrinter(jobID, fileURL, printerIP string, printerPort int) error {
-    // Скачиваем файл во временную директорию
-    tempFilePath := fmt.Sprintf("/tmp/%s.pdf", jobID)
-    err := downloadFile(tempFilePath, fileURL)
+// createPrintJobInput — поля задания, которые задаёт клиент. Ключи JSON те же,
+// что у models.PrintJob; остальные поля (статус, документ, оплата, попытки
+// отправки) ведёт сервер, и новое поле модели клиенту по умолчанию недоступно.
+type createPrintJobInput struct {
+    PrinterID     string
+    Pool          string
+    Location      string
+    FileURL       string
+    Copies        int
+    Pages         int
+    ColorMode     string
+    Sides         string
+    MediaSize     string
+    PageRanges    string
+    NumberUp      int
+    Orientation   string
+    Collate       *bool
+    SecureRelease bool
+    Priority      int
+    NotBefore     *time.Time
+}
+
+// Создать задание на печать
+func CreatePrintJob(c *gin.Context) {
+    var input createPrintJobInput
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    if input.Pages < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Число страниц не может быть отрицательным"})
+        return
+    }
+
+    // Владелец задания — текущий пользователь
+    user := middleware.CurrentUser(c)
+    job := models.PrintJob{
+        UserID:        user.ID,
+        PrinterID:     input.PrinterID,
+        Pool:          input.Pool,
+        Location:      input.Location,
+        FileURL:       input.FileURL,
+        Status:        models.JobStatusCreated,
+        Copies:        input.Copies,
+        Pages:         input.Pages,
+        ColorMode:     input.ColorMode,
+        Sides:         input.Sides,
+        MediaSize:     input.MediaSize,
+        PageRanges:    input.PageRanges,
+        NumberUp:      input.NumberUp,
+        Orientation:   input.Orientation,
+        Collate:       input.Collate,
+        SecureRelease: input.SecureRelease,
+        Priority:      input.Priority,
+        NotBefore:     input.NotBefore,
+    }
+
+    // Проверяем параметры печати
+    if err := services.NormalizeJobOptions(&job); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    // Срочный приоритет клиенту недоступен — его назначает оператор
+    if err := services.NormalizeJobSchedule(&job, services.HasPermission(user.Role, services.PermManageQueue)); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    // Задание пулу: принтер выберет очередь при отправке, а пока цену и параметры
+    // считаем по самому дорогому подходящему принтеру пула. При отправке годятся
+    // только принтеры, печать на которых не дороже этой цены.
+    if job.Pool != "" {
+        printer, err := services.QuotePoolPrinter(&job)
+        if err != nil {
+            respondPoolError(c, err)
+            return
+        }
+        job.PrinterID = printer.ID
+    }
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", job.PrinterID).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
+        return
+    }
+    // Параметры должны быть выполнимы на выбранном принтере
+    if err := services.CheckJobCapabilities(&job); err != nil {
+        var optErr *services.JobOptionsError
+        if errors.As(err, &optErr) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    // Стоимость считает сервер по прайс-листу принтера
+    quote, err := services.QuoteJob(&job)
     if err != nil {
-        return fmt.Errorf("Ошибка скачивания файла: %v", err)
+        respondPricingError(c, err)
+        return
     }
-    defer os.Remove(tempFilePath) // Почистим за собой после отправки
+    job.Cost = quote.Total
 
-    // Отправляем на принтер
-    err = services.SendToPrinterRaw(tempFilePath, printerIP, printerPort)
+    err = config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(&job).Error; err != nil {
+            return err
+        }
+        return services.RecordJobEvent(tx, job.ID, "", job.Status, services.UserActor(user.ID), "задание создано")
+    })
     if err != nil {
-        return fmt.Errorf("Ошибка отправки на принтер: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    // Документ по ссылке скачиваем сразу: цена зависит от фактического числа страниц
+    if job.FileURL != "" {
+        if err := services.ImportJobDocument(c.Request.Context(), &job); err != nil {
+            // Задание без документа не нужно: удаляем его, чтобы клиент создал новое
+            delErr := config.DB.Transaction(func(tx *gorm.DB) error {
+                if err := tx.Delete(&models.JobEvent{}, "print_job_id = ?", job.ID).Error; err != nil {
+                    return err
+                }
+                return tx.Delete(&models.PrintJob{}, "id = ?", job.ID).Error
+            })
+            if delErr != nil {
+                log.Printf("Задание %s: не удалось удалить после ошибки загрузки документа: %v", job.ID, delErr)
+            }
+            if services.IsDocumentRejected(err) {
+                c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+                return
+            }
+            c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+            return
+        }
     }
-    return nil
+
+    c.JSON(http.StatusCreated, job)
 }
 
+// Предварительный расчёт стоимости задания без его создания
+func QuotePrintJob(c *gin.Context) {
+    var input struct {
+        PrinterID  string `form:"printer_id"`
+        Pool       string `form:"pool"`
+        Location   string `form:"location"`
+        Pages      int    `form:"pages"`
+        Copies     int    `form:"copies"`
+        ColorMode  string `form:"color_mode"`
+        Sides      string `form:"sides"`
+        MediaSize  string `form:"media_size"`
+        PageRanges string `form:"page_ranges"`
+        NumberUp   int    `form:"number_up"`
+    }
+    if err := c.ShouldBindQuery(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if input.Pages < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Число страниц не может быть отрицательным"})
+        return
+    }
 
-// downloadFile сохраняет файл по HTTP-ссылке localPath
-func downloadFile(localPath, url string) error {
-    resp, err := http.Get(url)
-    if err != nil {
-        return err
+    if input.PrinterID == "" && input.Pool == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите принтер или пул"})
+        return
     }
-    defer resp.Body.Close()
 
-    out, err := os.Create(localPath)
-    if err != nil {
-        return err
+    job := models.PrintJob{
+        PrinterID:  input.PrinterID,
+        Pool:       input.Pool,
+        Location:   input.Location,
+        Pages:      input.Pages,
+        Copies:     input.Copies,
+        ColorMode:  input.ColorMode,
+        Sides:      input.Sides,
+        MediaSize:  input.MediaSize,
+        PageRanges: input.PageRanges,
+        NumberUp:   input.NumberUp,
+    }
+    if err := services.NormalizeJobOptions(&job); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if job.Pool != "" {
+        printer, err := services.QuotePoolPrinter(&job)
+        if err != nil {
+            respondPoolError(c, err)
+            return
+        }
+        job.PrinterID = printer.ID
     }
-    defer out.Close()
 
-    _, err = io.Copy(out, resp.Body)
-    return err
+    quote, err := services.QuoteJob(&job)
+    if err != nil {
+        respondPricingError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, quote)
 }
 
 
-// Создать задание на печать
-func CreatePrintJob(c *gin.Context) {
+// Изменить приоритет и время печати ожидающего задания (оператор)
+func ReschedulePrintJob(c *gin.Context) {
+    id := c.Param("id")
     var job models.PrintJob
-    if err := c.ShouldBindJSON(&job); err != nil {
+    if err := config.DB.First(&job, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Задание не найдено"})
+        return
+    }
+
+    var input struct {
+        Priority  int        `json:"priority" binding:"required"`
+        NotBefore *time.Time `json:"not_before"`
+        Reason    string     `json:"reason"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    if err := config.DB.Create(&job).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    user := middleware.CurrentUser(c)
+    err := services.RescheduleJob(&job, input.Priority, input.NotBefore, services.UserActor(user.ID), input.Reason)
+    if err != nil {
+        var optErr *services.JobOptionsError
+        switch {
+        case errors.As(err, &optErr):
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        case errors.Is(err, services.ErrNotReschedulable):
+            c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+        default:
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        }
         return
     }
-    c.JSON(http.StatusCreated, job)
+    c.JSON(http.StatusOK, job)
 }
 
-
-// Получить все задания
+// Получить все задания: свои, а операторам и администраторам — все
 func GetAllPrintJobs(c *gin.Context) {
     var jobs []models.PrintJob
-    if err := config.DB.Find(&jobs).Error; err != nil {
+    if err := scopeToCaller(c, config.DB, "user_id").Find(&jobs).Error; err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
     c.JSON(http.StatusOK, jobs)
 }
 
-// Отправить готовое задание на принтер
+// Поставить готовое задание в очередь печати
 func SendPrintJobHandler(c *gin.Context) {
     jobID := c.Param("id")
 
     // 1. Ищем задание
-    var job models.PrintJob
-    if err := config.DB.First(&job, "id = ?", jobID).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Задание не найдено"})
+    job, ok := findUserPrintJob(c, jobID)
+    if !ok {
         return
     }
 
-    // 2. Ищем принтер
+    // 2. Проверяем, что принтер существует
     var printer models.Printer
     if err := config.DB.First(&printer, "id = ?", job.PrinterID).Error; err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
         return
     }
 
-    // 3. Проверяем путь к файлу
-    if job.FileURL == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Нет пути к файлу (job.FileURL) для печати"})
+    // 3. Проверяем, что есть документ: загруженный или по ссылке
+    if job.DocumentKey == "" && job.FileURL == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Нет документа для печати: загрузите файл или укажите FileURL"})
         return
     }
 
-    // Если fileURL — HTTP-ссылка, скачиваем и отправляем
-    err := DownloadAndSendToPrinter(job.ID, job.FileURL, printer.IPAddress, printer.Port)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    // 4. Ставим в очередь, если задание оплачено или покрыто бесплатной квотой
+    if err := services.ReleaseJob(job, services.UserActor(middleware.CurrentUser(c).ID), "отправка на печать"); err != nil {
+        if errors.Is(err, services.ErrPaymentRequired) {
+            message := "Задание нужно оплатить перед печатью"
+            if errors.Is(err, services.ErrInsufficientFunds) {
+                message = "Недостаточно средств на балансе"
+            }
+            balance, _ := services.WalletBalance(job.UserID)
+            c.JSON(http.StatusPaymentRequired, gin.H{
+                "error":   message,
+                "job_id":  job.ID,
+                "status":  job.Status,
+                "cost":    job.Cost,
+                "balance": balance,
+            })
+            return
+        }
+        respondJobError(c, err)
         return
     }
 
-    // 4. Обновляем статус задания в БД
-    job.Status = "printing"
-    if err := config.DB.Save(&job).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    // Задание с выпуском у принтера ждёт владельца: выдаём PIN-код
+    if job.SecureRelease && job.Status == models.JobStatusHeld {
+        pin, err := services.IssueReleasePIN(job)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusAccepted, gin.H{
+            "message":    "Задание ожидает выпуска у принтера: введите PIN-код на киоске или отсканируйте QR-код",
+            "job_id":     jobID,
+            "status":     job.Status,
+            "pin":        pin,
+            "expires_at": job.ReleaseExpiresAt,
+        })
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{
-        "message":  "Задание отправлено на принтер",
+    c.JSON(http.StatusAccepted, gin.H{
+        "message":  "Задание поставлено в очередь печати",
         "job_id":   jobID,
+        "status":   job.Status,
         "filePath": filepath.Base(job.FileURL),
     })
 }
 
-// Обновить статус задания
+// Загрузить документ задания: multipart (поле "file") или тело запроса целиком
+func UploadPrintJobDocument(c *gin.Context) {
+    id := c.Param("id")
+    job, ok := findUserPrintJob(c, id)
+    if !ok {
+        return
+    }
+
+    // Документ можно заменить только до постановки в очередь
+    if job.Status != models.JobStatusCreated && job.Status != models.JobStatusAwaitingPayment {
+        c.JSON(http.StatusConflict, gin.H{"error": "Документ нельзя изменить в статусе " + job.Status})
+        return
+    }
+
+    var (
+        body     io.Reader
+        fileName string
+        mimeType string
+    )
+    if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+        fileHeader, err := c.FormFile("file")
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Не передан файл (поле file)"})
+            return
+        }
+        f, err := fileHeader.Open()
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        defer f.Close()
+        body = f
+        fileName = fileHeader.Filename
+        mimeType = fileHeader.Header.Get("Content-Type")
+    } else {
+        body = c.Request.Body
+        fileName = c.Query("filename")
+        mimeType = c.ContentType()
+    }
+
+    if err := services.StoreJobDocument(c.Request.Context(), job, body, fileName, mimeType); err != nil {
+        if errors.Is(err, services.ErrDocumentTooLarge) {
+            c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+            return
+        }
+        if services.IsDocumentRejected(err) || errors.Is(err, services.ErrNoPriceList) {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    c.JSON(http.StatusOK, job)
+}
+
+// Обновить статус задания (стоимость считает сервер и через API не меняется)
 func UpdatePrintJob(c *gin.Context) {
     id := c.Param("id")
-    var job models.PrintJob
-    if err := config.DB.First(&job, "id = ?", id).Error; err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Задание не найдено"})
+    job, ok := findUserPrintJob(c, id)
+    if !ok {
         return
     }
 
     var input struct {
-        Status string  `json:"status"`
-        Cost   float64 `json:"cost"`
+        Status string `json:"status"`
+        Reason string `json:"reason"`
     }
     if err := c.ShouldBindJSON(&input); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
-    job.Status = input.Status
-    job.Cost = input.Cost
-    if err := config.DB.Save(&job).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    // Смена статуса идёт через те же проверки, что и отдельные запросы
+    // send/hold/release/cancel: оплата, квота и выпуск у принтера не обходятся
+    if input.Status != "" && input.Status != job.Status {
+        if !services.IsJobStatus(input.Status) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный статус задания"})
+            return
+        }
+        // Клиент может только отменить своё задание; прочими переходами управляет оператор
+        user := middleware.CurrentUser(c)
+        if input.Status != models.JobStatusCanceled && !services.HasPermission(user.Role, services.PermManageQueue) {
+            c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+            return
+        }
+        actor := services.UserActor(user.ID)
+        var err error
+        switch input.Status {
+        case models.JobStatusCanceled:
+            err = services.CancelJob(c.Request.Context(), job, actor, input.Reason)
+        case models.JobStatusHeld:
+            err = services.HoldJob(job, actor, input.Reason)
+        case models.JobStatusQueued:
+            if job.Status == models.JobStatusHeld {
+                err = services.ResumeJob(job, actor, input.Reason)
+                break
+            }
+            if job.DocumentKey == "" && job.FileURL == "" {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Нет документа для печати: загрузите файл или укажите FileURL"})
+                return
+            }
+            reason := input.Reason
+            if reason == "" {
+                reason = "отправка на печать"
+            }
+            err = services.ReleaseJob(job, actor, reason)
+        case models.JobStatusCompleted, models.JobStatusFailed:
+            if job.Status != models.JobStatusNeedsCheck {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Итог печати задаёт система; вручную — только для заданий в needs_check"})
+                return
+            }
+            err = services.ResolveJob(job, input.Status, actor, input.Reason)
+        default:
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Этот статус задаёт система; допустимы queued, held и canceled"})
+            return
+        }
+        if errors.Is(err, services.ErrPaymentRequired) {
+            c.JSON(http.StatusPaymentRequired, gin.H{"error": "Задание нужно оплатить перед печатью", "job_id": job.ID, "cost": job.Cost})
+            return
+        }
+        if err != nil {
+            respondJobError(c, err)
+            return
+        }
+    }
+
+    c.JSON(http.StatusOK, job)
+}
+
+// jobControlInput — необязательное тело запросов отмены, удержания и возврата в очередь
+type jobControlInput struct {
+    Reason string `json:"reason"`
+}
+
+// bindJobControl читает причину; пустое тело допустимо
+func bindJobControl(c *gin.Context) (jobControlInput, bool) {
+    var input jobControlInput
+    if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return input, false
+    }
+    return input, true
+}
+
+// Отменить задание: из очереди, во время передачи или печати
+func CancelPrintJob(c *gin.Context) {
+    job, ok := findUserPrintJob(c, c.Param("id"))
+    if !ok {
+        return
+    }
+    input, ok := bindJobControl(c)
+    if !ok {
         return
     }
 
+    user := middleware.CurrentUser(c)
+    if err := services.CancelJob(c.Request.Context(), job, services.UserActor(user.ID), input.Reason); err != nil {
+        respondJobError(c, err)
+        return
+    }
     c.JSON(http.StatusOK, job)
+}
+
+// Удержать задание в очереди, не теряя места
+func HoldPrintJob(c *gin.Context) {
+    job, ok := findUserPrintJob(c, c.Param("id"))
+    if !ok {
+        return
+    }
+    input, ok := bindJobControl(c)
+    if !ok {
+        return
+    }
+
+    user := middleware.CurrentUser(c)
+    if err := services.HoldJob(job, services.UserActor(user.ID), input.Reason); err != nil {
+        respondJobError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, job)
+}
+
+// Вернуть удержанное задание в очередь печати
+func ResumePrintJob(c *gin.Context) {
+    job, ok := findUserPrintJob(c, c.Param("id"))
+    if !ok {
+        return
+    }
+    input, ok := bindJobControl(c)
+    if !ok {
+        return
+    }
+    // Задание, удержанное до постановки в очередь, отправляется как новое
+    if job.QueuedAt == nil && job.DocumentKey == "" && job.FileURL == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Нет документа для печати: загрузите файл или укажите FileURL"})
+        return
+    }
+
+    user := middleware.CurrentUser(c)
+    if err := services.ResumeJob(job, services.UserActor(user.ID), input.Reason); err != nil {
+        if errors.Is(err, services.ErrPaymentRequired) {
+            c.JSON(http.StatusPaymentRequired, gin.H{"error": "Задание нужно оплатить перед печатью", "job_id": job.ID, "cost": job.Cost})
+            return
+        }
+        respondJobError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, job)
+}
+
+// История смены статусов задания
+func GetPrintJobEvents(c *gin.Context) {
+    id := c.Param("id")
+    job, ok := findUserPrintJob(c, id)
+    if !ok {
+        return
+    }
+
+    var events []models.JobEvent
+    if err := config.DB.Where("print_job_id = ?", job.ID).Order("created_at").Find(&events).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, events)
+}
+
+// scopeToCaller ограничивает запрос записями текущего пользователя,
+// если у него нет права видеть задания и платежи всех пользователей
+func scopeToCaller(c *gin.Context, db *gorm.DB, userColumn string) *gorm.DB {
+    user := middleware.CurrentUser(c)
+    if services.HasPermission(user.Role, services.PermViewAllJobs) {
+        return db
+    }
+    return db.Where(userColumn+" = ?", user.ID)
+}
+
+// findUserPrintJob ищет задание, доступное текущему пользователю; чужие задания
+// не отличаются от несуществующих, чтобы не раскрывать их ID
+func findUserPrintJob(c *gin.Context, id string) (*models.PrintJob, bool) {
+    var job models.PrintJob
+    err := scopeToCaller(c, config.DB.Where("id = ?", id), "user_id").First(&job).Error
+    if err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Задание не найдено"})
+        } else {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        }
+        return nil, false
+    }
+    return &job, true
+}
+
+// respondPricingError отвечает 422, если цену посчитать нельзя из-за настроек
+func respondPricingError(c *gin.Context, err error) {
+    if errors.Is(err, services.ErrNoPriceList) {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// respondJobError отвечает 409 на недопустимый переход статуса (и отмену, которую
+// принтер не выполняет) и 500 на прочие ошибки
+func respondJobError(c *gin.Context, err error) {
+    if errors.Is(err, services.ErrIllegalTransition) || errors.Is(err, services.ErrCancelNotSupported) ||
+        errors.Is(err, services.ErrAwaitingRelease) {
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
\ No newline at end of file
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+    "print-automation/config"
+    "print-automation/middleware"
+    "print-automation/models"
+    "print-automation/services"
+)
+
+// Выдать новый PIN-код для выпуска своего удержанного задания (прежний перестаёт действовать)
+func IssueReleasePIN(c *gin.Context) {
+    job, ok := findUserPrintJob(c, c.Param("id"))
+    if !ok {
+        return
+    }
+
+    pin, err := services.IssueReleasePIN(job)
+    if err != nil {
+        respondReleaseError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{
+        "job_id":     job.ID,
+        "pin":        pin,
+        "expires_at": job.ReleaseExpiresAt,
+    })
+}
+
+// Выпустить свои удержанные задания на принтер, QR-код киоска которого отсканирован
+func ReleaseByQR(c *gin.Context) {
+    var input struct {
+        Token      string `json:"token" binding:"required"`
+        PrintJobID string `json:"print_job_id"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    printerID, err := services.VerifyKioskToken(input.Token)
+    if err != nil {
+        respondReleaseError(c, err)
+        return
+    }
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", printerID).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
+        return
+    }
+
+    jobs, err := services.ReleaseForUser(printer, middleware.CurrentUser(c).ID, input.PrintJobID)
+    if err != nil {
+        respondReleaseError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"printer_id": printer.ID, "released": jobs})
+}
+
+// Страница киоска: принтер и QR-код для выпуска заданий (код обновляется каждые пару минут)
+func KioskPrinterInfo(c *gin.Context) {
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", c.Param("id")).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
+        return
+    }
+
+    token, expires := services.KioskToken(printer.ID)
+    c.JSON(http.StatusOK, gin.H{
+        "printer_id": printer.ID,
+        "name":       printer.Name,
+        "is_online":  printer.IsOnline,
+        "status":     printer.Status,
+        "qr_token":   token,
+        "expires_at": expires,
+    })
+}
+
+// Выпустить задание PIN-кодом, введённым на киоске принтера
+func KioskReleaseByPIN(c *gin.Context) {
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", c.Param("id")).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
+        return
+    }
+
+    var input struct {
+        PIN string `json:"pin" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    job, err := services.ReleaseByPIN(printer, input.PIN)
+    if err != nil {
+        respondReleaseError(c, err)
+        return
+    }
+    // Киоску не нужны подробности задания — только подтверждение
+    c.JSON(http.StatusOK, gin.H{
+        "message":       "Задание отправлено на печать",
+        "job_id":        job.ID,
+        "document_name": job.DocumentName,
+        "pages":         job.Pages,
+        "copies":        job.Copies,
+    })
+}
+
+// Выдать киоску принтера новый ключ (прежний перестаёт действовать); ключ показывается один раз
+func IssueKioskKey(c *gin.Context) {
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", c.Param("id")).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Принтер не найден"})
+        return
+    }
+
+    key, err := services.IssueKioskKey(printer.ID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusCreated, gin.H{"printer_id": printer.ID, "kiosk_key": key})
+}
+
+// Отключить киоск принтера
+func RevokeKioskKey(c *gin.Context) {
+    if err := services.RevokeKioskKey(c.Param("id")); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"message": "Ключ киоска отозван"})
+}
+
+// respondReleaseError отвечает на ошибки выпуска задания у принтера
+func respondReleaseError(c *gin.Context, err error) {
+    var optErr *services.JobOptionsError
+    switch {
+    case errors.Is(err, services.ErrReleaseCodeInvalid), errors.Is(err, services.ErrKioskTokenInvalid):
+        c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+    case errors.Is(err, services.ErrTooManyReleaseAttempts):
+        c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+    case errors.Is(err, services.ErrNotReleasable), errors.Is(err, services.ErrIllegalTransition):
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+    case errors.As(err, &optErr), errors.Is(err, services.ErrNoPriceList):
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+    default:
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    }
+}
@@ -5,7 +5,9 @@ import (
 
     "github.com/gin-gonic/gin"
     "print-automation/config"
+    "print-automation/middleware"
     "print-automation/models"
+    "print-automation/services"
     "golang.org/x/crypto/bcrypt"
 )
 
@@ -24,6 +26,10 @@ func CreateUser(c *gin.Context) {
         return
     }
     user.PasswordHash = string(hashedPassword)
+    user.FreePages = 0
+
+    // Роль при регистрации не выбирается; первый администратор создаётся при запуске (ADMIN_EMAIL)
+    user.Role = models.RoleCustomer
 
     if err := config.DB.Create(&user).Error; err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -32,18 +38,87 @@ func CreateUser(c *gin.Context) {
     c.JSON(http.StatusCreated, user)
 }
 
-// Получить пользователя по ID
+// Получить пользователя по ID (свой профиль или любой — для администратора)
 func GetUserByID(c *gin.Context) {
+    id := c.Param("id")
+    current := middleware.CurrentUser(c)
+    if id != current.ID && !services.HasPermission(current.Role, services.PermManageUsers) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+        return
+    }
+    var user models.User
+    if err := config.DB.First(&user, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+        return
+    }
+    c.JSON(http.StatusOK, user)
+}
+
+// Назначить роль пользователю
+func UpdateUserRole(c *gin.Context) {
+    id := c.Param("id")
+    var user models.User
+    if err := config.DB.First(&user, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+        return
+    }
+
+    var input struct {
+        Role string `json:"role" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if !services.IsRole(input.Role) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестная роль"})
+        return
+    }
+
+    // Иначе можно случайно остаться без администратора
+    if user.ID == middleware.CurrentUser(c).ID {
+        c.JSON(http.StatusConflict, gin.H{"error": "Нельзя изменить собственную роль"})
+        return
+    }
+
+    user.Role = input.Role
+    if err := config.DB.Model(&user).Select("Role", "UpdatedAt").Updates(&user).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, user)
+}
+
+// Установить остаток бесплатной квоты пользователя (в оттисках)
+func UpdateUserFreePages(c *gin.Context) {
     id := c.Param("id")
     var user models.User
     if err := config.DB.First(&user, "id = ?", id).Error; err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
         return
     }
+
+    var input struct {
+        FreePages *int `json:"free_pages" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if *input.FreePages < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Квота не может быть отрицательной"})
+        return
+    }
+
+    user.FreePages = *input.FreePages
+    if err := config.DB.Model(&user).Select("FreePages", "UpdatedAt").Updates(&user).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
     c.JSON(http.StatusOK, user)
 }
 
-// Авторизация (упрощённый пример)
+// Авторизация: выдаёт пару access/refresh JWT
 func LoginUser(c *gin.Context) {
     var credentials struct {
         Email    string `json:"email"`
@@ -66,5 +141,93 @@ func LoginUser(c *gin.Context) {
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"message": "Успешная авторизация", "userId": user.ID})
+    tokens, err := services.IssueTokenPair(&user)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось выдать токен"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message":       "Успешная авторизация",
+        "userId":        user.ID,
+        "access_token":  tokens.AccessToken,
+        "refresh_token": tokens.RefreshToken,
+        "token_type":    tokens.TokenType,
+        "expires_in":    tokens.ExpiresIn,
+    })
+}
+
+// Обновление сессии: refresh-токен обменивается на новую пару и отзывается
+func RefreshToken(c *gin.Context) {
+    var input struct {
+        RefreshToken string `json:"refresh_token" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    claims, err := services.ParseToken(input.RefreshToken, services.TokenTypeRefresh)
+    if err != nil {
+        respondTokenError(c, err)
+        return
+    }
+
+    var user models.User
+    if err := config.DB.First(&user, "id = ?", claims.Subject).Error; err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не найден"})
+        return
+    }
+
+    // Повторно использовать refresh-токен нельзя
+    if err := services.RevokeToken(claims); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    tokens, err := services.IssueTokenPair(&user)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось выдать токен"})
+        return
+    }
+    c.JSON(http.StatusOK, tokens)
+}
+
+// Выход: отзывает текущий access-токен и, если передан, refresh-токен
+func LogoutUser(c *gin.Context) {
+    var input struct {
+        RefreshToken string `json:"refresh_token"`
+    }
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&input); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+    }
+
+    if err := services.RevokeToken(middleware.CurrentClaims(c)); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+
+    if input.RefreshToken != "" {
+        claims, err := services.ParseToken(input.RefreshToken, services.TokenTypeRefresh)
+        if err == nil && claims.Subject == middleware.CurrentUser(c).ID {
+            if err := services.RevokeToken(claims); err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                return
+            }
+        }
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Сессия завершена"})
+}
+
+// respondTokenError отвечает 401 на недействительный токен и 500 на сбой проверки
+func respondTokenError(c *gin.Context, err error) {
+    if services.IsAuthError(err) {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
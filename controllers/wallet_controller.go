@@ -0,0 +1,135 @@
+package controllers
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+
+    "github.com/gin-gonic/gin"
+    "print-automation/config"
+    "print-automation/middleware"
+    "print-automation/models"
+    "print-automation/services"
+)
+
+// Баланс кошелька пользователя
+func GetUserBalance(c *gin.Context) {
+    id := c.Param("id")
+    if !canViewUser(c, id) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+        return
+    }
+
+    balance, err := services.WalletBalance(id)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"user_id": id, "balance": balance})
+}
+
+// Операции по кошельку пользователя, новые первыми (?limit=&offset=)
+func GetUserTransactions(c *gin.Context) {
+    id := c.Param("id")
+    if !canViewUser(c, id) {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+        return
+    }
+
+    limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+    if err != nil || limit < 1 || limit > 500 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "limit должен быть от 1 до 500"})
+        return
+    }
+    offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+    if err != nil || offset < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный offset"})
+        return
+    }
+
+    var entries []models.LedgerEntry
+    err = config.DB.Where("account = ?", models.UserAccount(id)).
+        Order("created_at DESC").Limit(limit).Offset(offset).
+        Find(&entries).Error
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, entries)
+}
+
+// Пополнить свой кошелёк через платёжного провайдера
+func TopUpBalance(c *gin.Context) {
+    id := c.Param("id")
+    if id != middleware.CurrentUser(c).ID {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Пополнить можно только свой кошелёк"})
+        return
+    }
+
+    var input struct {
+        Amount        float64 `json:"amount" binding:"required"`
+        Provider      string  `json:"provider" binding:"required"`
+        PaymentMethod string  `json:"payment_method"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if input.Amount <= 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Сумма пополнения должна быть положительной"})
+        return
+    }
+
+    payment, intent, err := services.StartTopUp(c.Request.Context(), id, input.Amount, input.Provider, input.PaymentMethod)
+    if err != nil {
+        if errors.Is(err, services.ErrUnknownPaymentProvider) || errors.Is(err, services.ErrMockPaymentsDisabled) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusCreated, gin.H{"payment": payment, "payment_url": intent.PaymentURL})
+}
+
+// Корректировка баланса администратором
+func AdjustUserBalance(c *gin.Context) {
+    id := c.Param("id")
+    var user models.User
+    if err := config.DB.First(&user, "id = ?", id).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+        return
+    }
+
+    var input struct {
+        Amount float64 `json:"amount" binding:"required"`
+        Reason string  `json:"reason" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&input); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    actor := services.UserActor(middleware.CurrentUser(c).ID)
+    if err := services.AdjustBalance(user.ID, input.Amount, input.Reason, actor); err != nil {
+        if errors.Is(err, services.ErrInsufficientFunds) {
+            c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    balance, err := services.WalletBalance(user.ID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"user_id": user.ID, "balance": balance})
+}
+
+// canViewUser разрешает смотреть свой кошелёк, а операторам и администраторам — любой
+func canViewUser(c *gin.Context, id string) bool {
+    user := middleware.CurrentUser(c)
+    return id == user.ID || services.HasPermission(user.Role, services.PermViewAllJobs)
+}
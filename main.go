@@ -4,12 +4,56 @@ import (
     "fmt"
     "print-automation/config"
     "print-automation/routers"
+    "print-automation/services"
+    "time"
 )
 
 func main() {
     // Инициализация БД
     config.InitDB()
 
+    // Ключ подписи JWT
+    services.InitAuth()
+
+    // Первый администратор (ADMIN_EMAIL, ADMIN_PASSWORD)
+    if err := services.BootstrapAdmin(); err != nil {
+        panic(err)
+    }
+
+    // Платёжные провайдеры
+    services.InitPaymentProviders()
+
+    // Хранилище загруженных документов
+    if err := services.InitDocumentStore(); err != nil {
+        panic(err)
+    }
+
+    // Запуск обработчиков очереди печати
+    services.StartPrintQueue(services.QueueConfig{
+        Workers:       config.GetEnvInt("QUEUE_WORKERS", 2),
+        PollInterval:  config.GetEnvDuration("QUEUE_POLL_INTERVAL", 5*time.Second),
+        SubmitTimeout: config.GetEnvDuration("QUEUE_SUBMIT_TIMEOUT", 10*time.Minute),
+        TrackInterval: config.GetEnvDuration("QUEUE_TRACK_INTERVAL", 5*time.Second),
+        TrackTimeout:  config.GetEnvDuration("QUEUE_TRACK_TIMEOUT", 30*time.Minute),
+        Retry: services.RetryPolicy{
+            MaxAttempts: config.GetEnvInt("QUEUE_MAX_ATTEMPTS", 5),
+            BaseDelay:   config.GetEnvDuration("QUEUE_RETRY_BASE_DELAY", 10*time.Second),
+            MaxDelay:    config.GetEnvDuration("QUEUE_RETRY_MAX_DELAY", 10*time.Minute),
+        },
+        Failover:      config.GetEnv("QUEUE_FAILOVER", "false") == "true",
+        PriorityAging: config.GetEnvDuration("QUEUE_PRIORITY_AGING", services.DefaultPriorityAging),
+    })
+
+    // Фоновая проверка доступности и состояния принтеров
+    if config.GetEnv("MONITOR_ENABLED", "true") != "false" {
+        services.StartPrinterMonitor(services.MonitorConfig{
+            Interval:     config.GetEnvDuration("MONITOR_INTERVAL", time.Minute),
+            ProbeTimeout: config.GetEnvDuration("MONITOR_PROBE_TIMEOUT", 5*time.Second),
+            MaxBackoff:   config.GetEnvDuration("MONITOR_MAX_BACKOFF", 30*time.Minute),
+            Concurrency:  config.GetEnvInt("MONITOR_CONCURRENCY", 8),
+        })
+    }
+
     // Настройка роутера
     r := routers.SetupRouter()
 
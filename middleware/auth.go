@@ -0,0 +1,88 @@
+package middleware
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "print-automation/config"
+    "print-automation/models"
+    "print-automation/services"
+)
+
+// Ключи контекста Gin
+const (
+    userKey   = "auth_user"
+    claimsKey = "auth_claims"
+)
+
+// RequireAuth пропускает запрос только с действующим access-токеном
+// в заголовке "Authorization: Bearer <token>" и кладёт пользователя в контекст
+func RequireAuth() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        header := c.GetHeader("Authorization")
+        token, ok := strings.CutPrefix(header, "Bearer ")
+        if !ok || strings.TrimSpace(token) == "" {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Требуется авторизация"})
+            return
+        }
+
+        claims, err := services.ParseToken(strings.TrimSpace(token), services.TokenTypeAccess)
+        if err != nil {
+            if services.IsAuthError(err) {
+                c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+                return
+            }
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+
+        // Пользователь мог быть удалён после выдачи токена
+        var user models.User
+        if err := config.DB.First(&user, "id = ?", claims.Subject).Error; err != nil {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не найден"})
+            return
+        }
+
+        c.Set(userKey, &user)
+        c.Set(claimsKey, claims)
+        c.Next()
+    }
+}
+
+// CurrentUser возвращает пользователя, установленного RequireAuth
+func CurrentUser(c *gin.Context) *models.User {
+    if v, ok := c.Get(userKey); ok {
+        if user, ok := v.(*models.User); ok {
+            return user
+        }
+    }
+    return nil
+}
+
+// CurrentClaims возвращает содержимое токена текущего запроса
+func CurrentClaims(c *gin.Context) *services.TokenClaims {
+    if v, ok := c.Get(claimsKey); ok {
+        if claims, ok := v.(*services.TokenClaims); ok {
+            return claims
+        }
+    }
+    return nil
+}
+
+// RequirePermission пропускает запрос, только если у роли пользователя есть право.
+// Ставится после RequireAuth.
+func RequirePermission(perm services.Permission) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        user := CurrentUser(c)
+        if user == nil {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Требуется авторизация"})
+            return
+        }
+        if !services.HasPermission(user.Role, perm) {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+            return
+        }
+        c.Next()
+    }
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+    "print-automation/services"
+)
+
+// RequireKiosk пропускает запросы киоска принтера :id: заголовок X-Kiosk-Key
+// должен содержать ключ, выданный именно этому принтеру
+func RequireKiosk() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        err := services.VerifyKioskKey(c.Param("id"), c.GetHeader("X-Kiosk-Key"))
+        if errors.Is(err, services.ErrKioskKeyInvalid) {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Неверный ключ киоска"})
+            return
+        }
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.Next()
+    }
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// JobEvent — запись о смене статуса задания
+type JobEvent struct {
+    ID         string    `gorm:"type:varchar(36);primaryKey"`
+    PrintJobID string    `gorm:"type:varchar(36);not null;index"`
+    FromStatus string    `gorm:"type:varchar(50)"`
+    ToStatus   string    `gorm:"type:varchar(50);not null"`
+    Actor      string    `gorm:"type:varchar(100);not null"`
+    Reason     string    `gorm:"type:varchar(500)"`
+    CreatedAt  time.Time `gorm:"not null;index"`
+}
+
+func (e *JobEvent) BeforeCreate(tx *gorm.DB) (err error) {
+    e.ID = uuid.New().String()
+    e.CreatedAt = time.Now()
+    return
+}
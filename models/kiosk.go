@@ -0,0 +1,36 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// KioskCredential — ключ киоска, привязанный к одному принтеру.
+// Хранится только SHA-256 ключа; сам ключ показывается один раз при выдаче.
+type KioskCredential struct {
+    ID        string    `gorm:"type:varchar(36);primaryKey"`
+    PrinterID string    `gorm:"type:varchar(36);not null;uniqueIndex"`
+    KeyHash   string    `gorm:"type:varchar(64);not null"`
+    CreatedAt time.Time `gorm:"not null"`
+}
+
+func (k *KioskCredential) BeforeCreate(tx *gorm.DB) (err error) {
+    k.ID = uuid.New().String()
+    k.CreatedAt = time.Now()
+    return
+}
+
+// ReleaseFailure — неверный PIN-код, введённый на киоске (для ограничения подбора)
+type ReleaseFailure struct {
+    ID        string    `gorm:"type:varchar(36);primaryKey"`
+    PrinterID string    `gorm:"type:varchar(36);not null;index"`
+    CreatedAt time.Time `gorm:"not null;index"`
+}
+
+func (f *ReleaseFailure) BeforeCreate(tx *gorm.DB) (err error) {
+    f.ID = uuid.New().String()
+    f.CreatedAt = time.Now()
+    return
+}
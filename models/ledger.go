@@ -0,0 +1,50 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Виды операций кошелька
+const (
+    LedgerKindTopUp      = "topup"
+    LedgerKindJobCharge  = "job_charge"
+    LedgerKindRefund     = "refund"
+    LedgerKindAdjustment = "adjustment"
+)
+
+// Системные счета; счёт кошелька пользователя — "user:<id>"
+const (
+    LedgerAccountCash        = "system:cash"        // деньги, поступившие через платёжных провайдеров
+    LedgerAccountRevenue     = "system:revenue"     // выручка за печать
+    LedgerAccountAdjustments = "system:adjustments" // ручные корректировки администратора
+)
+
+// LedgerEntry — проводка по счёту. Операция (TransactionID) состоит из проводок,
+// сумма которых равна нулю. Записи только добавляются и никогда не изменяются.
+type LedgerEntry struct {
+    ID            string    `gorm:"type:varchar(36);primaryKey"`
+    TransactionID string    `gorm:"type:varchar(36);not null;index"`
+    Account       string    `gorm:"type:varchar(100);not null;index"`
+    // Amount — изменение остатка счёта: положительное пополняет, отрицательное списывает
+    Amount        float64   `gorm:"type:decimal(10,2);not null"`
+    Kind          string    `gorm:"type:varchar(20);not null"`
+    PrintJobID    string    `gorm:"type:varchar(36);index"`
+    PaymentID     string    `gorm:"type:varchar(36);index"`
+    Description   string    `gorm:"type:varchar(500)"`
+    CreatedBy     string    `gorm:"type:varchar(100);not null"`
+    CreatedAt     time.Time `gorm:"not null;index"`
+}
+
+func (e *LedgerEntry) BeforeCreate(tx *gorm.DB) (err error) {
+    e.ID = uuid.New().String()
+    e.CreatedAt = time.Now()
+    return
+}
+
+// UserAccount — счёт кошелька пользователя
+func UserAccount(userID string) string {
+    return "user:" + userID
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+    "time"
+)
+
+// SchemaMigration — выполненная разовая миграция данных (AutoMigrate меняет
+// только схему; перенос данных записывается сюда, чтобы не повторяться)
+type SchemaMigration struct {
+    ID        string    `gorm:"type:varchar(64);primaryKey"`
+    AppliedAt time.Time `gorm:"not null"`
+}
@@ -7,13 +7,35 @@ import (
     "gorm.io/gorm"
 )
 
+// Назначение платежа: оплата задания или пополнение кошелька
+const (
+    PaymentPurposeJob   = "job"
+    PaymentPurposeTopUp = "topup"
+)
+
+// Статусы платежа: created → pending → succeeded / failed, succeeded → refunded
+const (
+    PaymentStatusCreated   = "created"
+    PaymentStatusPending   = "pending"
+    PaymentStatusSucceeded = "succeeded"
+    PaymentStatusFailed    = "failed"
+    PaymentStatusRefunded  = "refunded"
+)
+
 type Payment struct {
     ID            string    `gorm:"type:varchar(36);primaryKey"`
-    PrintJobID    string    `gorm:"type:varchar(36);not null"`
+    // PrintJobID пуст у пополнений кошелька
+    PrintJobID    string    `gorm:"type:varchar(36);not null;index"`
+    UserID        string    `gorm:"type:varchar(36);index"`
+    Purpose       string    `gorm:"type:varchar(20);not null;default:'job'"`
     Amount        float64   `gorm:"type:decimal(8,2);not null"`
+    // RefundedAmount — сумма проведённых возвратов; при полном возврате статус refunded
+    RefundedAmount float64  `gorm:"type:decimal(8,2);not null;default:0"`
     Status        string    `gorm:"type:varchar(50);not null;default:'created'"`
+    Provider      string    `gorm:"type:varchar(50);not null;default:'mock'"`
     PaymentMethod string    `gorm:"type:varchar(50)"`
-    TransactionID string    `gorm:"type:varchar(100)"`
+    // TransactionID — идентификатор платежа у провайдера
+    TransactionID string    `gorm:"type:varchar(100);index"`
     CreatedAt     time.Time `gorm:"not null"`
     UpdatedAt     time.Time `gorm:"not null"`
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+    "time"
+
+    "gorm.io/gorm"
+)
+
+// PaymentEvent — обработанное уведомление платёжного провайдера.
+// По ней отсекаются повторно присланные уведомления с тем же EventID.
+type PaymentEvent struct {
+    Provider  string    `gorm:"type:varchar(50);primaryKey"`
+    EventID   string    `gorm:"type:varchar(100);primaryKey"`
+    PaymentID string    `gorm:"type:varchar(36);not null;index"`
+    Status    string    `gorm:"type:varchar(50);not null"`
+    CreatedAt time.Time `gorm:"not null"`
+}
+
+func (e *PaymentEvent) BeforeCreate(tx *gorm.DB) (err error) {
+    e.CreatedAt = time.Now()
+    return
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// PriceList — прайс-лист принтера. Прайс-лист с пустым PrinterID действует
+// для всех принтеров, у которых нет собственного.
+type PriceList struct {
+    ID        string `gorm:"type:varchar(36);primaryKey"`
+    PrinterID string `gorm:"type:varchar(36);uniqueIndex"`
+    Name      string `gorm:"type:varchar(255);not null"`
+    // Цена одной стороны листа (оттиска): ч/б и цветной
+    PerPage      float64 `gorm:"type:decimal(10,4);not null;default:0"`
+    ColorPerPage float64 `gorm:"type:decimal(10,4);not null;default:0"`
+    // Фиксированная плата за каждую копию
+    PerCopy float64 `gorm:"type:decimal(10,4);not null;default:0"`
+    // Скидка на постраничную часть при двусторонней печати, %
+    DuplexDiscount float64 `gorm:"type:decimal(5,2);not null;default:0"`
+    // Минимальная стоимость задания
+    MinCharge float64 `gorm:"type:decimal(8,2);not null;default:0"`
+
+    Tiers           []PriceTier      `gorm:"foreignKey:PriceListID;constraint:OnDelete:CASCADE"`
+    PaperSurcharges []PaperSurcharge `gorm:"foreignKey:PriceListID;constraint:OnDelete:CASCADE"`
+
+    CreatedAt time.Time `gorm:"not null"`
+    UpdatedAt time.Time `gorm:"not null"`
+}
+
+// PriceTier — объёмная скидка: от MinPages оттисков в задании
+type PriceTier struct {
+    ID              string  `gorm:"type:varchar(36);primaryKey"`
+    PriceListID     string  `gorm:"type:varchar(36);not null;index"`
+    MinPages        int     `gorm:"not null"`
+    DiscountPercent float64 `gorm:"type:decimal(5,2);not null"`
+}
+
+// PaperSurcharge — доплата за оттиск на бумаге определённого формата
+type PaperSurcharge struct {
+    ID          string  `gorm:"type:varchar(36);primaryKey"`
+    PriceListID string  `gorm:"type:varchar(36);not null;index"`
+    MediaSize   string  `gorm:"type:varchar(50);not null"`
+    PerPage     float64 `gorm:"type:decimal(10,4);not null"`
+}
+
+func (pl *PriceList) BeforeCreate(tx *gorm.DB) (err error) {
+    pl.ID = uuid.New().String()
+    pl.CreatedAt = time.Now()
+    pl.UpdatedAt = time.Now()
+    return
+}
+
+func (pl *PriceList) BeforeUpdate(tx *gorm.DB) (err error) {
+    pl.UpdatedAt = time.Now()
+    return
+}
+
+func (t *PriceTier) BeforeCreate(tx *gorm.DB) (err error) {
+    t.ID = uuid.New().String()
+    return
+}
+
+func (s *PaperSurcharge) BeforeCreate(tx *gorm.DB) (err error) {
+    s.ID = uuid.New().String()
+    return
+}
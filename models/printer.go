@@ -13,8 +13,24 @@ type Printer struct {
     IPAddress  string    `gorm:"type:varchar(64);not null"`
     Port       int       `gorm:"not null"`
     Protocol   string    `gorm:"type:varchar(20);not null"`
+    // Очередь LPD или путь ресурса IPP (по умолчанию /ipp/print); баннерная страница — только LPD
+    Queue      string    `gorm:"type:varchar(100)"`
+    BannerPage bool      `gorm:"not null;default:false"`
+    // Пул взаимозаменяемых принтеров (для переключения при сбое)
+    Pool       string    `gorm:"type:varchar(100);index"`
+    // Location — где стоит принтер, от общего к частному через "/" ("Офис/3 этаж/Восток")
+    Location   string    `gorm:"type:varchar(255)"`
     IsOnline   bool      `gorm:"not null;default:false"`
     Status     string    `gorm:"type:varchar(50);not null;default:'UNKNOWN'"`
+    // SNMP: версия ("v1", "v2c" или "off") и community для опроса Printer-MIB
+    SNMPVersion   string `gorm:"type:varchar(10);not null;default:'v2c'"`
+    SNMPCommunity string `gorm:"type:varchar(100);not null;default:'public'"`
+    // Данные последнего опроса SNMP: флаги ошибок (через запятую) и счётчик страниц
+    ErrorState    string     `gorm:"type:varchar(255)"`
+    PageCount     int64      `gorm:"not null;default:0"`
+    SNMPCheckedAt *time.Time
+    // LastSeenAt — когда принтер последний раз ответил монитору
+    LastSeenAt    *time.Time
     CreatedAt  time.Time `gorm:"not null"`
     UpdatedAt  time.Time `gorm:"not null"`
 }
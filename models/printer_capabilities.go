@@ -0,0 +1,49 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Источники сведений о возможностях принтера
+const (
+    CapabilitiesSourceIPP    = "ipp"
+    CapabilitiesSourceManual = "manual"
+)
+
+// PrinterCapabilities — что умеет принтер. Списки хранятся через запятую;
+// пустой список означает «неизвестно» и при проверке заданий не ограничивает.
+type PrinterCapabilities struct {
+    ID              string `gorm:"type:varchar(36);primaryKey"`
+    PrinterID       string `gorm:"type:varchar(36);not null;uniqueIndex"`
+    // Source — откуда получены данные: "ipp" (Get-Printer-Attributes) или "manual"
+    Source          string `gorm:"type:varchar(20);not null;default:'manual'"`
+    MakeAndModel    string `gorm:"type:varchar(255)"`
+    // Языки описания страниц (MIME-типы документов), например application/pdf
+    DocumentFormats string `gorm:"type:varchar(1000)"`
+    ColorSupported  bool   `gorm:"not null;default:false"`
+    // Режимы печати сторон: one-sided, two-sided-long-edge, two-sided-short-edge
+    DuplexModes     string `gorm:"type:varchar(255)"`
+    // Форматы бумаги в тех же обозначениях, что у заданий (A4, Letter...)
+    MediaSizes      string `gorm:"type:varchar(1000)"`
+    Trays           string `gorm:"type:varchar(500)"`
+    // MaxCopies — наибольшее число копий (0 — без ограничения)
+    MaxCopies       int    `gorm:"not null;default:0"`
+    Resolutions     string `gorm:"type:varchar(255)"`
+    CreatedAt       time.Time `gorm:"not null"`
+    UpdatedAt       time.Time `gorm:"not null"`
+}
+
+func (pc *PrinterCapabilities) BeforeCreate(tx *gorm.DB) (err error) {
+    pc.ID = uuid.New().String()
+    pc.CreatedAt = time.Now()
+    pc.UpdatedAt = time.Now()
+    return
+}
+
+func (pc *PrinterCapabilities) BeforeUpdate(tx *gorm.DB) (err error) {
+    pc.UpdatedAt = time.Now()
+    return
+}
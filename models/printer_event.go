@@ -0,0 +1,25 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// PrinterEvent — запись о смене доступности или состояния принтера
+type PrinterEvent struct {
+    ID         string    `gorm:"type:varchar(36);primaryKey"`
+    PrinterID  string    `gorm:"type:varchar(36);not null;index"`
+    FromStatus string    `gorm:"type:varchar(50)"`
+    ToStatus   string    `gorm:"type:varchar(50);not null"`
+    IsOnline   bool      `gorm:"not null"`
+    Message    string    `gorm:"type:varchar(500)"`
+    CreatedAt  time.Time `gorm:"not null;index"`
+}
+
+func (e *PrinterEvent) BeforeCreate(tx *gorm.DB) (err error) {
+    e.ID = uuid.New().String()
+    e.CreatedAt = time.Now()
+    return
+}
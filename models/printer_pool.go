@@ -0,0 +1,40 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Политики выбора принтера в пуле
+const (
+    PoolPolicyLeastPages = "least_pages"
+    PoolPolicyRoundRobin = "round_robin"
+    PoolPolicyNearest    = "nearest"
+    PoolPolicyCapability = "capability"
+)
+
+// PrinterPool — именованная группа взаимозаменяемых принтеров.
+// Участники — принтеры, у которых Printer.Pool совпадает с Name.
+type PrinterPool struct {
+    ID          string `gorm:"type:varchar(36);primaryKey"`
+    Name        string `gorm:"type:varchar(100);not null;uniqueIndex"`
+    Description string `gorm:"type:varchar(255)"`
+    // Policy — как выбирается принтер для задания, адресованного пулу
+    Policy      string `gorm:"type:varchar(20);not null;default:'least_pages'"`
+    CreatedAt   time.Time `gorm:"not null"`
+    UpdatedAt   time.Time `gorm:"not null"`
+}
+
+func (p *PrinterPool) BeforeCreate(tx *gorm.DB) (err error) {
+    p.ID = uuid.New().String()
+    p.CreatedAt = time.Now()
+    p.UpdatedAt = time.Now()
+    return
+}
+
+func (p *PrinterPool) BeforeUpdate(tx *gorm.DB) (err error) {
+    p.UpdatedAt = time.Now()
+    return
+}
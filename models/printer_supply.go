@@ -0,0 +1,34 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// PrinterSupply — расходный материал принтера по данным Printer-MIB (prtMarkerSuppliesTable)
+type PrinterSupply struct {
+    ID          string `gorm:"type:varchar(36);primaryKey"`
+    PrinterID   string `gorm:"type:varchar(36);not null;uniqueIndex:idx_printer_supply"`
+    // SupplyIndex — номер строки prtMarkerSuppliesTable
+    SupplyIndex int    `gorm:"not null;uniqueIndex:idx_printer_supply"`
+    Description string `gorm:"type:varchar(255)"`
+    // Level и MaxCapacity — как сообщает принтер; -3 означает «есть, но сколько — неизвестно»
+    Level       int    `gorm:"not null"`
+    MaxCapacity int    `gorm:"not null"`
+    // Percent — остаток в процентах, -1 если его нельзя вычислить
+    Percent     int    `gorm:"not null"`
+    UpdatedAt   time.Time `gorm:"not null"`
+}
+
+func (s *PrinterSupply) BeforeCreate(tx *gorm.DB) (err error) {
+    s.ID = uuid.New().String()
+    s.UpdatedAt = time.Now()
+    return
+}
+
+func (s *PrinterSupply) BeforeUpdate(tx *gorm.DB) (err error) {
+    s.UpdatedAt = time.Now()
+    return
+}
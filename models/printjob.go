@@ -7,15 +7,104 @@ import (
     "gorm.io/gorm"
 )
 
+// Жизненный цикл задания. Допустимые переходы описаны в services/job_state.go
+const (
+    JobStatusCreated         = "created"
+    JobStatusAwaitingPayment = "awaiting_payment"
+    JobStatusQueued          = "queued"
+    JobStatusSending         = "sending"
+    JobStatusPrinting        = "printing"
+    JobStatusCompleted       = "completed"
+    JobStatusFailed          = "failed"
+    JobStatusCanceled        = "canceled"
+    JobStatusHeld            = "held"
+    // JobStatusNeedsCheck — принтер перестал сообщать о задании (например, после
+    // перезапуска сервера); напечатано ли оно, решает оператор
+    JobStatusNeedsCheck      = "needs_check"
+)
+
+// Параметры печати задания
+const (
+    ColorModeMonochrome = "monochrome"
+    ColorModeColor      = "color"
+
+    SidesOneSided          = "one-sided"
+    SidesTwoSidedLongEdge  = "two-sided-long-edge"
+    SidesTwoSidedShortEdge = "two-sided-short-edge"
+
+    DefaultMediaSize = "A4"
+
+    OrientationPortrait         = "portrait"
+    OrientationLandscape        = "landscape"
+    OrientationReverseLandscape = "reverse-landscape"
+    OrientationReversePortrait  = "reverse-portrait"
+)
+
+// Уровни приоритета задания; срочный назначает только оператор
+const (
+    JobPriorityLow    = 1
+    JobPriorityNormal = 2
+    JobPriorityHigh   = 3
+    JobPriorityUrgent = 4
+)
+
 type PrintJob struct {
     ID        string    `gorm:"type:varchar(36);primaryKey"`
     UserID    string    `gorm:"type:varchar(36);not null"`
     PrinterID string    `gorm:"type:varchar(36);not null"`
+    // Pool — задание адресовано пулу: принтер выбирается при отправке, а PrinterID
+    // до этого лишь предварительный; Location — где клиент (для политики nearest)
+    Pool      string    `gorm:"type:varchar(100);index"`
+    Location  string    `gorm:"type:varchar(255)"`
     FileURL   string    `gorm:"type:varchar(255)"`
-    Status    string    `gorm:"type:varchar(50);not null;default:'pending'"`
+    // Загруженный документ: ключ в хранилище, исходное имя, размер, MIME-тип и SHA-256
+    DocumentKey    string `gorm:"type:varchar(255)"`
+    DocumentName   string `gorm:"type:varchar(255)"`
+    DocumentSize   int64  `gorm:"not null;default:0"`
+    DocumentMIME   string `gorm:"type:varchar(100)"`
+    DocumentSHA256 string `gorm:"type:varchar(64)"`
+    Status    string    `gorm:"type:varchar(50);not null;default:'created'"`
     Copies    int       `gorm:"not null;default:1"`
-    Pages     int       `gorm:"not null;default:1"`
+    // Pages заявляет клиент (0 — неизвестно); после загрузки документа
+    // сервер записывает фактическое число и выставляет PagesCounted
+    Pages        int  `gorm:"not null;default:0"`
+    PagesCounted bool `gorm:"not null;default:false"`
     Cost      float64   `gorm:"type:decimal(8,2)"`
+    // FreePagesUsed — сколько страниц бесплатной квоты пользователя списано за задание
+    FreePagesUsed int `gorm:"not null;default:0"`
+    // WalletCharge — сколько списано с кошелька пользователя за задание
+    WalletCharge float64 `gorm:"type:decimal(8,2);not null;default:0"`
+    // Параметры печати, от которых зависит цена
+    ColorMode string `gorm:"type:varchar(20);not null;default:'monochrome'"`
+    Sides     string `gorm:"type:varchar(30);not null;default:'one-sided'"`
+    MediaSize string `gorm:"type:varchar(50);not null;default:'A4'"`
+    // PageRanges — печатаемые страницы ("1-3,5"); пусто — весь документ
+    PageRanges string `gorm:"type:varchar(255)"`
+    // NumberUp — сколько страниц документа размещается на одной стороне листа
+    NumberUp int `gorm:"not null;default:1"`
+    // Ориентация и подбор копий комплектами (nil при создании означает «да»)
+    Orientation string `gorm:"type:varchar(20);not null;default:'portrait'"`
+    Collate     *bool  `gorm:"not null;default:true"`
+    // SecureRelease — после оплаты задание удерживается, пока владелец не выпустит его
+    // у принтера PIN-кодом или QR-кодом киоска; ReleaseCodeHash — HMAC действующего PIN
+    SecureRelease    bool   `gorm:"not null;default:false"`
+    ReleaseCodeHash  string `gorm:"type:varchar(64);index"`
+    ReleaseExpiresAt *time.Time
+    // Идентификатор и состояние задания на стороне принтера (IPP job-id, номер задания LPD)
+    PrinterJobID    int    `gorm:"not null;default:0"`
+    PrinterJobState string `gorm:"type:varchar(50)"`
+    // PagesPrinted — сколько оттисков напечатано по данным принтера (nil — принтер не сообщает)
+    PagesPrinted *int
+    // Время постановки в очередь (порядок обработки)
+    QueuedAt  *time.Time `gorm:"index"`
+    // Priority — уровень приоритета в очереди; NotBefore — задание не печатается
+    // раньше этого времени (например, большие тиражи ночью)
+    Priority  int        `gorm:"not null;default:2;index"`
+    NotBefore *time.Time `gorm:"index"`
+    // Попытки отправки: счётчик, последняя ошибка и время следующей попытки
+    Attempts      int        `gorm:"not null;default:0"`
+    LastError     string     `gorm:"type:varchar(500)"`
+    NextAttemptAt *time.Time
     CreatedAt time.Time `gorm:"not null"`
     UpdatedAt time.Time `gorm:"not null"`
 }
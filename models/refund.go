@@ -0,0 +1,41 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Статусы возврата
+const (
+    RefundStatusPending   = "pending"
+    RefundStatusSucceeded = "succeeded"
+    RefundStatusFailed    = "failed"
+)
+
+// Refund — возврат (полный или частичный) по платежу
+type Refund struct {
+    ID         string    `gorm:"type:varchar(36);primaryKey"`
+    PaymentID  string    `gorm:"type:varchar(36);not null;index"`
+    PrintJobID string    `gorm:"type:varchar(36);not null;index"`
+    Amount     float64   `gorm:"type:decimal(8,2);not null"`
+    Status     string    `gorm:"type:varchar(50);not null;default:'pending'"`
+    Reason     string    `gorm:"type:varchar(500)"`
+    // LastError — ответ провайдера на неудавшийся возврат
+    LastError  string    `gorm:"type:varchar(500)"`
+    CreatedAt  time.Time `gorm:"not null"`
+    UpdatedAt  time.Time `gorm:"not null"`
+}
+
+func (r *Refund) BeforeCreate(tx *gorm.DB) (err error) {
+    r.ID = uuid.New().String()
+    r.CreatedAt = time.Now()
+    r.UpdatedAt = time.Now()
+    return
+}
+
+func (r *Refund) BeforeUpdate(tx *gorm.DB) (err error) {
+    r.UpdatedAt = time.Now()
+    return
+}
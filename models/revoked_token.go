@@ -0,0 +1,21 @@
+package models
+
+import (
+    "time"
+
+    "gorm.io/gorm"
+)
+
+// RevokedToken — отозванный JWT (выход из системы или использованный refresh-токен).
+// Запись нужна только до истечения срока действия токена.
+type RevokedToken struct {
+    JTI       string    `gorm:"type:varchar(36);primaryKey"`
+    UserID    string    `gorm:"type:varchar(36);not null;index"`
+    ExpiresAt time.Time `gorm:"not null;index"`
+    CreatedAt time.Time `gorm:"not null"`
+}
+
+func (t *RevokedToken) BeforeCreate(tx *gorm.DB) (err error) {
+    t.CreatedAt = time.Now()
+    return
+}
@@ -7,10 +7,20 @@ import (
     "gorm.io/gorm"
 )
 
+// Роли пользователей
+const (
+    RoleAdmin    = "admin"    // принтеры, прайс-листы, пользователи
+    RoleOperator = "operator" // очереди печати и возвраты
+    RoleCustomer = "customer" // свои задания и их оплата
+)
+
 type User struct {
     ID           string    `gorm:"type:varchar(36);primaryKey"`
     Email        string    `gorm:"type:varchar(255);unique;not null"`
     PasswordHash string    `gorm:"type:varchar(255);not null"`
+    Role         string    `gorm:"type:varchar(20);not null;default:'customer'"`
+    // FreePages — остаток бесплатной квоты (в оттисках: страницы × копии)
+    FreePages    int       `gorm:"not null;default:0"`
     CreatedAt    time.Time `gorm:"not null"`
     UpdatedAt    time.Time `gorm:"not null"`
 }
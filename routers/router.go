@@ -3,6 +3,8 @@ package routers
 import (
     "github.com/gin-gonic/gin"
     "print-automation/controllers"
+    "print-automation/middleware"
+    "print-automation/services"
 	"github.com/gin-contrib/cors"
 	"time"
 
@@ -15,7 +17,7 @@ func SetupRouter() *gin.Engine {
 	r.Use(cors.New(cors.Config{
         AllowOrigins:     []string{"http://localhost:3000"},
         AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-        AllowHeaders:     []string{"Content-Type", "Authorization"},
+        AllowHeaders:     []string{"Content-Type", "Authorization", "X-Kiosk-Key"},
         ExposeHeaders:    []string{"Content-Length"},
         AllowCredentials: true,
         MaxAge: 12 * time.Hour, 
@@ -24,26 +26,90 @@ func SetupRouter() *gin.Engine {
     // Пример роутов для пользователей
     r.POST("/users", controllers.CreateUser)
     r.POST("/users/login", controllers.LoginUser)
-    r.GET("/users/:id", controllers.GetUserByID)
-
-    // Принтеры
-    r.GET("/printers", controllers.GetAllPrinters)
-    r.POST("/printers", controllers.CreatePrinter)
-    r.GET("/printers/:id", controllers.GetPrinterByID)
-    r.PUT("/printers/:id", controllers.UpdatePrinter)
-    r.DELETE("/printers/:id", controllers.DeletePrinter)
-	r.GET("/printers/:id/check", controllers.CheckPrinterConnectionHandler)
-
-    // Задания на печать
-    r.GET("/printjobs", controllers.GetAllPrintJobs)
-    r.POST("/printjobs", controllers.CreatePrintJob)
-    r.PUT("/printjobs/:id", controllers.UpdatePrintJob)
-	r.POST("/printjobs/:id/send", controllers.SendPrintJobHandler)
+    r.POST("/users/refresh", controllers.RefreshToken)
+
+    // Уведомления платёжных провайдеров проверяются по подписи
+    r.POST("/payments/webhook/:provider", controllers.PaymentWebhook)
+
+    // Киоски у принтеров: QR-код и выпуск заданий по PIN-коду; у каждого киоска
+    // свой ключ, действующий только для его принтера
+    kiosk := r.Group("/kiosk", middleware.RequireKiosk())
+    kiosk.GET("/printers/:id", controllers.KioskPrinterInfo)
+    kiosk.POST("/printers/:id/release", controllers.KioskReleaseByPIN)
+
+    // Дальше — только с действующим access-токеном
+    auth := r.Group("/", middleware.RequireAuth())
+    auth.POST("/users/logout", controllers.LogoutUser)
+    auth.GET("/users/:id", controllers.GetUserByID)
+    auth.GET("/users/:id/balance", controllers.GetUserBalance)
+    auth.GET("/users/:id/transactions", controllers.GetUserTransactions)
+
+    // Справочники доступны всем авторизованным пользователям
+    auth.GET("/printers", controllers.GetAllPrinters)
+    auth.GET("/printers/:id", controllers.GetPrinterByID)
+    auth.GET("/printers/:id/supplies", controllers.GetPrinterSupplies)
+    auth.GET("/printers/:id/capabilities", controllers.GetPrinterCapabilities)
+    auth.GET("/pools", controllers.GetAllPools)
+    auth.GET("/pools/:id", controllers.GetPoolByID)
+    auth.GET("/pricelists", controllers.GetAllPriceLists)
+
+    // Администратор: пользователи, принтеры, прайс-листы
+    users := auth.Group("/", middleware.RequirePermission(services.PermManageUsers))
+    users.PUT("/users/:id/role", controllers.UpdateUserRole)
+    users.PUT("/users/:id/free-pages", controllers.UpdateUserFreePages)
+    users.POST("/users/:id/balance/adjust", controllers.AdjustUserBalance)
+
+    printers := auth.Group("/", middleware.RequirePermission(services.PermManagePrinters))
+    printers.POST("/printers", controllers.CreatePrinter)
+    printers.GET("/printers/discover", controllers.DiscoverPrinters)
+    printers.POST("/printers/discover/register", controllers.RegisterDiscoveredPrinter)
+    printers.PUT("/printers/:id", controllers.UpdatePrinter)
+    printers.DELETE("/printers/:id", controllers.DeletePrinter)
+    printers.PUT("/printers/:id/capabilities", controllers.UpdatePrinterCapabilities)
+    printers.POST("/printers/:id/capabilities/refresh", controllers.RefreshPrinterCapabilities)
+    printers.POST("/printers/:id/kiosk-key", controllers.IssueKioskKey)
+    printers.DELETE("/printers/:id/kiosk-key", controllers.RevokeKioskKey)
+    printers.POST("/pools", controllers.CreatePool)
+    printers.PUT("/pools/:id", controllers.UpdatePool)
+    printers.DELETE("/pools/:id", controllers.DeletePool)
+
+    pricing := auth.Group("/", middleware.RequirePermission(services.PermManagePricing))
+    pricing.POST("/pricelists", controllers.CreatePriceList)
+    pricing.PUT("/pricelists/:id", controllers.UpdatePriceList)
+    pricing.DELETE("/pricelists/:id", controllers.DeletePriceList)
+
+    // Оператор: состояние принтеров и ручное управление платежами
+    queue := auth.Group("/", middleware.RequirePermission(services.PermManageQueue))
+    queue.GET("/printers/:id/check", controllers.CheckPrinterConnectionHandler)
+    queue.GET("/printers/:id/events", controllers.GetPrinterEvents)
+    queue.PUT("/printjobs/:id/schedule", controllers.ReschedulePrintJob)
+
+    paymentsAdmin := auth.Group("/", middleware.RequirePermission(services.PermManagePayments))
+    paymentsAdmin.PUT("/payments/:id", controllers.UpdatePayment)
+    paymentsAdmin.POST("/payments/:id/capture", controllers.CapturePayment)
+    paymentsAdmin.GET("/refunds", controllers.GetAllRefunds)
+    paymentsAdmin.POST("/refunds/:id/retry", controllers.RetryRefund)
+
+    // Задания на печать: клиент видит только свои
+    jobs := auth.Group("/", middleware.RequirePermission(services.PermSubmitJobs))
+    jobs.GET("/printjobs", controllers.GetAllPrintJobs)
+    jobs.GET("/printjobs/quote", controllers.QuotePrintJob)
+    jobs.POST("/printjobs", controllers.CreatePrintJob)
+    jobs.PUT("/printjobs/:id", controllers.UpdatePrintJob)
+    jobs.POST("/printjobs/:id/send", controllers.SendPrintJobHandler)
+    jobs.POST("/printjobs/:id/cancel", controllers.CancelPrintJob)
+    jobs.POST("/printjobs/:id/hold", controllers.HoldPrintJob)
+    jobs.POST("/printjobs/:id/release", controllers.ResumePrintJob)
+    jobs.GET("/printjobs/:id/events", controllers.GetPrintJobEvents)
+    jobs.POST("/printjobs/:id/document", controllers.UploadPrintJobDocument)
+    jobs.POST("/printjobs/:id/release-pin", controllers.IssueReleasePIN)
+    jobs.POST("/release/qr", controllers.ReleaseByQR)
 
     // Платежи
-    r.GET("/payments", controllers.GetAllPayments)
-    r.POST("/payments", controllers.CreatePayment)
-    r.PUT("/payments/:id", controllers.UpdatePayment)
+    payments := auth.Group("/", middleware.RequirePermission(services.PermPayJobs))
+    payments.GET("/payments", controllers.GetAllPayments)
+    payments.POST("/payments", controllers.CreatePayment)
+    payments.POST("/users/:id/balance/topup", controllers.TopUpBalance)
 
     return r
 }
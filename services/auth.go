@@ -0,0 +1,209 @@
+package services
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "log"
+    "strings"
+    "time"
+
+    "github.com/google/uuid"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// Типы токенов: access — для запросов к API, refresh — для выпуска новой пары
+const (
+    TokenTypeAccess  = "access"
+    TokenTypeRefresh = "refresh"
+)
+
+var (
+    ErrInvalidToken = errors.New("недействительный токен")
+    ErrTokenExpired = errors.New("срок действия токена истёк")
+    ErrTokenRevoked = errors.New("токен отозван")
+)
+
+// TokenClaims — содержимое JWT
+type TokenClaims struct {
+    Subject   string `json:"sub"`
+    ID        string `json:"jti"`
+    Type      string `json:"typ"`
+    IssuedAt  int64  `json:"iat"`
+    ExpiresAt int64  `json:"exp"`
+}
+
+// Expires возвращает момент истечения токена
+func (c *TokenClaims) Expires() time.Time {
+    return time.Unix(c.ExpiresAt, 0)
+}
+
+// TokenPair — ответ на вход и обновление сессии
+type TokenPair struct {
+    AccessToken  string `json:"access_token"`
+    RefreshToken string `json:"refresh_token"`
+    TokenType    string `json:"token_type"`
+    ExpiresIn    int64  `json:"expires_in"`
+}
+
+var jwtSecret []byte
+
+// InitAuth читает JWT_SECRET. Без него ключ генерируется случайно,
+// и все выданные токены перестают действовать после перезапуска.
+func InitAuth() {
+    if secret := config.GetEnv("JWT_SECRET", ""); secret != "" {
+        jwtSecret = []byte(secret)
+        return
+    }
+    key := make([]byte, 32)
+    if _, err := rand.Read(key); err != nil {
+        panic(err)
+    }
+    jwtSecret = key
+    log.Println("JWT_SECRET не задан: используется случайный ключ, сессии не переживут перезапуск")
+}
+
+func accessTokenTTL() time.Duration {
+    return config.GetEnvDuration("JWT_ACCESS_TTL", 15*time.Minute)
+}
+
+func refreshTokenTTL() time.Duration {
+    return config.GetEnvDuration("JWT_REFRESH_TTL", 30*24*time.Hour)
+}
+
+// IssueTokenPair выпускает access- и refresh-токены пользователя
+func IssueTokenPair(user *models.User) (*TokenPair, error) {
+    now := time.Now()
+    access, err := signToken(user.ID, TokenTypeAccess, now, accessTokenTTL())
+    if err != nil {
+        return nil, err
+    }
+    refresh, err := signToken(user.ID, TokenTypeRefresh, now, refreshTokenTTL())
+    if err != nil {
+        return nil, err
+    }
+    return &TokenPair{
+        AccessToken:  access,
+        RefreshToken: refresh,
+        TokenType:    "Bearer",
+        ExpiresIn:    int64(accessTokenTTL() / time.Second),
+    }, nil
+}
+
+// ParseToken проверяет подпись, тип и срок действия токена и то, что он не отозван
+func ParseToken(token, tokenType string) (*TokenClaims, error) {
+    claims, err := verifyToken(token, tokenType, time.Now())
+    if err != nil {
+        return nil, err
+    }
+
+    var count int64
+    if err := config.DB.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&count).Error; err != nil {
+        return nil, err
+    }
+    if count > 0 {
+        return nil, ErrTokenRevoked
+    }
+    return claims, nil
+}
+
+// verifyToken проверяет подпись, тип и срок действия токена без обращения к базе
+func verifyToken(token, tokenType string, now time.Time) (*TokenClaims, error) {
+    parts := strings.Split(token, ".")
+    if len(parts) != 3 || len(jwtSecret) == 0 {
+        return nil, ErrInvalidToken
+    }
+
+    headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+    if err != nil {
+        return nil, ErrInvalidToken
+    }
+    var header struct {
+        Alg string `json:"alg"`
+    }
+    // Принимаем только HS256: "none" и асимметричные алгоритмы отвергаются
+    if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != "HS256" {
+        return nil, ErrInvalidToken
+    }
+
+    signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil {
+        return nil, ErrInvalidToken
+    }
+    if !hmac.Equal(signature, jwtSignature(parts[0]+"."+parts[1])) {
+        return nil, ErrInvalidToken
+    }
+
+    payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+    if err != nil {
+        return nil, ErrInvalidToken
+    }
+    var claims TokenClaims
+    if err := json.Unmarshal(payload, &claims); err != nil {
+        return nil, ErrInvalidToken
+    }
+    if claims.Type != tokenType || claims.Subject == "" || claims.ID == "" {
+        return nil, ErrInvalidToken
+    }
+    if !now.Before(claims.Expires()) {
+        return nil, ErrTokenExpired
+    }
+    return &claims, nil
+}
+
+// RevokeToken отзывает токен до истечения его срока и заодно удаляет
+// записи об уже истёкших токенах — они отклоняются и без списка
+func RevokeToken(claims *TokenClaims) error {
+    revoked := models.RevokedToken{
+        JTI:       claims.ID,
+        UserID:    claims.Subject,
+        ExpiresAt: claims.Expires(),
+    }
+    if err := config.DB.Create(&revoked).Error; err != nil {
+        return err
+    }
+    return config.DB.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
+
+// IsAuthError сообщает, что токен отклонён (а не сбой проверки)
+func IsAuthError(err error) bool {
+    return errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenExpired) || errors.Is(err, ErrTokenRevoked)
+}
+
+// UserActor — инициатор перехода статуса для журнала событий задания
+func UserActor(userID string) string {
+    return "user:" + userID
+}
+
+func signToken(subject, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+    header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+    payload, err := json.Marshal(TokenClaims{
+        Subject:   subject,
+        ID:        uuid.New().String(),
+        Type:      tokenType,
+        IssuedAt:  now.Unix(),
+        ExpiresAt: now.Add(ttl).Unix(),
+    })
+    if err != nil {
+        return "", err
+    }
+    unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+    return unsigned + "." + base64.RawURLEncoding.EncodeToString(jwtSignature(unsigned)), nil
+}
+
+// derivedKey — отдельный ключ для назначения purpose (подпись QR-кодов киосков, HMAC
+// PIN-кодов), производный от JWT_SECRET. Ключи разных назначений не совпадают
+// между собой и с ключом подписи токенов.
+func derivedKey(purpose string) []byte {
+    return hmacSHA256(jwtSecret, purpose)
+}
+
+func jwtSignature(unsigned string) []byte {
+    mac := hmac.New(sha256.New, jwtSecret)
+    mac.Write([]byte(unsigned))
+    return mac.Sum(nil)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+    "bytes"
+    "encoding/base64"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+func withJWTSecret(t *testing.T, secret string) {
+    saved := jwtSecret
+    jwtSecret = []byte(secret)
+    t.Cleanup(func() { jwtSecret = saved })
+}
+
+// replacePayload подменяет полезную нагрузку, сохраняя исходную подпись
+func replacePayload(token, payload string) string {
+    parts := strings.Split(token, ".")
+    parts[1] = base64.RawURLEncoding.EncodeToString([]byte(payload))
+    return strings.Join(parts, ".")
+}
+
+func TestVerifyToken(t *testing.T) {
+    withJWTSecret(t, "test-secret")
+    now := time.Unix(1700000000, 0)
+
+    access, err := signToken("user-1", TokenTypeAccess, now, 15*time.Minute)
+    if err != nil {
+        t.Fatal(err)
+    }
+    refresh, _ := signToken("user-1", TokenTypeRefresh, now, time.Hour)
+    jwtSecret = []byte("other-secret")
+    foreign, _ := signToken("user-1", TokenTypeAccess, now, 15*time.Minute)
+    jwtSecret = []byte("test-secret")
+
+    unsignedNone := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." +
+        strings.Split(access, ".")[1] + "."
+
+    tests := []struct {
+        name    string
+        token   string
+        typ     string
+        at      time.Time
+        wantErr error
+    }{
+        {"valid access token", access, TokenTypeAccess, now.Add(time.Minute), nil},
+        {"valid refresh token", refresh, TokenTypeRefresh, now.Add(30 * time.Minute), nil},
+        {"refresh used as access", refresh, TokenTypeAccess, now, ErrInvalidToken},
+        {"access used as refresh", access, TokenTypeRefresh, now, ErrInvalidToken},
+        {"expired", access, TokenTypeAccess, now.Add(15 * time.Minute), ErrTokenExpired},
+        {"signed with another key", foreign, TokenTypeAccess, now, ErrInvalidToken},
+        {"payload tampered", replacePayload(access, `{"sub":"admin","jti":"x","typ":"access","exp":9999999999}`), TokenTypeAccess, now, ErrInvalidToken},
+        {"alg none", unsignedNone, TokenTypeAccess, now, ErrInvalidToken},
+        {"signature stripped", strings.TrimRight(access, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"), TokenTypeAccess, now, ErrInvalidToken},
+        {"two parts", "a.b", TokenTypeAccess, now, ErrInvalidToken},
+        {"empty", "", TokenTypeAccess, now, ErrInvalidToken},
+    }
+    for _, tt := range tests {
+        claims, err := verifyToken(tt.token, tt.typ, tt.at)
+        if tt.wantErr != nil {
+            if !errors.Is(err, tt.wantErr) {
+                t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+            }
+            if !IsAuthError(err) {
+                t.Errorf("%s: IsAuthError(%v) = false", tt.name, err)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("%s: %v", tt.name, err)
+            continue
+        }
+        if claims.Subject != "user-1" || claims.Type != tt.typ || claims.ID == "" {
+            t.Errorf("%s: unexpected claims %+v", tt.name, claims)
+        }
+    }
+}
+
+func TestVerifyTokenWithoutSecret(t *testing.T) {
+    withJWTSecret(t, "test-secret")
+    now := time.Now()
+    token, _ := signToken("user-1", TokenTypeAccess, now, time.Minute)
+
+    jwtSecret = nil
+    if _, err := verifyToken(token, TokenTypeAccess, now); !errors.Is(err, ErrInvalidToken) {
+        t.Errorf("without a key: err = %v, want ErrInvalidToken", err)
+    }
+}
+
+func TestSignTokenUniqueIDs(t *testing.T) {
+    withJWTSecret(t, "test-secret")
+    now := time.Now()
+    a, _ := signToken("user-1", TokenTypeAccess, now, time.Minute)
+    b, _ := signToken("user-1", TokenTypeAccess, now, time.Minute)
+    ca, _ := verifyToken(a, TokenTypeAccess, now)
+    cb, _ := verifyToken(b, TokenTypeAccess, now)
+    if ca == nil || cb == nil || ca.ID == cb.ID {
+        t.Errorf("tokens issued at the same moment share jti: %+v %+v", ca, cb)
+    }
+}
+
+func TestDerivedKey(t *testing.T) {
+    withJWTSecret(t, "test-secret")
+    kiosk, pin := derivedKey("kiosk"), derivedKey("pin")
+    if !bytes.Equal(kiosk, derivedKey("kiosk")) {
+        t.Error("derived key is not deterministic")
+    }
+    tests := []struct {
+        name string
+        a, b []byte
+    }{
+        {"kiosk and pin", kiosk, pin},
+        {"kiosk and server key", kiosk, jwtSecret},
+        {"pin and server key", pin, jwtSecret},
+    }
+    for _, tt := range tests {
+        if bytes.Equal(tt.a, tt.b) {
+            t.Errorf("%s: keys are equal", tt.name)
+        }
+    }
+    jwtSecret = []byte("other-secret")
+    if bytes.Equal(kiosk, derivedKey("kiosk")) {
+        t.Error("derived key does not depend on the server key")
+    }
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+var (
+    // ErrCapabilitiesNotQueryable — протокол принтера не позволяет узнать его возможности
+    ErrCapabilitiesNotQueryable = errors.New("возможности принтера можно запросить только по IPP")
+    // ErrDocumentFormatUnsupported — принтер не понимает формат документа
+    ErrDocumentFormatUnsupported = errors.New("принтер не поддерживает формат документа")
+)
+
+// pwgMediaNames — имена форматов PWG 5101.1 в обозначениях, принятых у заданий
+var pwgMediaNames = map[string]string{
+    "iso_a3_297x420mm":         "A3",
+    "iso_a4_210x297mm":         "A4",
+    "iso_a5_148x210mm":         "A5",
+    "iso_a6_105x148mm":         "A6",
+    "iso_b5_176x250mm":         "B5",
+    "jis_b5_182x257mm":         "JIS-B5",
+    "na_letter_8.5x11in":       "Letter",
+    "na_legal_8.5x14in":        "Legal",
+    "na_ledger_11x17in":        "Ledger",
+    "na_executive_7.25x10.5in": "Executive",
+}
+
+// MediaSizeName переводит имя формата PWG ("iso_a4_210x297mm") в обозначение задания ("A4")
+func MediaSizeName(pwg string) string {
+    if name, ok := pwgMediaNames[strings.ToLower(pwg)]; ok {
+        return name
+    }
+    return pwg
+}
+
+// PWGMediaName переводит обозначение формата задания ("A4") в имя PWG для IPP.
+// Имена PWG передаются как есть; неизвестный формат даёт пустую строку.
+func PWGMediaName(name string) string {
+    for pwg, short := range pwgMediaNames {
+        if strings.EqualFold(short, name) {
+            return pwg
+        }
+    }
+    if strings.Count(name, "_") >= 2 {
+        return strings.ToLower(name)
+    }
+    return ""
+}
+
+// SplitList разбирает список, хранящийся через запятую
+func SplitList(s string) []string {
+    var out []string
+    for _, v := range strings.Split(s, ",") {
+        if v = strings.TrimSpace(v); v != "" {
+            out = append(out, v)
+        }
+    }
+    return out
+}
+
+// JoinList собирает список для хранения через запятую, пропуская пустые и повторы
+func JoinList(values []string) string {
+    var out []string
+    for _, v := range values {
+        v = strings.TrimSpace(strings.ReplaceAll(v, ",", " "))
+        if v != "" && !containsFold(out, v) {
+            out = append(out, v)
+        }
+    }
+    return strings.Join(out, ",")
+}
+
+func containsFold(list []string, s string) bool {
+    for _, v := range list {
+        if strings.EqualFold(v, s) {
+            return true
+        }
+    }
+    return false
+}
+
+// PrinterCapabilitiesFor возвращает возможности принтера; nil — сведений нет
+func PrinterCapabilitiesFor(printerID string) (*models.PrinterCapabilities, error) {
+    var caps models.PrinterCapabilities
+    err := config.DB.Where("printer_id = ?", printerID).First(&caps).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &caps, nil
+}
+
+// SavePrinterCapabilities создаёт или заменяет запись о возможностях принтера
+func SavePrinterCapabilities(caps *models.PrinterCapabilities) error {
+    existing, err := PrinterCapabilitiesFor(caps.PrinterID)
+    if err != nil {
+        return err
+    }
+    if existing == nil {
+        return config.DB.Create(caps).Error
+    }
+    caps.ID = existing.ID
+    caps.CreatedAt = existing.CreatedAt
+    return config.DB.Save(caps).Error
+}
+
+// FetchPrinterCapabilities запрашивает возможности принтера операцией Get-Printer-Attributes
+func FetchPrinterCapabilities(ctx context.Context, printer models.Printer) (*models.PrinterCapabilities, error) {
+    protocol := strings.ToLower(printer.Protocol)
+    if protocol != "ipp" && protocol != "ipps" {
+        return nil, ErrCapabilitiesNotQueryable
+    }
+
+    attrs, err := ippClientFor(printer).GetPrinterAttributes(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("не удалось получить атрибуты принтера: %w", err)
+    }
+
+    media := make([]string, 0, len(attrs.Media))
+    for _, m := range attrs.Media {
+        media = append(media, MediaSizeName(m))
+    }
+    return &models.PrinterCapabilities{
+        PrinterID:       printer.ID,
+        Source:          models.CapabilitiesSourceIPP,
+        MakeAndModel:    truncateRunes(attrs.MakeAndModel, 255),
+        DocumentFormats: truncateRunes(JoinList(attrs.DocumentFormats), 1000),
+        ColorSupported:  attrs.ColorSupported,
+        DuplexModes:     truncateRunes(JoinList(attrs.Sides), 255),
+        MediaSizes:      truncateRunes(JoinList(media), 1000),
+        Trays:           truncateRunes(JoinList(attrs.MediaSources), 500),
+        MaxCopies:       attrs.MaxCopies,
+        Resolutions:     truncateRunes(JoinList(attrs.Resolutions), 255),
+    }, nil
+}
+
+// RefreshPrinterCapabilities запрашивает возможности IPP-принтера и сохраняет их,
+// заменяя введённые вручную
+func RefreshPrinterCapabilities(ctx context.Context, printer models.Printer) (*models.PrinterCapabilities, error) {
+    caps, err := FetchPrinterCapabilities(ctx, printer)
+    if err != nil {
+        return nil, err
+    }
+    if err := SavePrinterCapabilities(caps); err != nil {
+        return nil, err
+    }
+    return caps, nil
+}
+
+// CheckJobCapabilities проверяет, что принтер задания может выполнить его параметры.
+// Если возможности принтера неизвестны, задание не ограничивается.
+func CheckJobCapabilities(job *models.PrintJob) error {
+    if job.PageRanges != "" || job.NumberUp > 1 {
+        var printer models.Printer
+        if err := config.DB.Select("protocol").First(&printer, "id = ?", job.PrinterID).Error; err != nil {
+            return err
+        }
+        if err := checkPageLayout(job, printer); err != nil {
+            return err
+        }
+    }
+
+    caps, err := PrinterCapabilitiesFor(job.PrinterID)
+    if err != nil || caps == nil {
+        return err
+    }
+
+    if job.ColorMode == models.ColorModeColor && !caps.ColorSupported {
+        return &JobOptionsError{Field: "color_mode", Message: "принтер печатает только в монохроме"}
+    }
+    if modes := SplitList(caps.DuplexModes); len(modes) > 0 && !containsFold(modes, job.Sides) {
+        return &JobOptionsError{Field: "sides", Message: "принтер поддерживает только " + strings.Join(modes, ", ")}
+    }
+    if sizes := SplitList(caps.MediaSizes); len(sizes) > 0 && !containsFold(sizes, job.MediaSize) {
+        return &JobOptionsError{Field: "media_size", Message: "формат не поддерживается принтером (доступны: " + strings.Join(sizes, ", ") + ")"}
+    }
+    if caps.MaxCopies > 0 && job.Copies > caps.MaxCopies {
+        return &JobOptionsError{Field: "copies", Message: fmt.Sprintf("принтер печатает не более %d копий", caps.MaxCopies)}
+    }
+    return nil
+}
+
+// checkPageLayout отклоняет диапазоны страниц и n-up, если драйвер принтера
+// не может передать их так, чтобы принтер их выполнил: иначе напечатан будет
+// весь документ по странице на стороне, а оплачены — только выбранные стороны
+func checkPageLayout(job *models.PrintJob, printer models.Printer) error {
+    if job.PageRanges == "" && job.NumberUp <= 1 {
+        return nil
+    }
+    driver, err := DriverFor(printer.Protocol)
+    if err != nil {
+        return err
+    }
+    driverCaps, err := driver.Capabilities(context.Background(), printer)
+    if err != nil {
+        return err
+    }
+    if driverCaps.PageLayout {
+        return nil
+    }
+    field := "page_ranges"
+    if job.PageRanges == "" {
+        field = "number_up"
+    }
+    return &JobOptionsError{Field: field, Message: fmt.Sprintf("не поддерживается при печати по протоколу %s", printerProtocolName(printer))}
+}
+
+func printerProtocolName(printer models.Printer) string {
+    if protocol := strings.TrimSpace(printer.Protocol); protocol != "" {
+        return strings.ToLower(protocol)
+    }
+    return "raw"
+}
+
+// checkDocumentFormat проверяет, что принтер понимает формат документа.
+// application/octet-stream в списке означает, что принтер сам определяет формат.
+func checkDocumentFormat(printerID, mimeType string) error {
+    caps, err := PrinterCapabilitiesFor(printerID)
+    if err != nil || caps == nil {
+        return err
+    }
+    formats := SplitList(caps.DocumentFormats)
+    if len(formats) == 0 || containsFold(formats, mimeType) || containsFold(formats, "application/octet-stream") {
+        return nil
+    }
+    return fmt.Errorf("%w: %s (поддерживаются: %s)", ErrDocumentFormatUnsupported, mimeType, strings.Join(formats, ", "))
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestMediaSizeNames(t *testing.T) {
+    tests := []struct {
+        pwg   string
+        short string
+    }{
+        {"iso_a4_210x297mm", "A4"},
+        {"na_letter_8.5x11in", "Letter"},
+        {"jis_b5_182x257mm", "JIS-B5"},
+        {"na_executive_7.25x10.5in", "Executive"},
+    }
+    for _, tt := range tests {
+        if got := MediaSizeName(tt.pwg); got != tt.short {
+            t.Errorf("MediaSizeName(%q) = %q, want %q", tt.pwg, got, tt.short)
+        }
+        if got := PWGMediaName(tt.short); got != tt.pwg {
+            t.Errorf("PWGMediaName(%q) = %q, want %q", tt.short, got, tt.pwg)
+        }
+    }
+
+    if got := MediaSizeName("ISO_A4_210x297mm"); got != "A4" {
+        t.Errorf("MediaSizeName is case-sensitive: got %q", got)
+    }
+    if got := MediaSizeName("om_small-photo_100x150mm"); got != "om_small-photo_100x150mm" {
+        t.Errorf("unknown PWG name changed to %q", got)
+    }
+    if got := PWGMediaName("a4"); got != "iso_a4_210x297mm" {
+        t.Errorf("PWGMediaName(a4) = %q", got)
+    }
+    if got := PWGMediaName("OM_Small-Photo_100x150mm"); got != "om_small-photo_100x150mm" {
+        t.Errorf("PWG name not passed through: %q", got)
+    }
+    if got := PWGMediaName("Tabloid"); got != "" {
+        t.Errorf("PWGMediaName(Tabloid) = %q, want empty", got)
+    }
+}
+
+func TestSplitJoinList(t *testing.T) {
+    splits := []struct {
+        in   string
+        want []string
+    }{
+        {"", nil},
+        {"A4", []string{"A4"}},
+        {"A4, Letter ,,A3", []string{"A4", "Letter", "A3"}},
+        {" , ", nil},
+    }
+    for _, tt := range splits {
+        if got := SplitList(tt.in); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("SplitList(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+
+    joins := []struct {
+        name string
+        in   []string
+        want string
+    }{
+        {"empty", nil, ""},
+        {"trimmed", []string{" A4 ", "Letter"}, "A4,Letter"},
+        {"duplicates ignore case", []string{"A4", "a4", "Letter"}, "A4,Letter"},
+        {"blank values dropped", []string{"", " ", "A3"}, "A3"},
+        {"commas cannot split a value", []string{"one-sided,two"}, "one-sided two"},
+    }
+    for _, tt := range joins {
+        got := JoinList(tt.in)
+        if got != tt.want {
+            t.Errorf("%s: JoinList(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+        }
+        if back := JoinList(SplitList(got)); back != got {
+            t.Errorf("%s: %q does not survive a round trip: %q", tt.name, got, back)
+        }
+    }
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+    "context"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// Источники найденных принтеров
+const (
+    DiscoverySourceMDNS = "mdns"
+    DiscoverySourceScan = "scan"
+)
+
+// maxScanHosts — предел размера сканируемой подсети (/22)
+const maxScanHosts = 1024
+
+// dnssdProtocols — типы сервисов DNS-SD и соответствующие протоколы драйверов
+var dnssdProtocols = map[string]string{
+    "_ipps._tcp":           "ipps",
+    "_ipp._tcp":            "ipp",
+    "_pdl-datastream._tcp": "raw",
+    "_printer._tcp":        "lpd",
+}
+
+// scanPorts — порты, проверяемые при сканировании подсети
+var scanPorts = []struct {
+    Port     int
+    Protocol string
+}{
+    {631, "ipp"},
+    {9100, "raw"},
+    {515, "lpd"},
+}
+
+// protocolPreference — порядок выбора протокола, если принтер объявил несколько
+var protocolPreference = map[string]int{"ipps": 0, "ipp": 1, "raw": 2, "lpd": 3}
+
+// DiscoveredPrinter — кандидат на регистрацию, найденный в сети
+type DiscoveredPrinter struct {
+    Name       string `json:"name"`
+    Host       string `json:"host,omitempty"`
+    IPAddress  string `json:"ip_address"`
+    Port       int    `json:"port"`
+    Protocol   string `json:"protocol"`
+    Queue      string `json:"queue,omitempty"`
+    Model      string `json:"model,omitempty"`
+    Source     string `json:"source"`
+    // Протоколы, на которых принтер ответил (выбран первый по предпочтению)
+    Protocols  []string `json:"protocols"`
+    // Registered — принтер с этим адресом уже зарегистрирован
+    Registered bool   `json:"registered"`
+    PrinterID  string `json:"printer_id,omitempty"`
+}
+
+// DiscoveryResult — итог поиска
+type DiscoveryResult struct {
+    Printers []DiscoveredPrinter `json:"printers"`
+    // Partial — сканирование прервано раньше, чем были проверены все адреса
+    Partial  bool                `json:"partial"`
+}
+
+// DiscoveryOptions — параметры поиска
+type DiscoveryOptions struct {
+    MDNS        bool
+    CIDR        string
+    // Timeout — время ожидания ответов mDNS; сканирование подсети получает
+    // не меньше, сколько нужно на проверку всех адресов (см. scanTimeout)
+    Timeout     time.Duration
+    DialTimeout time.Duration
+    Concurrency int
+}
+
+// DefaultDiscoveryOptions — параметры по умолчанию; подсеть берётся из DISCOVERY_CIDR
+func DefaultDiscoveryOptions() DiscoveryOptions {
+    return DiscoveryOptions{
+        MDNS:        true,
+        CIDR:        os.Getenv("DISCOVERY_CIDR"),
+        Timeout:     3 * time.Second,
+        DialTimeout: 500 * time.Millisecond,
+        Concurrency: 64,
+    }
+}
+
+// DiscoverPrinters ищет принтеры через mDNS/DNS-SD и (если задана подсеть) сканированием портов.
+// Кандидаты с одним адресом объединяются; уже зарегистрированные отмечаются.
+func DiscoverPrinters(ctx context.Context, opts DiscoveryOptions) (*DiscoveryResult, error) {
+    var hosts []net.IP
+    if opts.CIDR != "" {
+        var err error
+        if hosts, err = subnetHosts(opts.CIDR); err != nil {
+            return nil, err
+        }
+    }
+    if !opts.MDNS && len(hosts) == 0 {
+        return nil, errors.New("не выбран ни mDNS, ни диапазон адресов для сканирования")
+    }
+
+    var (
+        mu         sync.Mutex
+        found      []DiscoveredPrinter
+        mdnsErr    error
+        partial    bool
+        wg         sync.WaitGroup
+    )
+    if opts.MDNS {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+            defer cancel()
+            result, err := discoverMDNS(ctx)
+            mu.Lock()
+            defer mu.Unlock()
+            found = append(found, result...)
+            mdnsErr = err
+        }()
+    }
+    if len(hosts) > 0 {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            ctx, cancel := context.WithTimeout(ctx, scanTimeout(len(hosts), opts))
+            defer cancel()
+            result, complete := scanHosts(ctx, hosts, opts)
+            mu.Lock()
+            defer mu.Unlock()
+            found = append(found, result...)
+            partial = !complete
+        }()
+    }
+    wg.Wait()
+
+    // Ошибка mDNS не критична, если сканирование что-то нашло
+    if mdnsErr != nil && len(hosts) == 0 {
+        return nil, fmt.Errorf("mDNS: %w", mdnsErr)
+    }
+
+    result := mergeDiscovered(found)
+    if err := markRegistered(result); err != nil {
+        return nil, err
+    }
+    return &DiscoveryResult{Printers: result, Partial: partial}, nil
+}
+
+func discoverMDNS(ctx context.Context) ([]DiscoveredPrinter, error) {
+    var types []string
+    for t := range dnssdProtocols {
+        types = append(types, t)
+    }
+    services, err := BrowseMDNS(ctx, types)
+    if err != nil {
+        return nil, err
+    }
+
+    var result []DiscoveredPrinter
+    for _, svc := range services {
+        var ip string
+        for _, addr := range svc.IPs {
+            // Предпочитаем IPv4: его проще указать в настройках принтера
+            if addr.To4() != nil {
+                ip = addr.String()
+                break
+            }
+            if ip == "" {
+                ip = addr.String()
+            }
+        }
+        if ip == "" || svc.Port == 0 {
+            continue
+        }
+        protocol := dnssdProtocols[svc.Service]
+        p := DiscoveredPrinter{
+            Name:      svc.Instance,
+            Host:      strings.TrimSuffix(svc.Host, "."),
+            IPAddress: ip,
+            Port:      svc.Port,
+            Protocol:  protocol,
+            Model:     svc.TXT["ty"],
+            Source:    DiscoverySourceMDNS,
+            Protocols: []string{protocol},
+        }
+        // rp — путь ресурса IPP или имя очереди LPD
+        if rp := svc.TXT["rp"]; rp != "" && (protocol == "ipp" || protocol == "ipps" || protocol == "lpd") {
+            if protocol == "lpd" {
+                p.Queue = rp
+            } else {
+                p.Queue = "/" + strings.TrimPrefix(rp, "/")
+            }
+        }
+        result = append(result, p)
+    }
+    return result, nil
+}
+
+func scanConcurrency(opts DiscoveryOptions) int {
+    if opts.Concurrency <= 0 {
+        return 64
+    }
+    return opts.Concurrency
+}
+
+// scanTimeout — сколько нужно на проверку hosts адресов: подключения идут раундами
+// по Concurrency штук, и каждый раунд в худшем случае ждёт DialTimeout.
+// Меньше opts.Timeout не бывает.
+func scanTimeout(hosts int, opts DiscoveryOptions) time.Duration {
+    concurrency := scanConcurrency(opts)
+    rounds := (hosts*len(scanPorts) + concurrency - 1) / concurrency
+    d := time.Duration(rounds)*opts.DialTimeout + time.Second
+    if d < opts.Timeout {
+        return opts.Timeout
+    }
+    return d
+}
+
+// scanHosts проверяет порты принтеров на каждом адресе подсети.
+// complete ложно, если контекст истёк раньше, чем проверены все адреса.
+func scanHosts(ctx context.Context, hosts []net.IP, opts DiscoveryOptions) (result []DiscoveredPrinter, complete bool) {
+    dialer := net.Dialer{Timeout: opts.DialTimeout}
+
+    var (
+        mu     sync.Mutex
+        wg     sync.WaitGroup
+        sem    = make(chan struct{}, scanConcurrency(opts))
+    )
+    complete = true
+    for _, host := range hosts {
+        for _, sp := range scanPorts {
+            if ctx.Err() != nil {
+                mu.Lock()
+                complete = false
+                mu.Unlock()
+                break
+            }
+            wg.Add(1)
+            sem <- struct{}{}
+            go func(ip string, port int, protocol string) {
+                defer wg.Done()
+                defer func() { <-sem }()
+                conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+                if err != nil {
+                    if ctx.Err() != nil {
+                        mu.Lock()
+                        complete = false
+                        mu.Unlock()
+                    }
+                    return
+                }
+                conn.Close()
+                mu.Lock()
+                result = append(result, DiscoveredPrinter{
+                    Name:      ip,
+                    IPAddress: ip,
+                    Port:      port,
+                    Protocol:  protocol,
+                    Source:    DiscoverySourceScan,
+                    Protocols: []string{protocol},
+                })
+                mu.Unlock()
+            }(host.String(), sp.Port, sp.Protocol)
+        }
+    }
+    wg.Wait()
+    return result, complete
+}
+
+// subnetHosts перечисляет адреса узлов IPv4-подсети (без адреса сети и широковещательного)
+func subnetHosts(cidr string) ([]net.IP, error) {
+    _, network, err := net.ParseCIDR(cidr)
+    if err != nil {
+        return nil, fmt.Errorf("некорректная подсеть %q: %w", cidr, err)
+    }
+    base := network.IP.To4()
+    if base == nil {
+        return nil, errors.New("сканирование поддерживается только для IPv4")
+    }
+    ones, bits := network.Mask.Size()
+    size := 1 << uint(bits-ones)
+    if size > maxScanHosts+2 {
+        return nil, fmt.Errorf("подсеть %s слишком велика: не более %d адресов", cidr, maxScanHosts)
+    }
+
+    start := binary.BigEndian.Uint32(base)
+    first, last := 0, size
+    if size > 2 {
+        first, last = 1, size-1
+    }
+    hosts := make([]net.IP, 0, last-first)
+    for i := first; i < last; i++ {
+        ip := make(net.IP, 4)
+        binary.BigEndian.PutUint32(ip, start+uint32(i))
+        hosts = append(hosts, ip)
+    }
+    return hosts, nil
+}
+
+// mergeDiscovered объединяет кандидатов с одним адресом: имя и модель берутся из mDNS,
+// протокол — первый по предпочтению
+func mergeDiscovered(found []DiscoveredPrinter) []DiscoveredPrinter {
+    byIP := map[string]*DiscoveredPrinter{}
+    var order []string
+    for _, p := range found {
+        current, ok := byIP[p.IPAddress]
+        if !ok {
+            p := p
+            byIP[p.IPAddress] = &p
+            order = append(order, p.IPAddress)
+            continue
+        }
+        for _, proto := range p.Protocols {
+            if !containsString(current.Protocols, proto) {
+                current.Protocols = append(current.Protocols, proto)
+            }
+        }
+        if current.Source != DiscoverySourceMDNS && p.Source == DiscoverySourceMDNS {
+            current.Name, current.Host, current.Model, current.Source = p.Name, p.Host, p.Model, p.Source
+        }
+        better := protocolPreference[p.Protocol] < protocolPreference[current.Protocol]
+        // Данные mDNS (порт, путь ресурса) точнее результатов сканирования
+        sameButAnnounced := p.Protocol == current.Protocol && p.Source == DiscoverySourceMDNS
+        if better || sameButAnnounced {
+            current.Protocol, current.Port, current.Queue = p.Protocol, p.Port, p.Queue
+        }
+    }
+
+    result := make([]DiscoveredPrinter, 0, len(order))
+    for _, ip := range order {
+        p := byIP[ip]
+        sort.Slice(p.Protocols, func(i, j int) bool {
+            return protocolPreference[p.Protocols[i]] < protocolPreference[p.Protocols[j]]
+        })
+        result = append(result, *p)
+    }
+    sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+    return result
+}
+
+// markRegistered отмечает кандидатов, чей адрес уже есть среди принтеров
+func markRegistered(found []DiscoveredPrinter) error {
+    if len(found) == 0 {
+        return nil
+    }
+    ips := make([]string, 0, len(found))
+    for _, p := range found {
+        ips = append(ips, p.IPAddress)
+    }
+    var printers []models.Printer
+    if err := config.DB.Select("id", "ip_address").Where("ip_address IN ?", ips).Find(&printers).Error; err != nil {
+        return err
+    }
+    registered := map[string]string{}
+    for _, p := range printers {
+        registered[p.IPAddress] = p.ID
+    }
+    for i := range found {
+        if id, ok := registered[found[i].IPAddress]; ok {
+            found[i].Registered = true
+            found[i].PrinterID = id
+        }
+    }
+    return nil
+}
+
+func containsString(list []string, s string) bool {
+    for _, v := range list {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+    "context"
+    "net"
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestSubnetHosts(t *testing.T) {
+    tests := []struct {
+        name  string
+        cidr  string
+        count int
+        first string
+        last  string
+        valid bool
+    }{
+        {"/24", "192.168.1.0/24", 254, "192.168.1.1", "192.168.1.254", true},
+        {"host bits set", "192.168.1.77/24", 254, "192.168.1.1", "192.168.1.254", true},
+        {"/30", "10.0.0.4/30", 2, "10.0.0.5", "10.0.0.6", true},
+        {"/31 keeps both", "10.0.0.4/31", 2, "10.0.0.4", "10.0.0.5", true},
+        {"/32", "10.0.0.9/32", 1, "10.0.0.9", "10.0.0.9", true},
+        {"/22 is the limit", "10.1.0.0/22", 1022, "10.1.0.1", "10.1.3.254", true},
+        {"/21 is too large", "10.1.0.0/21", 0, "", "", false},
+        {"IPv6", "fd00::/120", 0, "", "", false},
+        {"not a subnet", "192.168.1.1", 0, "", "", false},
+    }
+    for _, tt := range tests {
+        hosts, err := subnetHosts(tt.cidr)
+        if !tt.valid {
+            if err == nil {
+                t.Errorf("%s: got %d hosts, want an error", tt.name, len(hosts))
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("%s: %v", tt.name, err)
+            continue
+        }
+        if len(hosts) != tt.count || hosts[0].String() != tt.first || hosts[len(hosts)-1].String() != tt.last {
+            t.Errorf("%s: got %d hosts %s..%s, want %d hosts %s..%s", tt.name,
+                len(hosts), hosts[0], hosts[len(hosts)-1], tt.count, tt.first, tt.last)
+        }
+    }
+}
+
+func TestMergeDiscovered(t *testing.T) {
+    found := []DiscoveredPrinter{
+        {Name: "10.0.0.5", IPAddress: "10.0.0.5", Port: 9100, Protocol: "raw", Source: DiscoverySourceScan, Protocols: []string{"raw"}},
+        {Name: "10.0.0.5", IPAddress: "10.0.0.5", Port: 515, Protocol: "lpd", Source: DiscoverySourceScan, Protocols: []string{"lpd"}},
+        {Name: "10.0.0.5", IPAddress: "10.0.0.5", Port: 631, Protocol: "ipp", Source: DiscoverySourceScan, Protocols: []string{"ipp"}},
+        {Name: "Lab Printer", Host: "lab.local", IPAddress: "10.0.0.5", Port: 8631, Protocol: "ipp", Queue: "/ipp/print",
+            Model: "LaserJet", Source: DiscoverySourceMDNS, Protocols: []string{"ipp"}},
+        {Name: "10.0.0.9", IPAddress: "10.0.0.9", Port: 515, Protocol: "lpd", Source: DiscoverySourceScan, Protocols: []string{"lpd"}},
+        {Name: "Annex", IPAddress: "10.0.0.7", Port: 631, Protocol: "ipps", Source: DiscoverySourceMDNS, Protocols: []string{"ipps"}},
+        {Name: "10.0.0.7", IPAddress: "10.0.0.7", Port: 631, Protocol: "ipp", Source: DiscoverySourceScan, Protocols: []string{"ipp"}},
+    }
+    want := []DiscoveredPrinter{
+        {Name: "10.0.0.9", IPAddress: "10.0.0.9", Port: 515, Protocol: "lpd", Source: DiscoverySourceScan, Protocols: []string{"lpd"}},
+        // ipps предпочтительнее ipp, даже если ipp нашло сканирование
+        {Name: "Annex", IPAddress: "10.0.0.7", Port: 631, Protocol: "ipps", Source: DiscoverySourceMDNS, Protocols: []string{"ipps", "ipp"}},
+        // Имя, модель, порт и путь ресурса — из mDNS, протоколы — по предпочтению
+        {Name: "Lab Printer", Host: "lab.local", IPAddress: "10.0.0.5", Port: 8631, Protocol: "ipp", Queue: "/ipp/print",
+            Model: "LaserJet", Source: DiscoverySourceMDNS, Protocols: []string{"ipp", "raw", "lpd"}},
+    }
+    if got := mergeDiscovered(found); !reflect.DeepEqual(got, want) {
+        t.Errorf("mergeDiscovered:\n got %+v\nwant %+v", got, want)
+    }
+    if got := mergeDiscovered(nil); len(got) != 0 {
+        t.Errorf("mergeDiscovered(nil) = %+v", got)
+    }
+}
+
+func TestScanTimeout(t *testing.T) {
+    opts := DiscoveryOptions{Timeout: 3 * time.Second, DialTimeout: 500 * time.Millisecond, Concurrency: 64}
+    tests := []struct {
+        name  string
+        hosts int
+        opts  DiscoveryOptions
+        want  time.Duration
+    }{
+        {"single host keeps the default", 1, opts, 3 * time.Second},
+        {"/24", 254, opts, 12*500*time.Millisecond + time.Second},
+        {"/22", 1022, opts, 48*500*time.Millisecond + time.Second},
+        {"default concurrency", 254, DiscoveryOptions{DialTimeout: time.Second}, 12*time.Second + time.Second},
+    }
+    for _, tt := range tests {
+        if got := scanTimeout(tt.hosts, tt.opts); got != tt.want {
+            t.Errorf("%s: scanTimeout = %s, want %s", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestScanHosts(t *testing.T) {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Skipf("loopback недоступен: %v", err)
+    }
+    defer ln.Close()
+    port := ln.Addr().(*net.TCPAddr).Port
+
+    saved := scanPorts
+    scanPorts = []struct {
+        Port     int
+        Protocol string
+    }{{port, "ipp"}}
+    defer func() { scanPorts = saved }()
+
+    hosts := []net.IP{net.ParseIP("127.0.0.1")}
+    opts := DiscoveryOptions{DialTimeout: time.Second, Concurrency: 4}
+
+    found, complete := scanHosts(context.Background(), hosts, opts)
+    if !complete || len(found) != 1 || found[0].Port != port || found[0].Source != DiscoverySourceScan {
+        t.Errorf("scan: got %+v, complete %v", found, complete)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    found, complete = scanHosts(ctx, hosts, opts)
+    if complete || len(found) != 0 {
+        t.Errorf("expired scan: got %+v, complete %v; want partial result", found, complete)
+    }
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// ErrDocumentTooLarge — документ превышает MAX_UPLOAD_SIZE_MB
+var ErrDocumentTooLarge = errors.New("документ превышает допустимый размер")
+
+// maxDocumentSize — предельный размер документа в байтах (по умолчанию 100 МБ)
+func maxDocumentSize() int64 {
+    return int64(config.GetEnvInt("MAX_UPLOAD_SIZE_MB", 100)) << 20
+}
+
+// StoreJobDocument сохраняет документ задания в хранилище и записывает
+// в задание его размер, MIME-тип и SHA-256. Прежний документ задания удаляется.
+func StoreJobDocument(ctx context.Context, job *models.PrintJob, r io.Reader, fileName, declaredMIME string) error {
+    store := DocumentStore()
+    if store == nil {
+        return fmt.Errorf("хранилище документов не инициализировано")
+    }
+
+    // Сначала пишем во временный файл: нужны хэш, размер и заголовок для определения формата
+    tmp, err := os.CreateTemp("", "upload-*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    defer tmp.Close()
+
+    limit := maxDocumentSize()
+    hash := sha256.New()
+    size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, limit+1))
+    if err != nil {
+        return fmt.Errorf("ошибка приёма документа: %w", err)
+    }
+    if size > limit {
+        return ErrDocumentTooLarge
+    }
+    if size == 0 {
+        return fmt.Errorf("документ пуст")
+    }
+
+    head := make([]byte, 512)
+    n, _ := tmp.ReadAt(head, 0)
+    mimeType := DetectDocumentFormat(head[:n], fileName, declaredMIME)
+    if err := checkDocumentFormat(job.PrinterID, mimeType); err != nil {
+        return err
+    }
+
+    // Число страниц определяет сервер; заявленное клиентом должно с ним совпасть.
+    // Документ, страницы которого не посчитать, не принимается: цена по
+    // заявленному клиентом числу страниц была бы произвольной.
+    pages, err := CountPagesFile(tmp.Name(), mimeType)
+    switch {
+    case errors.Is(err, ErrPageCountUnsupported):
+        return fmt.Errorf("%w: %s", ErrPageCountUnsupported, mimeType)
+    case err != nil:
+        return fmt.Errorf("%w: %v", ErrPageCountFailed, err)
+    }
+    if !job.PagesCounted && job.Pages > 0 && job.Pages != pages {
+        return &PageCountMismatchError{Declared: job.Pages, Counted: pages}
+    }
+
+    // Цена зависит от числа страниц — пересчитываем по фактическому
+    priced := *job
+    priced.Pages = pages
+    if priced.PageRanges != "" && SelectedPages(&priced) == 0 {
+        return &JobOptionsError{Field: "page_ranges", Message: fmt.Sprintf("не попадает ни на одну из %d страниц документа", pages)}
+    }
+    quote, err := QuoteJob(&priced)
+    if err != nil {
+        return err
+    }
+
+    sum := hex.EncodeToString(hash.Sum(nil))
+    fileName = sanitizeFileName(fileName)
+    key := path.Join("jobs", job.ID, sum+strings.ToLower(filepath.Ext(fileName)))
+
+    if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+        return err
+    }
+    if err := store.Put(ctx, key, tmp, size); err != nil {
+        return fmt.Errorf("не удалось сохранить документ: %w", err)
+    }
+
+    oldKey := job.DocumentKey
+    job.DocumentKey = key
+    job.DocumentName = fileName
+    job.DocumentSize = size
+    job.DocumentMIME = mimeType
+    job.DocumentSHA256 = sum
+    job.Pages = pages
+    job.PagesCounted = true
+    job.Cost = quote.Total
+    err = config.DB.Model(job).
+        Select("DocumentKey", "DocumentName", "DocumentSize", "DocumentMIME", "DocumentSHA256", "Pages", "PagesCounted", "Cost", "UpdatedAt").
+        Updates(job).Error
+    if err != nil {
+        store.Delete(ctx, key)
+        return err
+    }
+
+    if oldKey != "" && oldKey != key {
+        if err := store.Delete(ctx, oldKey); err != nil {
+            log.Printf("Не удалось удалить прежний документ %s: %v", oldKey, err)
+        }
+    }
+    return nil
+}
+
+// FetchDocument даёт путь к документу задания на локальном диске.
+// Задание со ссылкой FileURL, но без загруженного документа, сначала
+// скачивается в хранилище. Вызывающий обязан вызвать cleanup после отправки.
+func FetchDocument(ctx context.Context, job *models.PrintJob) (filePath string, cleanup func(), err error) {
+    if job.DocumentKey == "" {
+        if job.FileURL == "" {
+            return "", nil, Permanent(fmt.Errorf("у задания нет документа для печати"))
+        }
+        if err := ImportJobDocument(ctx, job); err != nil {
+            return "", nil, err
+        }
+    }
+    return OpenBlobAsFile(ctx, DocumentStore(), job.DocumentKey)
+}
+
+// documentFetchTimeout — предельное время скачивания документа по ссылке вместе с телом
+const documentFetchTimeout = 2 * time.Minute
+
+// documentHTTPClient скачивает документы по ссылкам клиентов: медленный сервер
+// не должен держать запрос создания задания или обработчик очереди бесконечно
+var documentHTTPClient = &http.Client{Timeout: documentFetchTimeout}
+
+// ImportJobDocument скачивает документ по job.FileURL и кладёт его в хранилище
+func ImportJobDocument(ctx context.Context, job *models.PrintJob) error {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.FileURL, nil)
+    if err != nil {
+        return Permanent(fmt.Errorf("некорректная ссылка на файл: %w", err))
+    }
+    resp, err := documentHTTPClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("ошибка скачивания файла: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return &HTTPStatusError{URL: job.FileURL, Code: resp.StatusCode}
+    }
+
+    name := path.Base(req.URL.Path)
+    if err := StoreJobDocument(ctx, job, resp.Body, name, resp.Header.Get("Content-Type")); err != nil {
+        if IsDocumentRejected(err) {
+            return Permanent(err)
+        }
+        return fmt.Errorf("ошибка скачивания файла: %w", err)
+    }
+    return nil
+}
+
+// IsDocumentRejected сообщает, что документ отклонён проверками, а не сбоем хранилища
+func IsDocumentRejected(err error) bool {
+    var mismatch *PageCountMismatchError
+    var options *JobOptionsError
+    return errors.As(err, &mismatch) ||
+        errors.As(err, &options) ||
+        errors.Is(err, ErrDocumentTooLarge) ||
+        errors.Is(err, ErrDocumentFormatUnsupported) ||
+        errors.Is(err, ErrPageCountUnsupported) ||
+        errors.Is(err, ErrPageCountFailed)
+}
+
+// DetectDocumentFormat определяет MIME-тип по сигнатуре, затем по заявленному типу и расширению
+func DetectDocumentFormat(head []byte, fileName, declaredMIME string) string {
+    switch {
+    case bytes.HasPrefix(head, []byte("%PDF-")):
+        return "application/pdf"
+    case bytes.HasPrefix(head, []byte("%!PS")), bytes.HasPrefix(head, []byte("\x04%!PS")):
+        return "application/postscript"
+    case bytes.HasPrefix(head, []byte("\x1b%-12345X")):
+        // UEL: задание PJL; язык указан в @PJL ENTER LANGUAGE
+        upper := bytes.ToUpper(head)
+        if bytes.Contains(upper, []byte("LANGUAGE=POSTSCRIPT")) {
+            return "application/postscript"
+        }
+        if bytes.Contains(upper, []byte("LANGUAGE=PDF")) {
+            return "application/pdf"
+        }
+        return "application/vnd.hp-PCL"
+    case bytes.HasPrefix(head, []byte("\x1bE")):
+        return "application/vnd.hp-PCL"
+    }
+
+    if declaredMIME != "" {
+        if mt := strings.TrimSpace(strings.SplitN(declaredMIME, ";", 2)[0]); mt != "" && mt != "application/octet-stream" {
+            return strings.ToLower(mt)
+        }
+    }
+    if byExt := DocumentFormatFor(fileName); byExt != "application/octet-stream" {
+        return byExt
+    }
+    return strings.SplitN(http.DetectContentType(head), ";", 2)[0]
+}
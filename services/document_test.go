@@ -0,0 +1,31 @@
+package services
+
+import (
+    "testing"
+)
+
+func TestDetectDocumentFormat(t *testing.T) {
+    tests := []struct {
+        name     string
+        head     string
+        fileName string
+        declared string
+        want     string
+    }{
+        {"PDF by signature", "%PDF-1.7\n", "file.txt", "text/plain", "application/pdf"},
+        {"PostScript", "%!PS-Adobe-3.0\n", "", "", "application/postscript"},
+        {"PostScript with Ctrl-D", "\x04%!PS-Adobe-3.0\n", "", "", "application/postscript"},
+        {"PJL wrapping PostScript", "\x1b%-12345X@PJL ENTER LANGUAGE=POSTSCRIPT\n", "", "", "application/postscript"},
+        {"PJL wrapping PDF", "\x1b%-12345X@PJL ENTER LANGUAGE=PDF\n", "", "", "application/pdf"},
+        {"PJL defaults to PCL", "\x1b%-12345X@PJL JOB\n", "", "", "application/vnd.hp-PCL"},
+        {"PCL reset", "\x1bE\x1b&l0O", "", "", "application/vnd.hp-PCL"},
+        {"declared type wins over extension", "hello", "a.pdf", "Text/Plain; charset=utf-8", "text/plain"},
+        {"octet-stream falls back to extension", "hello", "a.txt", "application/octet-stream", "text/plain"},
+        {"sniffed", "\x89PNG\r\n\x1a\n", "", "", "image/png"},
+    }
+    for _, tt := range tests {
+        if got := DetectDocumentFormat([]byte(tt.head), tt.fileName, tt.declared); got != tt.want {
+            t.Errorf("%s: DetectDocumentFormat = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+
+    "print-automation/models"
+)
+
+// ErrDriverNotSupported — драйвер не поддерживает запрошенную операцию
+var ErrDriverNotSupported = errors.New("операция не поддерживается протоколом принтера")
+
+// ErrDriverJobLost — принтер поддерживает опрос, но состояние задания утеряно
+// (например, после перезапуска сервера или обрыва связи): итог неизвестен
+var ErrDriverJobLost = errors.New("состояние задания на принтере утеряно")
+
+// JobTicket — всё, что драйверу нужно знать о задании, независимо от протокола
+type JobTicket struct {
+    JobID          string
+    JobName        string
+    UserName       string
+    Copies         int
+    DocumentPath   string
+    DocumentName   string
+    DocumentFormat string
+    // Параметры печати задания (см. NormalizeJobOptions)
+    ColorMode      string
+    Sides          string
+    MediaSize      string
+    Orientation    string
+    PageRanges     []PageRange
+    NumberUp       int
+    Collate        bool
+}
+
+// Нормализованные состояния задания на стороне принтера
+const (
+    DriverJobPending    = "pending"
+    DriverJobProcessing = "processing"
+    DriverJobCompleted  = "completed"
+    DriverJobCanceled   = "canceled"
+    DriverJobAborted    = "aborted"
+    DriverJobUnknown    = "unknown"
+)
+
+// DriverJobStatus — состояние задания, которое сообщил принтер
+type DriverJobStatus struct {
+    // PrinterJobID — идентификатор задания на принтере (0, если протокол его не выдаёт)
+    PrinterJobID int
+    State        string
+    Reasons      []string
+    PagesPrinted int
+}
+
+// DriverCapabilities — что драйвер умеет делать с принтером
+type DriverCapabilities struct {
+    JobStatus       bool
+    Cancel          bool
+    // PageLayout — принтер получает диапазоны страниц и n-up вместе с заданием
+    // и выполняет их сам
+    PageLayout      bool
+    DocumentFormats []string
+}
+
+// PrinterDriver — транспорт до принтера, выбираемый по models.Printer.Protocol
+type PrinterDriver interface {
+    // Submit передаёт документ на принтер
+    Submit(ctx context.Context, printer models.Printer, ticket JobTicket) (*DriverJobStatus, error)
+    // Probe проверяет, что принтер доступен
+    Probe(ctx context.Context, printer models.Printer) error
+    // QueryStatus запрашивает состояние ранее отправленного задания
+    QueryStatus(ctx context.Context, printer models.Printer, printerJobID int) (*DriverJobStatus, error)
+    // Cancel отменяет задание на принтере; userName — тот же, что в JobTicket.UserName
+    // (LPD и IPP разрешают отмену только отправителю задания)
+    Cancel(ctx context.Context, printer models.Printer, printerJobID int, userName string) error
+    // Capabilities сообщает возможности драйвера для данного принтера
+    Capabilities(ctx context.Context, printer models.Printer) (*DriverCapabilities, error)
+}
+
+var (
+    driversMu sync.RWMutex
+    drivers   = map[string]PrinterDriver{}
+)
+
+func init() {
+    raw := &RawDriver{}
+    RegisterDriver("raw", raw)
+    RegisterDriver("socket", raw)
+    RegisterDriver("jetdirect", raw)
+
+    ipp := &IPPDriver{}
+    RegisterDriver("ipp", ipp)
+    RegisterDriver("ipps", ipp)
+
+    RegisterDriver("lpd", &LPDDriver{})
+}
+
+// RegisterDriver регистрирует (или подменяет) драйвер для протокола
+func RegisterDriver(protocol string, driver PrinterDriver) {
+    driversMu.Lock()
+    defer driversMu.Unlock()
+    drivers[strings.ToLower(protocol)] = driver
+}
+
+// DriverFor возвращает драйвер для протокола принтера. Пустой протокол означает RAW.
+func DriverFor(protocol string) (PrinterDriver, error) {
+    key := strings.ToLower(strings.TrimSpace(protocol))
+    if key == "" {
+        key = "raw"
+    }
+
+    driversMu.RLock()
+    defer driversMu.RUnlock()
+    driver, ok := drivers[key]
+    if !ok {
+        return nil, fmt.Errorf("неизвестный протокол принтера %q (доступны: %s)", protocol, strings.Join(registeredProtocols(), ", "))
+    }
+    return driver, nil
+}
+
+func registeredProtocols() []string {
+    names := make([]string, 0, len(drivers))
+    for name := range drivers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestDriverFor(t *testing.T) {
+    tests := []struct {
+        protocol string
+        want     PrinterDriver
+    }{
+        {"", drivers["raw"]},
+        {"RAW", drivers["raw"]},
+        {" socket ", drivers["raw"]},
+        {"jetdirect", drivers["raw"]},
+        {"ipp", drivers["ipp"]},
+        {"IPPS", drivers["ipp"]},
+        {"lpd", drivers["lpd"]},
+    }
+    for _, tt := range tests {
+        got, err := DriverFor(tt.protocol)
+        if err != nil {
+            t.Errorf("DriverFor(%q): %v", tt.protocol, err)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("DriverFor(%q) = %T, want %T", tt.protocol, got, tt.want)
+        }
+    }
+
+    if _, err := DriverFor("smb"); err == nil {
+        t.Error("DriverFor(smb): expected an error for an unknown protocol")
+    }
+}
+
+func TestDriverCancelCapabilities(t *testing.T) {
+    tests := []struct {
+        protocol string
+        cancel   bool
+    }{
+        {"lpd", true},
+        {"raw", false},
+    }
+    for _, tt := range tests {
+        driver, err := DriverFor(tt.protocol)
+        if err != nil {
+            t.Fatal(err)
+        }
+        caps, err := driver.Capabilities(context.Background(), models.Printer{Protocol: tt.protocol})
+        if err != nil {
+            t.Errorf("%s: Capabilities: %v", tt.protocol, err)
+            continue
+        }
+        if caps.Cancel != tt.cancel {
+            t.Errorf("%s: Cancel = %v, want %v", tt.protocol, caps.Cancel, tt.cancel)
+        }
+    }
+}
+
+func TestCheckPageLayout(t *testing.T) {
+    tests := []struct {
+        protocol string
+        job      models.PrintJob
+        field    string
+    }{
+        {"lpd", models.PrintJob{PageRanges: "1-2"}, "page_ranges"},
+        {"lpd", models.PrintJob{NumberUp: 2}, "number_up"},
+        {"raw", models.PrintJob{PageRanges: "1-2", NumberUp: 4}, "page_ranges"},
+        {"", models.PrintJob{NumberUp: 2}, "number_up"},
+        {"jetdirect", models.PrintJob{NumberUp: 2}, "number_up"},
+        {"ipp", models.PrintJob{PageRanges: "1-2", NumberUp: 4}, ""},
+        {"ipps", models.PrintJob{PageRanges: "3"}, ""},
+        {"lpd", models.PrintJob{NumberUp: 1}, ""},
+        {"raw", models.PrintJob{}, ""},
+    }
+    for _, tt := range tests {
+        err := checkPageLayout(&tt.job, models.Printer{Protocol: tt.protocol})
+        if tt.field == "" {
+            if err != nil {
+                t.Errorf("%q %+v: unexpected error %v", tt.protocol, tt.job, err)
+            }
+            continue
+        }
+        var optErr *JobOptionsError
+        if !errors.As(err, &optErr) || optErr.Field != tt.field {
+            t.Errorf("%q %+v: err = %v, want a %s error", tt.protocol, tt.job, err, tt.field)
+        }
+    }
+}
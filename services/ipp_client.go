@@ -0,0 +1,586 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/tls"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync/atomic"
+    "time"
+)
+
+// Коды операций IPP (RFC 8011, раздел 5.4.15)
+const (
+    ippOpPrintJob             uint16 = 0x0002
+    ippOpValidateJob          uint16 = 0x0004
+    ippOpCancelJob            uint16 = 0x0008
+    ippOpGetJobAttributes     uint16 = 0x0009
+    ippOpGetPrinterAttributes uint16 = 0x000B
+)
+
+// Теги групп атрибутов
+const (
+    ippTagOperation   byte = 0x01
+    ippTagJob         byte = 0x02
+    ippTagEnd         byte = 0x03
+    ippTagPrinter     byte = 0x04
+    ippTagUnsupported byte = 0x05
+)
+
+// Теги значений
+const (
+    ippTagInteger         byte = 0x21
+    ippTagBoolean         byte = 0x22
+    ippTagEnum            byte = 0x23
+    ippTagResolution      byte = 0x32
+    ippTagRangeOfInteger  byte = 0x33
+    ippTagText            byte = 0x41
+    ippTagName            byte = 0x42
+    ippTagKeyword         byte = 0x44
+    ippTagURI             byte = 0x45
+    ippTagCharset         byte = 0x47
+    ippTagNaturalLanguage byte = 0x48
+    ippTagMimeMediaType   byte = 0x49
+)
+
+// Состояния задания на стороне принтера (job-state)
+const (
+    IPPJobPending           = 3
+    IPPJobPendingHeld       = 4
+    IPPJobProcessing        = 5
+    IPPJobProcessingStopped = 6
+    IPPJobCanceled          = 7
+    IPPJobAborted           = 8
+    IPPJobCompleted         = 9
+)
+
+var ippJobStateNames = map[int]string{
+    IPPJobPending:           "pending",
+    IPPJobPendingHeld:       "pending-held",
+    IPPJobProcessing:        "processing",
+    IPPJobProcessingStopped: "processing-stopped",
+    IPPJobCanceled:          "canceled",
+    IPPJobAborted:           "aborted",
+    IPPJobCompleted:         "completed",
+}
+
+// DefaultIPPPath — ресурс принтера по умолчанию (IPP Everywhere)
+const DefaultIPPPath = "/ipp/print"
+
+var ippRequestID uint32
+
+// IPPStatusError — ответ принтера с кодом ошибки IPP
+type IPPStatusError struct {
+    Operation uint16
+    Code      uint16
+    Message   string
+}
+
+func (e *IPPStatusError) Error() string {
+    if e.Message != "" {
+        return fmt.Sprintf("IPP-операция 0x%04x отклонена принтером (0x%04x): %s", e.Operation, e.Code, e.Message)
+    }
+    return fmt.Sprintf("IPP-операция 0x%04x отклонена принтером (0x%04x)", e.Operation, e.Code)
+}
+
+// IsClientError сообщает, что ошибка вызвана самим запросом (0x04xx)
+func (e *IPPStatusError) IsClientError() bool {
+    return e.Code >= 0x0400 && e.Code < 0x0500
+}
+
+// IPPJobStatus — состояние задания, которое вернул принтер
+type IPPJobStatus struct {
+    JobID                int
+    State                int
+    StateReasons         []string
+    ImpressionsCompleted int
+}
+
+// StateName возвращает текстовое имя job-state
+func (s *IPPJobStatus) StateName() string {
+    if name, ok := ippJobStateNames[s.State]; ok {
+        return name
+    }
+    return "unknown"
+}
+
+// IPPPrinterAttributes — возможности принтера из ответа Get-Printer-Attributes
+type IPPPrinterAttributes struct {
+    MakeAndModel    string
+    DocumentFormats []string
+    ColorSupported  bool
+    Sides           []string
+    Media           []string
+    MediaSources    []string
+    // MaxCopies — верхняя граница copies-supported (0 — не сообщается)
+    MaxCopies       int
+    // Resolutions — printer-resolution-supported в виде "600x600dpi"
+    Resolutions     []string
+}
+
+// IPPJobOptions — атрибуты задания для Print-Job / Validate-Job
+type IPPJobOptions struct {
+    JobName        string
+    UserName       string
+    DocumentFormat string
+    Copies         int
+    // Атрибуты задания; пустые значения не передаются и остаются на усмотрение принтера
+    Sides          string
+    ColorMode      string
+    Media          string
+    Orientation    string
+    PageRanges     []PageRange
+    NumberUp       int
+    // Uncollated — копии печатаются не комплектами, а постранично
+    Uncollated     bool
+}
+
+// IPPClient — минимальный клиент IPP/2.0 поверх HTTP
+type IPPClient struct {
+    // URL, на который уходит HTTP POST (http:// или https://)
+    URL string
+    // PrinterURI передаётся в атрибуте printer-uri (ipp:// или ipps://)
+    PrinterURI string
+    HTTPClient *http.Client
+}
+
+// NewIPPClient создаёт клиента для принтера по адресу и протоколу ("ipp" или "ipps")
+func NewIPPClient(protocol, ip string, port int) *IPPClient {
+    return NewIPPClientPath(protocol, ip, port, DefaultIPPPath)
+}
+
+// NewIPPClientPath — то же, что NewIPPClient, но с другим путём ресурса принтера
+// (например, "/ipp/print/queue1" или "/printers/office" у CUPS)
+func NewIPPClientPath(protocol, ip string, port int, path string) *IPPClient {
+    if path == "" {
+        path = DefaultIPPPath
+    }
+    if !strings.HasPrefix(path, "/") {
+        path = "/" + path
+    }
+    if port == 0 {
+        port = 631
+    }
+    hostPort := net.JoinHostPort(ip, strconv.Itoa(port))
+
+    httpScheme, ippScheme := "http", "ipp"
+    transport := &http.Transport{
+        DialContext: (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+    }
+    if strings.EqualFold(protocol, "ipps") {
+        httpScheme, ippScheme = "https", "ipps"
+        // У большинства принтеров самоподписанные сертификаты,
+        // поэтому проверку можно отключить явно через окружение
+        if os.Getenv("IPP_INSECURE_SKIP_VERIFY") == "true" {
+            transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+        }
+    }
+
+    return &IPPClient{
+        URL:        fmt.Sprintf("%s://%s%s", httpScheme, hostPort, path),
+        PrinterURI: fmt.Sprintf("%s://%s%s", ippScheme, hostPort, path),
+        HTTPClient: &http.Client{Transport: transport},
+    }
+}
+
+// PrintJob отправляет документ операцией Print-Job и возвращает job-id принтера
+func (c *IPPClient) PrintJob(ctx context.Context, document io.Reader, opts IPPJobOptions) (*IPPJobStatus, error) {
+    req := c.newRequest(ippOpPrintJob)
+    c.addJobOperationAttrs(req, opts)
+    addJobTemplateAttrs(req, opts)
+
+    resp, err := c.do(ctx, req, document)
+    if err != nil {
+        return nil, err
+    }
+    return resp.jobStatus(), nil
+}
+
+// ValidateJob проверяет, что принтер примет задание с такими атрибутами
+func (c *IPPClient) ValidateJob(ctx context.Context, opts IPPJobOptions) error {
+    req := c.newRequest(ippOpValidateJob)
+    c.addJobOperationAttrs(req, opts)
+    addJobTemplateAttrs(req, opts)
+
+    _, err := c.do(ctx, req, nil)
+    return err
+}
+
+// GetJobAttributes запрашивает текущее состояние задания на принтере
+func (c *IPPClient) GetJobAttributes(ctx context.Context, jobID int) (*IPPJobStatus, error) {
+    req := c.newRequest(ippOpGetJobAttributes)
+    req.addInt(ippTagInteger, "job-id", jobID)
+    req.addString(ippTagKeyword, "requested-attributes",
+        "job-id", "job-state", "job-state-reasons", "job-impressions-completed")
+
+    resp, err := c.do(ctx, req, nil)
+    if err != nil {
+        return nil, err
+    }
+    status := resp.jobStatus()
+    if status.JobID == 0 {
+        status.JobID = jobID
+    }
+    return status, nil
+}
+
+// GetPrinterAttributes запрашивает у принтера поддерживаемые форматы, параметры и носители
+func (c *IPPClient) GetPrinterAttributes(ctx context.Context) (*IPPPrinterAttributes, error) {
+    req := c.newRequest(ippOpGetPrinterAttributes)
+    req.addString(ippTagKeyword, "requested-attributes",
+        "printer-make-and-model", "document-format-supported", "color-supported", "sides-supported",
+        "media-supported", "media-source-supported", "copies-supported", "printer-resolution-supported")
+
+    resp, err := c.do(ctx, req, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    attrs := &IPPPrinterAttributes{
+        MakeAndModel:    resp.firstString("printer-make-and-model"),
+        DocumentFormats: resp.strings("document-format-supported"),
+        Sides:           resp.strings("sides-supported"),
+        Media:           resp.strings("media-supported"),
+        MediaSources:    resp.strings("media-source-supported"),
+    }
+    if attr := resp.find("color-supported"); attr != nil && len(attr.values) > 0 && len(attr.values[0]) == 1 {
+        attrs.ColorSupported = attr.values[0][0] != 0
+    }
+    // copies-supported — rangeOfInteger: нижняя и верхняя границы по 4 байта
+    if attr := resp.find("copies-supported"); attr != nil && len(attr.values) > 0 && len(attr.values[0]) == 8 {
+        attrs.MaxCopies = int(int32(binary.BigEndian.Uint32(attr.values[0][4:8])))
+    }
+    // resolution: x и y по 4 байта и единицы (3 — точек на дюйм, 4 — на сантиметр)
+    if attr := resp.find("printer-resolution-supported"); attr != nil {
+        for _, v := range attr.values {
+            if len(v) != 9 {
+                continue
+            }
+            units := "dpi"
+            if v[8] == 4 {
+                units = "dpcm"
+            }
+            attrs.Resolutions = append(attrs.Resolutions, fmt.Sprintf("%dx%d%s",
+                int32(binary.BigEndian.Uint32(v[0:4])), int32(binary.BigEndian.Uint32(v[4:8])), units))
+        }
+    }
+    return attrs, nil
+}
+
+// CancelJob отменяет задание на принтере
+func (c *IPPClient) CancelJob(ctx context.Context, jobID int, userName string) error {
+    req := c.newRequest(ippOpCancelJob)
+    req.addInt(ippTagInteger, "job-id", jobID)
+    if userName != "" {
+        req.addString(ippTagName, "requesting-user-name", userName)
+    }
+
+    _, err := c.do(ctx, req, nil)
+    return err
+}
+
+func (c *IPPClient) newRequest(op uint16) *ippMessage {
+    req := &ippMessage{
+        code:      op,
+        requestID: atomic.AddUint32(&ippRequestID, 1),
+    }
+    req.group(ippTagOperation)
+    req.addString(ippTagCharset, "attributes-charset", "utf-8")
+    req.addString(ippTagNaturalLanguage, "attributes-natural-language", "en")
+    req.addString(ippTagURI, "printer-uri", c.PrinterURI)
+    return req
+}
+
+func (c *IPPClient) addJobOperationAttrs(req *ippMessage, opts IPPJobOptions) {
+    if opts.UserName != "" {
+        req.addString(ippTagName, "requesting-user-name", opts.UserName)
+    }
+    if opts.JobName != "" {
+        req.addString(ippTagName, "job-name", opts.JobName)
+    }
+    format := opts.DocumentFormat
+    if format == "" {
+        format = "application/octet-stream"
+    }
+    req.addString(ippTagMimeMediaType, "document-format", format)
+}
+
+// ippOrientations — значения enum orientation-requested
+var ippOrientations = map[string]int{
+    "portrait":          3,
+    "landscape":         4,
+    "reverse-landscape": 5,
+    "reverse-portrait":  6,
+}
+
+// addJobTemplateAttrs добавляет группу атрибутов задания (RFC 8011, раздел 5.2)
+func addJobTemplateAttrs(req *ippMessage, opts IPPJobOptions) {
+    req.group(ippTagJob)
+    if opts.Copies > 1 {
+        req.addInt(ippTagInteger, "copies", opts.Copies)
+        handling := "separate-documents-collated-copies"
+        if opts.Uncollated {
+            handling = "separate-documents-uncollated-copies"
+        }
+        req.addString(ippTagKeyword, "multiple-document-handling", handling)
+    }
+    if opts.Sides != "" {
+        req.addString(ippTagKeyword, "sides", opts.Sides)
+    }
+    if opts.ColorMode != "" {
+        req.addString(ippTagKeyword, "print-color-mode", opts.ColorMode)
+    }
+    if opts.Media != "" {
+        req.addString(ippTagKeyword, "media", opts.Media)
+    }
+    if value, ok := ippOrientations[opts.Orientation]; ok && value != 3 {
+        req.addInt(ippTagEnum, "orientation-requested", value)
+    }
+    if len(opts.PageRanges) > 0 {
+        req.addRanges("page-ranges", opts.PageRanges)
+    }
+    if opts.NumberUp > 1 {
+        req.addInt(ippTagInteger, "number-up", opts.NumberUp)
+    }
+}
+
+// do кодирует запрос, отправляет его и разбирает ответ
+func (c *IPPClient) do(ctx context.Context, req *ippMessage, document io.Reader) (*ippMessage, error) {
+    var body io.Reader = bytes.NewReader(req.encode())
+    if document != nil {
+        body = io.MultiReader(body, document)
+    }
+
+    httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, body)
+    if err != nil {
+        return nil, err
+    }
+    httpReq.Header.Set("Content-Type", "application/ipp")
+
+    httpResp, err := c.HTTPClient.Do(httpReq)
+    if err != nil {
+        return nil, fmt.Errorf("не удалось подключиться к принтеру [%s]: %w", c.URL, err)
+    }
+    defer httpResp.Body.Close()
+
+    if httpResp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("принтер [%s] вернул HTTP %d", c.URL, httpResp.StatusCode)
+    }
+
+    data, err := io.ReadAll(httpResp.Body)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения IPP-ответа: %w", err)
+    }
+    resp, err := decodeIPPMessage(data)
+    if err != nil {
+        return nil, err
+    }
+    if resp.code >= 0x0100 {
+        return resp, &IPPStatusError{Operation: req.code, Code: resp.code, Message: resp.firstString("status-message")}
+    }
+    return resp, nil
+}
+
+// DocumentFormatFor подбирает MIME-тип документа по расширению файла
+func DocumentFormatFor(name string) string {
+    switch strings.ToLower(filepath.Ext(name)) {
+    case ".pdf":
+        return "application/pdf"
+    case ".ps":
+        return "application/postscript"
+    case ".pcl":
+        return "application/vnd.hp-PCL"
+    case ".txt":
+        return "text/plain"
+    case ".jpg", ".jpeg":
+        return "image/jpeg"
+    case ".png":
+        return "image/png"
+    case ".pwg":
+        return "image/pwg-raster"
+    case ".urf":
+        return "image/urf"
+    }
+    return "application/octet-stream"
+}
+
+// ippAttribute — атрибут IPP с одним или несколькими значениями
+type ippAttribute struct {
+    group  byte
+    tag    byte
+    name   string
+    values [][]byte
+}
+
+// ippMessage — запрос или ответ IPP (для ответа code — это status-code)
+type ippMessage struct {
+    code       uint16
+    requestID  uint32
+    attributes []ippAttribute
+    curGroup   byte
+}
+
+func (m *ippMessage) group(tag byte) {
+    m.curGroup = tag
+}
+
+func (m *ippMessage) addString(tag byte, name string, values ...string) {
+    attr := ippAttribute{group: m.curGroup, tag: tag, name: name}
+    for _, v := range values {
+        attr.values = append(attr.values, []byte(v))
+    }
+    m.attributes = append(m.attributes, attr)
+}
+
+func (m *ippMessage) addInt(tag byte, name string, value int) {
+    buf := make([]byte, 4)
+    binary.BigEndian.PutUint32(buf, uint32(int32(value)))
+    m.attributes = append(m.attributes, ippAttribute{group: m.curGroup, tag: tag, name: name, values: [][]byte{buf}})
+}
+
+func (m *ippMessage) addRanges(name string, ranges []PageRange) {
+    attr := ippAttribute{group: m.curGroup, tag: ippTagRangeOfInteger, name: name}
+    for _, r := range ranges {
+        buf := make([]byte, 8)
+        binary.BigEndian.PutUint32(buf[0:4], uint32(int32(r.From)))
+        binary.BigEndian.PutUint32(buf[4:8], uint32(int32(r.To)))
+        attr.values = append(attr.values, buf)
+    }
+    m.attributes = append(m.attributes, attr)
+}
+
+func (m *ippMessage) encode() []byte {
+    var buf bytes.Buffer
+    buf.Write([]byte{0x02, 0x00})
+    binary.Write(&buf, binary.BigEndian, m.code)
+    binary.Write(&buf, binary.BigEndian, m.requestID)
+
+    lastGroup := byte(0)
+    for _, attr := range m.attributes {
+        if attr.group != lastGroup {
+            buf.WriteByte(attr.group)
+            lastGroup = attr.group
+        }
+        for i, v := range attr.values {
+            buf.WriteByte(attr.tag)
+            name := attr.name
+            if i > 0 {
+                name = ""
+            }
+            binary.Write(&buf, binary.BigEndian, uint16(len(name)))
+            buf.WriteString(name)
+            binary.Write(&buf, binary.BigEndian, uint16(len(v)))
+            buf.Write(v)
+        }
+    }
+    buf.WriteByte(ippTagEnd)
+    return buf.Bytes()
+}
+
+var errIPPTruncated = errors.New("IPP-ответ обрезан")
+
+func decodeIPPMessage(data []byte) (*ippMessage, error) {
+    if len(data) < 9 {
+        return nil, errIPPTruncated
+    }
+    m := &ippMessage{
+        code:      binary.BigEndian.Uint16(data[2:4]),
+        requestID: binary.BigEndian.Uint32(data[4:8]),
+    }
+
+    pos := 8
+    group := byte(0)
+    for pos < len(data) {
+        tag := data[pos]
+        pos++
+        if tag == ippTagEnd {
+            return m, nil
+        }
+        if tag < 0x10 {
+            group = tag
+            continue
+        }
+        if pos+2 > len(data) {
+            return nil, errIPPTruncated
+        }
+        nameLen := int(binary.BigEndian.Uint16(data[pos:]))
+        pos += 2
+        if pos+nameLen+2 > len(data) {
+            return nil, errIPPTruncated
+        }
+        name := string(data[pos : pos+nameLen])
+        pos += nameLen
+        valueLen := int(binary.BigEndian.Uint16(data[pos:]))
+        pos += 2
+        if pos+valueLen > len(data) {
+            return nil, errIPPTruncated
+        }
+        value := data[pos : pos+valueLen]
+        pos += valueLen
+
+        // Пустое имя означает дополнительное значение предыдущего атрибута
+        // (в том числе члены коллекций — отдельно их не разбираем)
+        if name == "" && len(m.attributes) > 0 {
+            last := &m.attributes[len(m.attributes)-1]
+            last.values = append(last.values, value)
+            continue
+        }
+        m.attributes = append(m.attributes, ippAttribute{group: group, tag: tag, name: name, values: [][]byte{value}})
+    }
+    return nil, errIPPTruncated
+}
+
+func (m *ippMessage) find(name string) *ippAttribute {
+    for i := range m.attributes {
+        if m.attributes[i].name == name {
+            return &m.attributes[i]
+        }
+    }
+    return nil
+}
+
+func (m *ippMessage) firstInt(name string) int {
+    attr := m.find(name)
+    if attr == nil || len(attr.values) == 0 || len(attr.values[0]) != 4 {
+        return 0
+    }
+    return int(int32(binary.BigEndian.Uint32(attr.values[0])))
+}
+
+func (m *ippMessage) firstString(name string) string {
+    attr := m.find(name)
+    if attr == nil || len(attr.values) == 0 {
+        return ""
+    }
+    return string(attr.values[0])
+}
+
+func (m *ippMessage) strings(name string) []string {
+    attr := m.find(name)
+    if attr == nil {
+        return nil
+    }
+    out := make([]string, 0, len(attr.values))
+    for _, v := range attr.values {
+        out = append(out, string(v))
+    }
+    return out
+}
+
+func (m *ippMessage) jobStatus() *IPPJobStatus {
+    return &IPPJobStatus{
+        JobID:                m.firstInt("job-id"),
+        State:                m.firstInt("job-state"),
+        StateReasons:         m.strings("job-state-reasons"),
+        ImpressionsCompleted: m.firstInt("job-impressions-completed"),
+    }
+}
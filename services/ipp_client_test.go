@@ -0,0 +1,161 @@
+package services
+
+import (
+    "bytes"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func TestIPPMessageRoundTrip(t *testing.T) {
+    req := &ippMessage{code: ippOpPrintJob, requestID: 42}
+    req.group(ippTagOperation)
+    req.addString(ippTagCharset, "attributes-charset", "utf-8")
+    req.addString(ippTagName, "job-name", "отчёт")
+    req.group(ippTagJob)
+    req.addInt(ippTagInteger, "copies", 3)
+    req.addInt(ippTagEnum, "orientation-requested", 4)
+    req.addString(ippTagKeyword, "media-supported", "iso_a4_210x297mm", "na_letter_8.5x11in")
+    req.addRanges("page-ranges", []PageRange{{From: 1, To: 2}, {From: 5, To: 5}})
+
+    got, err := decodeIPPMessage(req.encode())
+    if err != nil {
+        t.Fatalf("decode: %v", err)
+    }
+    if got.code != ippOpPrintJob || got.requestID != 42 {
+        t.Fatalf("header = %#04x/%d, want %#04x/42", got.code, got.requestID, ippOpPrintJob)
+    }
+
+    tests := []struct {
+        name  string
+        group byte
+        tag   byte
+        count int
+    }{
+        {"attributes-charset", ippTagOperation, ippTagCharset, 1},
+        {"job-name", ippTagOperation, ippTagName, 1},
+        {"copies", ippTagJob, ippTagInteger, 1},
+        {"orientation-requested", ippTagJob, ippTagEnum, 1},
+        {"media-supported", ippTagJob, ippTagKeyword, 2},
+        {"page-ranges", ippTagJob, ippTagRangeOfInteger, 2},
+    }
+    for _, tt := range tests {
+        attr := got.find(tt.name)
+        if attr == nil {
+            t.Errorf("%s: attribute missing", tt.name)
+            continue
+        }
+        if attr.group != tt.group || attr.tag != tt.tag || len(attr.values) != tt.count {
+            t.Errorf("%s: group %#x tag %#x values %d, want %#x %#x %d",
+                tt.name, attr.group, attr.tag, len(attr.values), tt.group, tt.tag, tt.count)
+        }
+    }
+
+    if n := got.firstInt("copies"); n != 3 {
+        t.Errorf("copies = %d, want 3", n)
+    }
+    if s := got.firstString("job-name"); s != "отчёт" {
+        t.Errorf("job-name = %q, want %q", s, "отчёт")
+    }
+    if s := got.strings("media-supported"); !reflect.DeepEqual(s, []string{"iso_a4_210x297mm", "na_letter_8.5x11in"}) {
+        t.Errorf("media-supported = %q", s)
+    }
+    if r := got.find("page-ranges").values[1]; !bytes.Equal(r, []byte{0, 0, 0, 5, 0, 0, 0, 5}) {
+        t.Errorf("second page range = % x", r)
+    }
+}
+
+func TestDecodeIPPMessageTruncated(t *testing.T) {
+    req := &ippMessage{code: ippOpGetJobAttributes, requestID: 1}
+    req.group(ippTagOperation)
+    req.addString(ippTagCharset, "attributes-charset", "utf-8")
+    full := req.encode()
+
+    tests := []struct {
+        name string
+        data []byte
+    }{
+        {"empty", nil},
+        {"short header", full[:8]},
+        {"cut name length", full[:10]},
+        {"cut value", full[:len(full)-3]},
+        {"no end tag", full[:len(full)-1]},
+    }
+    for _, tt := range tests {
+        if _, err := decodeIPPMessage(tt.data); !errors.Is(err, errIPPTruncated) {
+            t.Errorf("%s: err = %v, want errIPPTruncated", tt.name, err)
+        }
+    }
+}
+
+func TestIPPJobStatus(t *testing.T) {
+    resp := &ippMessage{code: 0x0000}
+    resp.group(ippTagJob)
+    resp.addInt(ippTagInteger, "job-id", 117)
+    resp.addInt(ippTagEnum, "job-state", IPPJobProcessingStopped)
+    resp.addString(ippTagKeyword, "job-state-reasons", "media-empty", "job-printing")
+    resp.addInt(ippTagInteger, "job-impressions-completed", 12)
+
+    decoded, err := decodeIPPMessage(resp.encode())
+    if err != nil {
+        t.Fatalf("decode: %v", err)
+    }
+    status := decoded.jobStatus()
+    want := &IPPJobStatus{
+        JobID:                117,
+        State:                IPPJobProcessingStopped,
+        StateReasons:         []string{"media-empty", "job-printing"},
+        ImpressionsCompleted: 12,
+    }
+    if !reflect.DeepEqual(status, want) {
+        t.Errorf("jobStatus = %+v, want %+v", status, want)
+    }
+    if name := status.StateName(); name != "processing-stopped" {
+        t.Errorf("StateName = %q", name)
+    }
+    if name := (&IPPJobStatus{State: 42}).StateName(); name != "unknown" {
+        t.Errorf("StateName(42) = %q, want unknown", name)
+    }
+}
+
+func TestIPPStatusErrorIsClientError(t *testing.T) {
+    tests := []struct {
+        code uint16
+        want bool
+    }{
+        {0x0400, true},  // client-error-bad-request
+        {0x0406, true},  // client-error-not-found
+        {0x04FF, true},
+        {0x0500, false}, // server-error-internal-error
+        {0x0507, false},
+        {0x0001, false},
+    }
+    for _, tt := range tests {
+        err := &IPPStatusError{Operation: ippOpCancelJob, Code: tt.code}
+        if got := err.IsClientError(); got != tt.want {
+            t.Errorf("IsClientError(%#04x) = %v, want %v", tt.code, got, tt.want)
+        }
+    }
+}
+
+func TestDocumentFormatFor(t *testing.T) {
+    tests := []struct {
+        name string
+        want string
+    }{
+        {"report.pdf", "application/pdf"},
+        {"REPORT.PDF", "application/pdf"},
+        {"poster.ps", "application/postscript"},
+        {"form.pcl", "application/vnd.hp-PCL"},
+        {"notes.txt", "text/plain"},
+        {"photo.jpeg", "image/jpeg"},
+        {"scan.png", "image/png"},
+        {"archive.zip", "application/octet-stream"},
+        {"noext", "application/octet-stream"},
+    }
+    for _, tt := range tests {
+        if got := DocumentFormatFor(tt.name); got != tt.want {
+            t.Errorf("DocumentFormatFor(%q) = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "os"
+
+    "print-automation/models"
+)
+
+// IPPDriver — печать по IPP/IPPS
+type IPPDriver struct{}
+
+func (d *IPPDriver) Submit(ctx context.Context, printer models.Printer, ticket JobTicket) (*DriverJobStatus, error) {
+    f, err := os.Open(ticket.DocumentPath)
+    if err != nil {
+        return nil, fmt.Errorf("не удалось открыть файл для печати: %w", err)
+    }
+    defer f.Close()
+
+    client := ippClientFor(printer)
+    opts := IPPJobOptions{
+        JobName:        ticket.JobName,
+        UserName:       ticket.UserName,
+        DocumentFormat: ticket.DocumentFormat,
+        Copies:         ticket.Copies,
+        Sides:          ticket.Sides,
+        ColorMode:      ticket.ColorMode,
+        Media:          PWGMediaName(ticket.MediaSize),
+        Orientation:    ticket.Orientation,
+        PageRanges:     ticket.PageRanges,
+        NumberUp:       ticket.NumberUp,
+        Uncollated:     !ticket.Collate,
+    }
+
+    if err := client.ValidateJob(ctx, opts); err != nil {
+        return nil, fmt.Errorf("принтер отклонил задание: %w", err)
+    }
+    status, err := client.PrintJob(ctx, f, opts)
+    if err != nil {
+        return nil, err
+    }
+    return ippDriverStatus(status), nil
+}
+
+func (d *IPPDriver) Probe(ctx context.Context, printer models.Printer) error {
+    port := printer.Port
+    if port == 0 {
+        port = 631
+    }
+    return CheckPrinterConnection(printer.IPAddress, port)
+}
+
+func (d *IPPDriver) QueryStatus(ctx context.Context, printer models.Printer, printerJobID int) (*DriverJobStatus, error) {
+    client := ippClientFor(printer)
+    status, err := client.GetJobAttributes(ctx, printerJobID)
+    if err != nil {
+        return nil, err
+    }
+    return ippDriverStatus(status), nil
+}
+
+func (d *IPPDriver) Cancel(ctx context.Context, printer models.Printer, printerJobID int, userName string) error {
+    client := ippClientFor(printer)
+    return client.CancelJob(ctx, printerJobID, userName)
+}
+
+func (d *IPPDriver) Capabilities(ctx context.Context, printer models.Printer) (*DriverCapabilities, error) {
+    return &DriverCapabilities{JobStatus: true, Cancel: true, PageLayout: true}, nil
+}
+
+// ippDriverStatus переводит job-state IPP в нормализованное состояние драйвера
+func ippDriverStatus(s *IPPJobStatus) *DriverJobStatus {
+    state := DriverJobUnknown
+    switch s.State {
+    case IPPJobPending, IPPJobPendingHeld:
+        state = DriverJobPending
+    case IPPJobProcessing, IPPJobProcessingStopped:
+        state = DriverJobProcessing
+    case IPPJobCompleted:
+        state = DriverJobCompleted
+    case IPPJobCanceled:
+        state = DriverJobCanceled
+    case IPPJobAborted:
+        state = DriverJobAborted
+    }
+    return &DriverJobStatus{
+        PrinterJobID: s.JobID,
+        State:        state,
+        Reasons:      s.StateReasons,
+        PagesPrinted: s.ImpressionsCompleted,
+    }
+}
+
+// ippClientFor создаёт клиент принтера; Queue у IPP-принтера — путь ресурса
+func ippClientFor(printer models.Printer) *IPPClient {
+    return NewIPPClientPath(printer.Protocol, printer.IPAddress, printer.Port, printer.Queue)
+}
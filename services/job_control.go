@@ -0,0 +1,133 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+
+    "print-automation/config"
+    "print-automation/models"
+)
+
+var (
+    // ErrCancelNotSupported — принтер не умеет отменять уже принятое задание
+    ErrCancelNotSupported = errors.New("принтер не поддерживает отмену принятого задания")
+    // ErrAwaitingRelease — задание выпускается у принтера PIN- или QR-кодом, а не через API
+    ErrAwaitingRelease = errors.New("задание ожидает выпуска у принтера по PIN- или QR-коду")
+)
+
+// CancelJob отменяет задание на любом этапе до завершения. Идущая передача на
+// принтер прерывается, а печатающееся задание отменяется на принтере (IPP
+// Cancel-Job, удаление из очереди LPD). Ненапечатанное возвращается клиенту
+// при переходе в canceled (см. refundUnprintedJob).
+func CancelJob(ctx context.Context, job *models.PrintJob, actor, reason string) error {
+    if reason == "" {
+        reason = "задание отменено"
+    }
+    if job.Status == models.JobStatusPrinting {
+        return cancelOnPrinter(ctx, job, actor, reason)
+    }
+
+    from := job.Status
+    err := TransitionJob(job, models.JobStatusCanceled, actor, reason)
+    var transErr *TransitionError
+    if errors.As(err, &transErr) && from == models.JobStatusSending && transErr.From == models.JobStatusPrinting {
+        // Пока отменяли, принтер успел принять задание
+        if err := config.DB.First(job, "id = ?", job.ID).Error; err != nil {
+            return err
+        }
+        return cancelOnPrinter(ctx, job, actor, reason)
+    }
+    if err != nil {
+        return err
+    }
+
+    if from == models.JobStatusSending && printQueue != nil {
+        printQueue.abort(job.ID)
+    }
+    return nil
+}
+
+// cancelOnPrinter отменяет принятое принтером задание и фиксирует, сколько
+// оттисков успело напечататься: от этого зависит возврат
+func cancelOnPrinter(ctx context.Context, job *models.PrintJob, actor, reason string) error {
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", job.PrinterID).Error; err != nil {
+        return err
+    }
+    driver, err := DriverFor(printer.Protocol)
+    if err != nil {
+        return err
+    }
+    caps, err := driver.Capabilities(ctx, printer)
+    if err != nil {
+        return err
+    }
+    if !caps.Cancel || job.PrinterJobID == 0 {
+        return ErrCancelNotSupported
+    }
+
+    if err := driver.Cancel(ctx, printer, job.PrinterJobID, job.UserID); err != nil {
+        if errors.Is(err, ErrDriverNotSupported) {
+            return ErrCancelNotSupported
+        }
+        return fmt.Errorf("принтер не отменил задание: %w", err)
+    }
+
+    if caps.JobStatus {
+        if status, err := driver.QueryStatus(ctx, printer, job.PrinterJobID); err == nil {
+            printed := status.PagesPrinted
+            job.PrinterJobState = status.State
+            job.PagesPrinted = &printed
+        }
+    }
+    return TransitionJob(job, models.JobStatusCanceled, actor, reason, "PrinterJobState", "PagesPrinted")
+}
+
+// ResolveJob фиксирует итог задания, о котором принтер перестал сообщать:
+// оператор проверил принтер и переводит задание в completed или failed
+func ResolveJob(job *models.PrintJob, to, actor, reason string) error {
+    if job.Status != models.JobStatusNeedsCheck || (to != models.JobStatusCompleted && to != models.JobStatusFailed) {
+        return &TransitionError{From: job.Status, To: to}
+    }
+    if reason == "" {
+        reason = "итог печати проверен оператором"
+    }
+    return TransitionJob(job, to, actor, reason)
+}
+
+// HoldJob снимает задание из очереди печати до ResumeJob; место в очереди сохраняется
+func HoldJob(job *models.PrintJob, actor, reason string) error {
+    if job.Status != models.JobStatusQueued {
+        return &TransitionError{From: job.Status, To: models.JobStatusHeld}
+    }
+    if reason == "" {
+        reason = "задание удержано"
+    }
+    return TransitionJob(job, models.JobStatusHeld, actor, reason)
+}
+
+// ResumeJob возвращает удержанное задание в очередь. Задание, которое ещё не
+// стояло в очереди, проходит проверку оплаты, как при отправке на печать.
+func ResumeJob(job *models.PrintJob, actor, reason string) error {
+    if job.Status != models.JobStatusHeld {
+        return &TransitionError{From: job.Status, To: models.JobStatusQueued}
+    }
+    if isAwaitingRelease(job) {
+        return ErrAwaitingRelease
+    }
+    if reason == "" {
+        reason = "задание возвращено в очередь"
+    }
+    if job.QueuedAt == nil {
+        return ReleaseJob(job, actor, reason)
+    }
+
+    if err := TransitionJob(job, models.JobStatusQueued, actor, reason); err != nil {
+        return err
+    }
+    if printQueue != nil {
+        printQueue.notify()
+    }
+    return nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "io"
+    "net"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestPrintQueueAbort(t *testing.T) {
+    q := &PrintQueue{inflight: map[string]context.CancelFunc{}}
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    if q.abort("job-1") {
+        t.Error("abort of a job that is not being sent reported success")
+    }
+    q.setInflight("job-1", cancel)
+    if !q.abort("job-1") || ctx.Err() == nil {
+        t.Error("abort did not cancel the transfer in flight")
+    }
+
+    // После передачи задание из списка убирается, и отмена уже ничего не прерывает
+    q.setInflight("job-1", nil)
+    if q.abort("job-1") {
+        t.Error("abort after the transfer finished reported success")
+    }
+}
+
+func TestStreamRaw(t *testing.T) {
+    client, printer := net.Pipe()
+    defer client.Close()
+    received := make(chan []byte, 1)
+    go func() {
+        data, _ := io.ReadAll(printer)
+        received <- data
+    }()
+
+    payload := bytes.Repeat([]byte("page\f"), 1000)
+    if err := streamRaw(context.Background(), client, bytes.NewReader(payload)); err != nil {
+        t.Fatalf("streamRaw: %v", err)
+    }
+    client.Close()
+    if got := <-received; !bytes.Equal(got, payload) {
+        t.Errorf("printer received %d bytes, want %d", len(got), len(payload))
+    }
+}
+
+func TestStreamRawAbort(t *testing.T) {
+    // Принтер ничего не читает: без отмены передача зависла бы навсегда
+    client, printer := net.Pipe()
+    defer client.Close()
+    defer printer.Close()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error, 1)
+    go func() {
+        done <- streamRaw(ctx, client, strings.NewReader("document"))
+    }()
+    time.Sleep(20 * time.Millisecond)
+    cancel()
+
+    select {
+    case err := <-done:
+        if !errors.Is(err, context.Canceled) {
+            t.Errorf("err = %v, want context.Canceled", err)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("canceling the context did not interrupt the transfer")
+    }
+}
+
+func TestStreamRawWriteError(t *testing.T) {
+    client, printer := net.Pipe()
+    printer.Close()
+    defer client.Close()
+
+    err := streamRaw(context.Background(), client, strings.NewReader("document"))
+    if err == nil || errors.Is(err, context.Canceled) {
+        t.Errorf("err = %v, want a transfer error", err)
+    }
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+
+    "print-automation/models"
+)
+
+// JobOptionsError — некорректные параметры печати задания
+type JobOptionsError struct {
+    Field   string
+    Message string
+}
+
+func (e *JobOptionsError) Error() string {
+    return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// NormalizeJobOptions подставляет значения по умолчанию и проверяет параметры печати
+func NormalizeJobOptions(job *models.PrintJob) error {
+    if job.Copies == 0 {
+        job.Copies = 1
+    }
+    if job.Copies < 1 {
+        return &JobOptionsError{Field: "copies", Message: "должно быть не меньше 1"}
+    }
+
+    job.ColorMode = strings.ToLower(strings.TrimSpace(job.ColorMode))
+    switch job.ColorMode {
+    case "":
+        job.ColorMode = models.ColorModeMonochrome
+    case models.ColorModeMonochrome, models.ColorModeColor:
+    default:
+        return &JobOptionsError{Field: "color_mode", Message: "допустимо monochrome или color"}
+    }
+
+    job.Sides = strings.ToLower(strings.TrimSpace(job.Sides))
+    switch job.Sides {
+    case "":
+        job.Sides = models.SidesOneSided
+    case models.SidesOneSided, models.SidesTwoSidedLongEdge, models.SidesTwoSidedShortEdge:
+    default:
+        return &JobOptionsError{Field: "sides", Message: "допустимо one-sided, two-sided-long-edge или two-sided-short-edge"}
+    }
+
+    job.MediaSize = strings.TrimSpace(job.MediaSize)
+    if job.MediaSize == "" {
+        job.MediaSize = models.DefaultMediaSize
+    }
+
+    job.Orientation = strings.ToLower(strings.TrimSpace(job.Orientation))
+    switch job.Orientation {
+    case "":
+        job.Orientation = models.OrientationPortrait
+    case models.OrientationPortrait, models.OrientationLandscape, models.OrientationReverseLandscape, models.OrientationReversePortrait:
+    default:
+        return &JobOptionsError{Field: "orientation", Message: "допустимо portrait, landscape, reverse-landscape или reverse-portrait"}
+    }
+
+    ranges, err := ParsePageRanges(job.PageRanges)
+    if err != nil {
+        return err
+    }
+    job.PageRanges = FormatPageRanges(ranges)
+    if len(ranges) > 0 && job.Pages > 0 && SelectedPages(job) == 0 {
+        return &JobOptionsError{Field: "page_ranges", Message: "не попадает ни на одну страницу документа"}
+    }
+
+    if job.NumberUp == 0 {
+        job.NumberUp = 1
+    }
+    if !validNumberUp[job.NumberUp] {
+        return &JobOptionsError{Field: "number_up", Message: "допустимо 1, 2, 4, 6, 9 или 16"}
+    }
+
+    if job.Collate == nil {
+        collate := true
+        job.Collate = &collate
+    }
+    return nil
+}
+
+// validNumberUp — допустимое число страниц на стороне листа (как у IPP number-up)
+var validNumberUp = map[int]bool{1: true, 2: true, 4: true, 6: true, 9: true, 16: true}
+
+// PageRange — диапазон страниц документа, включительно
+type PageRange struct {
+    From int
+    To   int
+}
+
+// ParsePageRanges разбирает диапазоны вида "1-3,5,8-10". Диапазоны должны
+// идти по возрастанию и не пересекаться (так их принимает IPP page-ranges).
+func ParsePageRanges(s string) ([]PageRange, error) {
+    var ranges []PageRange
+    for _, part := range strings.Split(s, ",") {
+        part = strings.TrimSpace(part)
+        if part == "" {
+            continue
+        }
+        from, to, isRange := strings.Cut(part, "-")
+        r := PageRange{}
+        var err1, err2 error
+        r.From, err1 = strconv.Atoi(strings.TrimSpace(from))
+        r.To = r.From
+        if isRange {
+            r.To, err2 = strconv.Atoi(strings.TrimSpace(to))
+        }
+        if err1 != nil || err2 != nil || r.From < 1 || r.To < r.From {
+            return nil, &JobOptionsError{Field: "page_ranges", Message: fmt.Sprintf("некорректный диапазон %q", part)}
+        }
+        if len(ranges) > 0 && r.From <= ranges[len(ranges)-1].To {
+            return nil, &JobOptionsError{Field: "page_ranges", Message: "диапазоны должны идти по возрастанию и не пересекаться"}
+        }
+        ranges = append(ranges, r)
+    }
+    return ranges, nil
+}
+
+// FormatPageRanges записывает диапазоны в каноническом виде
+func FormatPageRanges(ranges []PageRange) string {
+    parts := make([]string, 0, len(ranges))
+    for _, r := range ranges {
+        if r.From == r.To {
+            parts = append(parts, strconv.Itoa(r.From))
+        } else {
+            parts = append(parts, fmt.Sprintf("%d-%d", r.From, r.To))
+        }
+    }
+    return strings.Join(parts, ",")
+}
+
+// SelectedPages — сколько страниц документа будет напечатано с учётом диапазонов.
+// Пока число страниц неизвестно, диапазоны считаются целиком.
+func SelectedPages(job *models.PrintJob) int {
+    ranges, err := ParsePageRanges(job.PageRanges)
+    if err != nil || len(ranges) == 0 {
+        return job.Pages
+    }
+    count := 0
+    for _, r := range ranges {
+        to := r.To
+        if job.Pages > 0 && to > job.Pages {
+            to = job.Pages
+        }
+        if to >= r.From {
+            count += to - r.From + 1
+        }
+    }
+    return count
+}
+
+// SidesPerCopy — сколько сторон листа занимает один экземпляр задания
+// с учётом диапазонов и размещения нескольких страниц на стороне
+func SidesPerCopy(job *models.PrintJob) int {
+    pages := SelectedPages(job)
+    if pages < 1 {
+        pages = 1
+    }
+    nup := job.NumberUp
+    if nup < 1 {
+        nup = 1
+    }
+    return (pages + nup - 1) / nup
+}
+
+// IsCollated сообщает, что копии печатаются комплектами
+func IsCollated(job *models.PrintJob) bool {
+    return job.Collate == nil || *job.Collate
+}
+
+// IsDuplex сообщает, что задание печатается с двух сторон
+func IsDuplex(sides string) bool {
+    return sides == models.SidesTwoSidedLongEdge || sides == models.SidesTwoSidedShortEdge
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+    "errors"
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestParsePageRanges(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    string
+        wantErr bool
+    }{
+        {"", "", false},
+        {"1", "1", false},
+        {"1-3,5,8-10", "1-3,5,8-10", false},
+        {" 1 - 3 , 5 ", "1-3,5", false},
+        {"2-2", "2", false},
+        {"1,,3", "1,3", false},
+        {"0", "", true},
+        {"3-1", "", true},
+        {"1-3,2-5", "", true},
+        {"5,1", "", true},
+        {"1-", "", true},
+        {"-3", "", true},
+        {"a-b", "", true},
+        {"1-3-5", "", true},
+    }
+    for _, tt := range tests {
+        ranges, err := ParsePageRanges(tt.in)
+        if tt.wantErr {
+            var optErr *JobOptionsError
+            if !errors.As(err, &optErr) || optErr.Field != "page_ranges" {
+                t.Errorf("ParsePageRanges(%q) = %v, %v; want a page_ranges error", tt.in, ranges, err)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("ParsePageRanges(%q): %v", tt.in, err)
+            continue
+        }
+        if got := FormatPageRanges(ranges); got != tt.want {
+            t.Errorf("FormatPageRanges(ParsePageRanges(%q)) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestSelectedPagesAndSides(t *testing.T) {
+    tests := []struct {
+        name        string
+        job         models.PrintJob
+        selected    int
+        sides       int
+        impressions int
+    }{
+        {"whole document", models.PrintJob{Pages: 10, Copies: 1}, 10, 10, 10},
+        {"ranges", models.PrintJob{Pages: 10, Copies: 1, PageRanges: "1-3,8"}, 4, 4, 4},
+        {"range past the end is clipped", models.PrintJob{Pages: 5, Copies: 1, PageRanges: "4-9"}, 2, 2, 2},
+        {"range entirely past the end", models.PrintJob{Pages: 5, Copies: 1, PageRanges: "7-9"}, 0, 1, 1},
+        {"unknown page count counts ranges", models.PrintJob{PageRanges: "2-4"}, 3, 3, 3},
+        {"unknown page count, no ranges", models.PrintJob{}, 0, 1, 1},
+        {"2-up rounds up", models.PrintJob{Pages: 5, Copies: 1, NumberUp: 2}, 5, 3, 3},
+        {"4-up with ranges and copies", models.PrintJob{Pages: 20, Copies: 3, NumberUp: 4, PageRanges: "1-9"}, 9, 3, 9},
+        {"duplex does not change sides", models.PrintJob{Pages: 7, Copies: 2, Sides: models.SidesTwoSidedLongEdge}, 7, 7, 14},
+    }
+    for _, tt := range tests {
+        if got := SelectedPages(&tt.job); got != tt.selected {
+            t.Errorf("%s: SelectedPages = %d, want %d", tt.name, got, tt.selected)
+        }
+        if got := SidesPerCopy(&tt.job); got != tt.sides {
+            t.Errorf("%s: SidesPerCopy = %d, want %d", tt.name, got, tt.sides)
+        }
+        if got := JobImpressions(&tt.job); got != tt.impressions {
+            t.Errorf("%s: JobImpressions = %d, want %d", tt.name, got, tt.impressions)
+        }
+    }
+}
+
+func TestNormalizeJobOptions(t *testing.T) {
+    job := models.PrintJob{Pages: 4, ColorMode: " Color ", Sides: "TWO-SIDED-LONG-EDGE", PageRanges: " 1 - 2 ,4"}
+    if err := NormalizeJobOptions(&job); err != nil {
+        t.Fatal(err)
+    }
+    if job.Copies != 1 || job.ColorMode != models.ColorModeColor || job.Sides != models.SidesTwoSidedLongEdge ||
+        job.MediaSize != models.DefaultMediaSize || job.Orientation != models.OrientationPortrait ||
+        job.PageRanges != "1-2,4" || job.NumberUp != 1 || job.Collate == nil || !*job.Collate {
+        t.Errorf("defaults not applied: %+v", job)
+    }
+
+    notCollated := false
+    kept := models.PrintJob{Copies: 2, Collate: &notCollated, NumberUp: 16, Orientation: "landscape"}
+    if err := NormalizeJobOptions(&kept); err != nil || *kept.Collate || kept.NumberUp != 16 || IsCollated(&kept) {
+        t.Errorf("explicit options changed: %+v, %v", kept, err)
+    }
+
+    tests := []struct {
+        name  string
+        job   models.PrintJob
+        field string
+    }{
+        {"negative copies", models.PrintJob{Copies: -1}, "copies"},
+        {"unknown color mode", models.PrintJob{ColorMode: "sepia"}, "color_mode"},
+        {"unknown sides", models.PrintJob{Sides: "duplex"}, "sides"},
+        {"unknown orientation", models.PrintJob{Orientation: "upside-down"}, "orientation"},
+        {"bad ranges", models.PrintJob{PageRanges: "3-1"}, "page_ranges"},
+        {"ranges outside the document", models.PrintJob{Pages: 3, PageRanges: "5-6"}, "page_ranges"},
+        {"3-up", models.PrintJob{NumberUp: 3}, "number_up"},
+        {"negative n-up", models.PrintJob{NumberUp: -2}, "number_up"},
+    }
+    for _, tt := range tests {
+        err := NormalizeJobOptions(&tt.job)
+        var optErr *JobOptionsError
+        if !errors.As(err, &optErr) || optErr.Field != tt.field {
+            t.Errorf("%s: err = %v, want a %s error", tt.name, err, tt.field)
+        }
+    }
+}
+
+func TestIsDuplex(t *testing.T) {
+    tests := map[string]bool{
+        models.SidesOneSided:          false,
+        models.SidesTwoSidedLongEdge:  true,
+        models.SidesTwoSidedShortEdge: true,
+        "":                            false,
+    }
+    for sides, want := range tests {
+        if got := IsDuplex(sides); got != want {
+            t.Errorf("IsDuplex(%q) = %v, want %v", sides, got, want)
+        }
+    }
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// Инициаторы переходов, не связанные с конкретным пользователем
+const (
+    ActorSystem = "system"
+    ActorAPI    = "api"
+)
+
+// ErrIllegalTransition — переход между статусами задания запрещён
+var ErrIllegalTransition = errors.New("недопустимый переход статуса задания")
+
+// TransitionError описывает отклонённый переход
+type TransitionError struct {
+    From string
+    To   string
+}
+
+func (e *TransitionError) Error() string {
+    return fmt.Sprintf("недопустимый переход статуса задания: %s → %s", e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+    return target == ErrIllegalTransition
+}
+
+// jobTransitions — жизненный цикл задания:
+// created → awaiting_payment → queued → sending → printing → completed / failed / canceled,
+// с возможностью удержания (held) до начала отправки. Задание, о котором принтер
+// перестал сообщать, ждёт решения оператора в needs_check.
+var jobTransitions = map[string][]string{
+    models.JobStatusCreated:         {models.JobStatusAwaitingPayment, models.JobStatusQueued, models.JobStatusHeld, models.JobStatusCanceled},
+    models.JobStatusAwaitingPayment: {models.JobStatusQueued, models.JobStatusHeld, models.JobStatusCanceled},
+    models.JobStatusHeld:            {models.JobStatusQueued, models.JobStatusCanceled},
+    models.JobStatusQueued:          {models.JobStatusSending, models.JobStatusHeld, models.JobStatusCanceled},
+    models.JobStatusSending:         {models.JobStatusPrinting, models.JobStatusQueued, models.JobStatusFailed, models.JobStatusCanceled},
+    models.JobStatusPrinting:        {models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCanceled, models.JobStatusNeedsCheck},
+    models.JobStatusNeedsCheck:      {models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCanceled},
+    models.JobStatusCompleted:       {},
+    models.JobStatusFailed:          {},
+    models.JobStatusCanceled:        {},
+}
+
+// IsJobStatus сообщает, что статус входит в жизненный цикл задания
+func IsJobStatus(status string) bool {
+    _, ok := jobTransitions[status]
+    return ok
+}
+
+// IsTerminalJobStatus сообщает, что из статуса больше нет переходов
+func IsTerminalJobStatus(status string) bool {
+    next, ok := jobTransitions[status]
+    return ok && len(next) == 0
+}
+
+// CanTransitionJob проверяет, разрешён ли переход from → to
+func CanTransitionJob(from, to string) bool {
+    for _, next := range jobTransitions[from] {
+        if next == to {
+            return true
+        }
+    }
+    return false
+}
+
+// TransitionJob переводит задание в статус to и записывает событие в job_events.
+// Дополнительно сохраняются перечисленные в columns поля job (их нужно заполнить заранее).
+// Обновление условное (WHERE status = текущий), поэтому параллельный переход
+// того же задания вернёт ErrIllegalTransition.
+func TransitionJob(job *models.PrintJob, to, actor, reason string, columns ...string) error {
+    from := job.Status
+    if !CanTransitionJob(from, to) {
+        return &TransitionError{From: from, To: to}
+    }
+
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        job.Status = to
+        fields := append([]string{"Status", "UpdatedAt"}, columns...)
+        res := tx.Model(job).Where("status = ?", from).Select(fields).Updates(job)
+        if res.Error != nil {
+            return res.Error
+        }
+        if res.RowsAffected == 0 {
+            var current models.PrintJob
+            if err := tx.Select("status").First(&current, "id = ?", job.ID).Error; err != nil {
+                return err
+            }
+            return &TransitionError{From: current.Status, To: to}
+        }
+        return RecordJobEvent(tx, job.ID, from, to, actor, reason)
+    })
+    if err != nil {
+        job.Status = from
+        return err
+    }
+
+    // Ненапечатанное в прерванном задании возвращается клиенту
+    if to == models.JobStatusFailed || to == models.JobStatusCanceled {
+        refundUnprintedJob(job, from)
+    }
+    return nil
+}
+
+// RecordJobEvent добавляет запись в историю задания
+func RecordJobEvent(tx *gorm.DB, jobID, from, to, actor, reason string) error {
+    if actor == "" {
+        actor = ActorSystem
+    }
+    return tx.Create(&models.JobEvent{
+        PrintJobID: jobID,
+        FromStatus: from,
+        ToStatus:   to,
+        Actor:      actor,
+        Reason:     truncateRunes(reason, 500),
+    }).Error
+}
+
+// truncateRunes обрезает строку до n символов под размер varchar-колонки
+func truncateRunes(s string, n int) string {
+    if r := []rune(s); len(r) > n {
+        return string(r[:n])
+    }
+    return s
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestCanTransitionJob(t *testing.T) {
+    tests := []struct {
+        from, to string
+        want     bool
+    }{
+        {models.JobStatusCreated, models.JobStatusAwaitingPayment, true},
+        {models.JobStatusCreated, models.JobStatusQueued, true},
+        {models.JobStatusAwaitingPayment, models.JobStatusQueued, true},
+        {models.JobStatusQueued, models.JobStatusSending, true},
+        {models.JobStatusQueued, models.JobStatusHeld, true},
+        {models.JobStatusHeld, models.JobStatusQueued, true},
+        {models.JobStatusSending, models.JobStatusPrinting, true},
+        {models.JobStatusSending, models.JobStatusQueued, true}, // повтор после временной ошибки
+        {models.JobStatusPrinting, models.JobStatusCompleted, true},
+        {models.JobStatusPrinting, models.JobStatusCanceled, true},
+        {models.JobStatusPrinting, models.JobStatusNeedsCheck, true},
+        {models.JobStatusNeedsCheck, models.JobStatusCompleted, true},
+        {models.JobStatusNeedsCheck, models.JobStatusFailed, true},
+        {models.JobStatusNeedsCheck, models.JobStatusCanceled, true},
+
+        // Печать не начинается в обход очереди и не возобновляется после завершения
+        {models.JobStatusCreated, models.JobStatusSending, false},
+        {models.JobStatusCreated, models.JobStatusPrinting, false},
+        {models.JobStatusAwaitingPayment, models.JobStatusSending, false},
+        {models.JobStatusHeld, models.JobStatusSending, false},
+        {models.JobStatusPrinting, models.JobStatusQueued, false},
+        {models.JobStatusPrinting, models.JobStatusHeld, false},
+        {models.JobStatusCompleted, models.JobStatusQueued, false},
+        {models.JobStatusFailed, models.JobStatusQueued, false},
+        {models.JobStatusCanceled, models.JobStatusQueued, false},
+        {models.JobStatusNeedsCheck, models.JobStatusPrinting, false},
+        {models.JobStatusNeedsCheck, models.JobStatusQueued, false},
+        {models.JobStatusSending, models.JobStatusNeedsCheck, false},
+        {"pending", models.JobStatusQueued, false},
+        {models.JobStatusQueued, "pending", false},
+    }
+    for _, tt := range tests {
+        if got := CanTransitionJob(tt.from, tt.to); got != tt.want {
+            t.Errorf("CanTransitionJob(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+        }
+    }
+}
+
+func TestJobStatusClassification(t *testing.T) {
+    tests := []struct {
+        status   string
+        known    bool
+        terminal bool
+    }{
+        {models.JobStatusCreated, true, false},
+        {models.JobStatusAwaitingPayment, true, false},
+        {models.JobStatusHeld, true, false},
+        {models.JobStatusQueued, true, false},
+        {models.JobStatusSending, true, false},
+        {models.JobStatusPrinting, true, false},
+        {models.JobStatusNeedsCheck, true, false},
+        {models.JobStatusCompleted, true, true},
+        {models.JobStatusFailed, true, true},
+        {models.JobStatusCanceled, true, true},
+        {"pending", false, false},
+        {"", false, false},
+    }
+    for _, tt := range tests {
+        if got := IsJobStatus(tt.status); got != tt.known {
+            t.Errorf("IsJobStatus(%q) = %v, want %v", tt.status, got, tt.known)
+        }
+        if got := IsTerminalJobStatus(tt.status); got != tt.terminal {
+            t.Errorf("IsTerminalJobStatus(%q) = %v, want %v", tt.status, got, tt.terminal)
+        }
+    }
+}
+
+// Каждый нетерминальный статус должен вести к завершению: задание не может застрять
+func TestJobTransitionsReachTerminal(t *testing.T) {
+    for status := range jobTransitions {
+        seen := map[string]bool{}
+        queue := []string{status}
+        reached := false
+        for len(queue) > 0 && !reached {
+            current := queue[0]
+            queue = queue[1:]
+            if IsTerminalJobStatus(current) {
+                reached = true
+                break
+            }
+            for _, next := range jobTransitions[current] {
+                if !IsJobStatus(next) {
+                    t.Fatalf("переход %s → %s ведёт в неизвестный статус", current, next)
+                }
+                if !seen[next] {
+                    seen[next] = true
+                    queue = append(queue, next)
+                }
+            }
+        }
+        if !reached {
+            t.Errorf("из статуса %s нельзя дойти до завершения", status)
+        }
+    }
+}
+
+func TestTransitionErrorIs(t *testing.T) {
+    err := fmt.Errorf("отмена: %w", &TransitionError{From: models.JobStatusCompleted, To: models.JobStatusCanceled})
+    if !errors.Is(err, ErrIllegalTransition) {
+        t.Errorf("errors.Is(%v, ErrIllegalTransition) = false", err)
+    }
+    var te *TransitionError
+    if !errors.As(err, &te) || te.From != models.JobStatusCompleted {
+        t.Errorf("errors.As did not recover the transition: %+v", te)
+    }
+}
+
+func TestTruncateRunes(t *testing.T) {
+    tests := []struct {
+        in   string
+        n    int
+        want string
+    }{
+        {"short", 10, "short"},
+        {"exactly", 7, "exactly"},
+        {"принтер недоступен", 7, "принтер"},
+        {"", 3, ""},
+    }
+    for _, tt := range tests {
+        if got := truncateRunes(tt.in, tt.n); got != tt.want {
+            t.Errorf("truncateRunes(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+        }
+    }
+}
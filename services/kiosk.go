@@ -0,0 +1,66 @@
+package services
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/hex"
+    "errors"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// ErrKioskKeyInvalid — ключ киоска не выдан этому принтеру
+var ErrKioskKeyInvalid = errors.New("неверный ключ киоска")
+
+// IssueKioskKey выдаёт киоску принтера новый ключ; прежний перестаёт действовать
+func IssueKioskKey(printerID string) (string, error) {
+    raw := make([]byte, 32)
+    if _, err := rand.Read(raw); err != nil {
+        return "", err
+    }
+    key := hex.EncodeToString(raw)
+
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Delete(&models.KioskCredential{}, "printer_id = ?", printerID).Error; err != nil {
+            return err
+        }
+        return tx.Create(&models.KioskCredential{PrinterID: printerID, KeyHash: kioskKeyHash(key)}).Error
+    })
+    if err != nil {
+        return "", err
+    }
+    return key, nil
+}
+
+// RevokeKioskKey отключает киоск принтера
+func RevokeKioskKey(printerID string) error {
+    return config.DB.Delete(&models.KioskCredential{}, "printer_id = ?", printerID).Error
+}
+
+// VerifyKioskKey проверяет, что ключ выдан киоску именно этого принтера
+func VerifyKioskKey(printerID, key string) error {
+    if printerID == "" || key == "" {
+        return ErrKioskKeyInvalid
+    }
+    var cred models.KioskCredential
+    err := config.DB.Where("printer_id = ?", printerID).First(&cred).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return ErrKioskKeyInvalid
+    }
+    if err != nil {
+        return err
+    }
+    if subtle.ConstantTimeCompare([]byte(kioskKeyHash(key)), []byte(cred.KeyHash)) != 1 {
+        return ErrKioskKeyInvalid
+    }
+    return nil
+}
+
+// kioskKeyHash — SHA-256 ключа киоска (ключ случайный, соль не нужна)
+func kioskKeyHash(key string) string {
+    sum := sha256.Sum256([]byte(key))
+    return hex.EncodeToString(sum[:])
+}
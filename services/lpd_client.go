@@ -0,0 +1,256 @@
+package services
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "net"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync/atomic"
+    "time"
+)
+
+// DefaultLPDQueue — имя очереди, если у принтера она не задана
+const DefaultLPDQueue = "lp"
+
+var lpdJobNumber uint32
+
+// LPRJobOptions — параметры управляющего файла LPR (RFC 1179, раздел 7)
+type LPRJobOptions struct {
+    Queue    string
+    UserName string
+    JobName  string
+    FileName string
+    Copies   int
+    Banner   bool
+    // Options — параметры печати "ключ=значение" для строки "O" управляющего файла
+    // (расширение LPRng/CUPS; серверы по RFC 1179 её пропускают)
+    Options  []string
+}
+
+// SendToPrinterLPR отправляет локальный файл на принтер или принт-сервер по протоколу LPD (порт 515).
+// Возвращает номер задания, под которым оно передано серверу. Отмена ctx обрывает передачу.
+func SendToPrinterLPR(ctx context.Context, filePath, printerIP string, printerPort int, opts LPRJobOptions) (int, error) {
+    f, err := os.Open(filePath)
+    if err != nil {
+        return 0, fmt.Errorf("не удалось открыть файл для печати: %w", err)
+    }
+    defer f.Close()
+
+    info, err := f.Stat()
+    if err != nil {
+        return 0, fmt.Errorf("не удалось определить размер файла: %w", err)
+    }
+
+    if printerPort == 0 {
+        printerPort = 515
+    }
+    if opts.Queue == "" {
+        opts.Queue = DefaultLPDQueue
+    }
+    if opts.FileName == "" {
+        opts.FileName = filepath.Base(filePath)
+    }
+
+    host := lpdHostName()
+    jobNum := atomic.AddUint32(&lpdJobNumber, 1)%999 + 1
+    dataName := fmt.Sprintf("dfA%03d%s", jobNum, host)
+    controlName := fmt.Sprintf("cfA%03d%s", jobNum, host)
+    control := buildLPRControlFile(host, dataName, opts)
+
+    addr := net.JoinHostPort(printerIP, strconv.Itoa(printerPort))
+    dialer := net.Dialer{Timeout: 5 * time.Second}
+    conn, err := dialer.DialContext(ctx, "tcp", addr)
+    if err != nil {
+        return 0, fmt.Errorf("не удалось подключиться к LPD-серверу [%s]: %w", addr, err)
+    }
+    defer conn.Close()
+    stop := context.AfterFunc(ctx, func() { conn.Close() })
+    defer stop()
+
+    if err := sendLPRJob(conn, f, info.Size(), dataName, controlName, control, opts.Queue); err != nil {
+        if ctx.Err() != nil {
+            return 0, fmt.Errorf("передача задания на LPD-сервер прервана: %w", ctx.Err())
+        }
+        return 0, err
+    }
+    return int(jobNum), nil
+}
+
+// sendLPRJob передаёт файл данных и управляющий файл по открытому соединению
+func sendLPRJob(conn net.Conn, data io.Reader, size int64, dataName, controlName string, control []byte, queue string) error {
+    r := bufio.NewReader(conn)
+
+    // 02 — "receive a printer job"
+    if err := lpdCommand(conn, r, fmt.Sprintf("\x02%s\n", queue)); err != nil {
+        return fmt.Errorf("LPD-сервер отклонил очередь %q: %w", queue, err)
+    }
+
+    // 03 — файл данных (передаём первым, чтобы сервер не начал печать без него)
+    if err := lpdCommand(conn, r, fmt.Sprintf("\x03%d %s\n", size, dataName)); err != nil {
+        return fmt.Errorf("LPD-сервер отклонил файл данных: %w", err)
+    }
+    // Срок продлевается с каждой записью: большой файл передаётся сколько нужно,
+    // а сервер, переставший читать, не держит обработчик очереди бесконечно
+    if _, err := io.Copy(lpdDeadlineWriter{conn}, data); err != nil {
+        return fmt.Errorf("ошибка при передаче данных на принтер: %w", err)
+    }
+    if err := lpdCommand(conn, r, "\x00"); err != nil {
+        return fmt.Errorf("LPD-сервер не подтвердил файл данных: %w", err)
+    }
+
+    // 02 — управляющий файл
+    if err := lpdCommand(conn, r, fmt.Sprintf("\x02%d %s\n", len(control), controlName)); err != nil {
+        return fmt.Errorf("LPD-сервер отклонил управляющий файл: %w", err)
+    }
+    if err := lpdCommand(conn, r, string(control)+"\x00"); err != nil {
+        return fmt.Errorf("LPD-сервер не подтвердил управляющий файл: %w", err)
+    }
+    return nil
+}
+
+// lpdWriteTimeout — сколько ждать, пока сервер примет очередную порцию данных
+const lpdWriteTimeout = 30 * time.Second
+
+// lpdDeadlineWriter продлевает срок записи перед каждой порцией данных
+type lpdDeadlineWriter struct {
+    conn net.Conn
+}
+
+func (w lpdDeadlineWriter) Write(p []byte) (int, error) {
+    w.conn.SetWriteDeadline(time.Now().Add(lpdWriteTimeout))
+    return w.conn.Write(p)
+}
+
+// CancelLPRJob удаляет задание из очереди LPD-сервера (команда 05).
+// userName должен совпадать с отправителем (строка "P" управляющего файла),
+// иначе сервер откажет в удалении.
+func CancelLPRJob(ctx context.Context, printerIP string, printerPort int, queue, userName string, jobNum int) error {
+    if printerPort == 0 {
+        printerPort = 515
+    }
+    if queue == "" {
+        queue = DefaultLPDQueue
+    }
+    user := lpdSanitize(userName, 31)
+    if user == "" {
+        user = "print-automation"
+    }
+
+    addr := net.JoinHostPort(printerIP, strconv.Itoa(printerPort))
+    dialer := net.Dialer{Timeout: 5 * time.Second}
+    conn, err := dialer.DialContext(ctx, "tcp", addr)
+    if err != nil {
+        return fmt.Errorf("не удалось подключиться к LPD-серверу [%s]: %w", addr, err)
+    }
+    defer conn.Close()
+    stop := context.AfterFunc(ctx, func() { conn.Close() })
+    defer stop()
+
+    conn.SetDeadline(time.Now().Add(10 * time.Second))
+    if _, err := fmt.Fprintf(conn, "\x05%s %s %03d\n", queue, user, jobNum); err != nil {
+        return fmt.Errorf("ошибка при передаче команды удаления: %w", err)
+    }
+    return readLPDRemoveAck(conn)
+}
+
+// readLPDRemoveAck разбирает ответ на команду удаления. RFC 1179 ответа не требует:
+// сервер, закрывший соединение молча, задание удалил. Нулевой байт — тоже успех;
+// иначе сервер присылает код или текст отказа.
+func readLPDRemoveAck(r io.Reader) error {
+    first := make([]byte, 1)
+    if _, err := io.ReadFull(r, first); err != nil {
+        if err == io.EOF {
+            return nil
+        }
+        return fmt.Errorf("LPD-сервер не подтвердил удаление задания: %w", err)
+    }
+    if first[0] == 0 {
+        return nil
+    }
+    rest, _ := io.ReadAll(io.LimitReader(r, 511))
+    message := strings.TrimSpace(lpdSanitize(string(append(first, rest...)), 255))
+    if message == "" {
+        message = fmt.Sprintf("код ответа %d", first[0])
+    }
+    return fmt.Errorf("LPD-сервер отказал в удалении задания: %s", message)
+}
+
+// buildLPRControlFile формирует управляющий файл задания
+func buildLPRControlFile(host, dataName string, opts LPRJobOptions) []byte {
+    var b bytes.Buffer
+    user := lpdSanitize(opts.UserName, 31)
+    if user == "" {
+        user = "print-automation"
+    }
+    jobName := lpdSanitize(opts.JobName, 99)
+
+    fmt.Fprintf(&b, "H%s\n", host)
+    fmt.Fprintf(&b, "P%s\n", user)
+    if jobName != "" {
+        fmt.Fprintf(&b, "J%s\n", jobName)
+    }
+    if opts.Banner {
+        // C и L задают класс и имя пользователя на баннерной странице
+        fmt.Fprintf(&b, "C%s\n", host)
+        fmt.Fprintf(&b, "L%s\n", user)
+    }
+    if len(opts.Options) > 0 {
+        fmt.Fprintf(&b, "O%s\n", lpdSanitize(strings.Join(opts.Options, " "), 255))
+    }
+    copies := opts.Copies
+    if copies < 1 {
+        copies = 1
+    }
+    // "l" — печать без фильтрации управляющих символов (PDF/PS/PCL как есть)
+    for i := 0; i < copies; i++ {
+        fmt.Fprintf(&b, "l%s\n", dataName)
+    }
+    fmt.Fprintf(&b, "U%s\n", dataName)
+    fmt.Fprintf(&b, "N%s\n", lpdSanitize(opts.FileName, 131))
+    return b.Bytes()
+}
+
+// lpdCommand отправляет команду и ждёт нулевой байт подтверждения
+func lpdCommand(conn net.Conn, r *bufio.Reader, cmd string) error {
+    conn.SetDeadline(time.Now().Add(lpdWriteTimeout))
+    if _, err := io.WriteString(conn, cmd); err != nil {
+        return err
+    }
+    ack, err := r.ReadByte()
+    if err != nil {
+        return err
+    }
+    if ack != 0 {
+        return fmt.Errorf("код ответа %d", ack)
+    }
+    return nil
+}
+
+// lpdHostName возвращает имя хоста, допустимое в именах файлов LPD
+func lpdHostName() string {
+    host, err := os.Hostname()
+    if err != nil || host == "" {
+        host = "localhost"
+    }
+    return lpdSanitize(host, 31)
+}
+
+// lpdSanitize убирает управляющие символы и ограничивает длину поля
+func lpdSanitize(s string, max int) string {
+    s = strings.Map(func(r rune) rune {
+        if r < 0x20 || r == 0x7f {
+            return -1
+        }
+        return r
+    }, s)
+    if len(s) > max {
+        s = s[:max]
+    }
+    return s
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "syscall"
+    "testing"
+    "testing/iotest"
+    "time"
+)
+
+func TestBuildLPRControlFile(t *testing.T) {
+    tests := []struct {
+        name string
+        opts LPRJobOptions
+        want []string
+    }{
+        {
+            name: "minimal",
+            opts: LPRJobOptions{FileName: "doc.pdf"},
+            want: []string{"Hhost", "Pprint-automation", "ldfA001host", "UdfA001host", "Ndoc.pdf"},
+        },
+        {
+            name: "copies, banner and options",
+            opts: LPRJobOptions{UserName: "u1", JobName: "job", FileName: "a.ps", Copies: 2, Banner: true,
+                Options: []string{"sides=two-sided-long-edge", "media=A4"}},
+            want: []string{"Hhost", "Pu1", "Jjob", "Chost", "Lu1", "Osides=two-sided-long-edge media=A4",
+                "ldfA001host", "ldfA001host", "UdfA001host", "Na.ps"},
+        },
+        {
+            name: "control characters stripped",
+            opts: LPRJobOptions{UserName: "evil\nPadmin", JobName: "x\x00y", FileName: "f\r.txt"},
+            want: []string{"Hhost", "PevilPadmin", "Jxy", "ldfA001host", "UdfA001host", "Nf.txt"},
+        },
+        {
+            name: "long user name truncated",
+            opts: LPRJobOptions{UserName: strings.Repeat("u", 40), FileName: "f"},
+            want: []string{"Hhost", "P" + strings.Repeat("u", 31), "ldfA001host", "UdfA001host", "Nf"},
+        },
+    }
+    for _, tt := range tests {
+        got := string(buildLPRControlFile("host", "dfA001host", tt.opts))
+        want := strings.Join(tt.want, "\n") + "\n"
+        if got != want {
+            t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, want)
+        }
+    }
+}
+
+func TestLPDSanitize(t *testing.T) {
+    tests := []struct {
+        in   string
+        max  int
+        want string
+    }{
+        {"plain", 10, "plain"},
+        {"a\tb\nc\x7fd", 10, "abcd"},
+        {"abcdef", 3, "abc"},
+        {"", 5, ""},
+    }
+    for _, tt := range tests {
+        if got := lpdSanitize(tt.in, tt.max); got != tt.want {
+            t.Errorf("lpdSanitize(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+        }
+    }
+}
+
+// fakeLPD принимает одно задание по RFC 1179 и отдаёт принятые команды и файлы
+func fakeLPD(ln net.Listener) <-chan map[string]string {
+    out := make(chan map[string]string, 1)
+    go func() {
+        got := map[string]string{}
+        defer func() { out <- got }()
+        conn, err := ln.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        r := bufio.NewReader(conn)
+
+        line, err := r.ReadString('\n')
+        if err != nil {
+            return
+        }
+        got["queue"] = strings.TrimSuffix(line[1:], "\n")
+        conn.Write([]byte{0})
+
+        for {
+            line, err := r.ReadString('\n')
+            if err != nil {
+                return
+            }
+            fields := strings.Fields(line[1:])
+            size, _ := strconv.Atoi(fields[0])
+            kind := "control"
+            if line[0] == 3 {
+                kind = "data"
+            }
+            got[kind+"-name"] = fields[1]
+            conn.Write([]byte{0})
+
+            body := make([]byte, size+1)
+            if _, err := io.ReadFull(r, body); err != nil || body[size] != 0 {
+                return
+            }
+            got[kind] = string(body[:size])
+            conn.Write([]byte{0})
+        }
+    }()
+    return out
+}
+
+func TestSendToPrinterLPR(t *testing.T) {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Skipf("loopback недоступен: %v", err)
+    }
+    defer ln.Close()
+    received := fakeLPD(ln)
+
+    path := filepath.Join(t.TempDir(), "report.pdf")
+    document := []byte("%PDF-1.4 test document")
+    if err := os.WriteFile(path, document, 0o600); err != nil {
+        t.Fatal(err)
+    }
+
+    port := ln.Addr().(*net.TCPAddr).Port
+    jobNum, err := SendToPrinterLPR(context.Background(), path, "127.0.0.1", port, LPRJobOptions{Queue: "raw", UserName: "u1", Copies: 2})
+    if err != nil {
+        t.Fatalf("SendToPrinterLPR: %v", err)
+    }
+    ln.Close()
+    got := <-received
+
+    if got["queue"] != "raw" {
+        t.Errorf("queue = %q, want raw", got["queue"])
+    }
+    if got["data"] != string(document) {
+        t.Errorf("data file = %q", got["data"])
+    }
+    if !strings.HasPrefix(got["data-name"], fmt.Sprintf("dfA%03d", jobNum)) {
+        t.Errorf("data file name %q does not carry job number %d", got["data-name"], jobNum)
+    }
+    control := got["control"]
+    for _, line := range []string{"Pu1\n", "Nreport.pdf\n", "U" + got["data-name"] + "\n"} {
+        if !strings.Contains(control, line) {
+            t.Errorf("control file %q lacks %q", control, line)
+        }
+    }
+    if n := bytes.Count([]byte(control), []byte("\nl"+got["data-name"])); n != 2 {
+        t.Errorf("control file prints data %d times, want 2", n)
+    }
+}
+
+func TestReadLPDRemoveAck(t *testing.T) {
+    tests := []struct {
+        name    string
+        reply   io.Reader
+        wantErr string
+    }{
+        {"removed", strings.NewReader("\x00"), ""},
+        {"removed, extra bytes ignored", strings.NewReader("\x00junk"), ""},
+        {"closed without a reply", strings.NewReader(""), ""},
+        {"error code", strings.NewReader("\x01"), "код ответа 1"},
+        {"error text", strings.NewReader("lpd: not your job\n"), "not your job"},
+        {"connection reset", iotest.ErrReader(syscall.ECONNRESET), "не подтвердил удаление"},
+    }
+    for _, tt := range tests {
+        err := readLPDRemoveAck(tt.reply)
+        switch {
+        case tt.wantErr == "" && err != nil:
+            t.Errorf("%s: unexpected error %v", tt.name, err)
+        case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+            t.Errorf("%s: err = %v, want it to mention %q", tt.name, err, tt.wantErr)
+        }
+    }
+}
+
+func TestCancelLPRJobSendsSubmitter(t *testing.T) {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Skipf("loopback недоступен: %v", err)
+    }
+    defer ln.Close()
+
+    commands := make(chan string, 1)
+    go func() {
+        conn, err := ln.Accept()
+        if err != nil {
+            commands <- ""
+            return
+        }
+        defer conn.Close()
+        line, _ := bufio.NewReader(conn).ReadString('\n')
+        commands <- line
+        conn.Write([]byte{0})
+    }()
+
+    port := ln.Addr().(*net.TCPAddr).Port
+    if err := CancelLPRJob(context.Background(), "127.0.0.1", port, "raw", "user-42", 7); err != nil {
+        t.Fatalf("CancelLPRJob: %v", err)
+    }
+    if got, want := <-commands, "\x05raw user-42 007\n"; got != want {
+        t.Errorf("remove command = %q, want %q", got, want)
+    }
+}
+
+func TestSendToPrinterLPRAbort(t *testing.T) {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Skipf("loopback недоступен: %v", err)
+    }
+    defer ln.Close()
+
+    // Сервер принимает команды, но перестаёт читать, едва начинается файл данных
+    stalled := make(chan struct{})
+    defer close(stalled)
+    go func() {
+        conn, err := ln.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        r := bufio.NewReader(conn)
+        for i := 0; i < 2; i++ {
+            if _, err := r.ReadString('\n'); err != nil {
+                return
+            }
+            conn.Write([]byte{0})
+        }
+        <-stalled
+    }()
+
+    path := filepath.Join(t.TempDir(), "big.pcl")
+    if err := os.WriteFile(path, bytes.Repeat([]byte("x"), 32<<20), 0o600); err != nil {
+        t.Fatal(err)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error, 1)
+    go func() {
+        port := ln.Addr().(*net.TCPAddr).Port
+        _, err := SendToPrinterLPR(ctx, path, "127.0.0.1", port, LPRJobOptions{Queue: "raw"})
+        done <- err
+    }()
+    time.Sleep(200 * time.Millisecond)
+    cancel()
+
+    select {
+    case err := <-done:
+        if !errors.Is(err, context.Canceled) {
+            t.Errorf("err = %v, want context.Canceled", err)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("canceling the context did not interrupt a stalled LPD transfer")
+    }
+}
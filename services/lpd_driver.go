@@ -0,0 +1,71 @@
+package services
+
+import (
+    "context"
+    "fmt"
+
+    "print-automation/models"
+)
+
+// LPDDriver — печать по LPD/LPR (RFC 1179)
+type LPDDriver struct{}
+
+func (d *LPDDriver) Submit(ctx context.Context, printer models.Printer, ticket JobTicket) (*DriverJobStatus, error) {
+    jobNum, err := SendToPrinterLPR(ctx, ticket.DocumentPath, printer.IPAddress, printer.Port, LPRJobOptions{
+        Queue:    printer.Queue,
+        UserName: ticket.UserName,
+        JobName:  ticket.JobName,
+        FileName: ticket.DocumentName,
+        Copies:   ticket.Copies,
+        Banner:   printer.BannerPage,
+        Options:  lprTicketOptions(ticket),
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &DriverJobStatus{PrinterJobID: jobNum, State: DriverJobPending}, nil
+}
+
+func (d *LPDDriver) Probe(ctx context.Context, printer models.Printer) error {
+    port := printer.Port
+    if port == 0 {
+        port = 515
+    }
+    return CheckPrinterConnection(printer.IPAddress, port)
+}
+
+func (d *LPDDriver) QueryStatus(ctx context.Context, printer models.Printer, printerJobID int) (*DriverJobStatus, error) {
+    // Формат ответа на "send queue state" у серверов разный — не разбираем его
+    return nil, ErrDriverNotSupported
+}
+
+func (d *LPDDriver) Cancel(ctx context.Context, printer models.Printer, printerJobID int, userName string) error {
+    return CancelLPRJob(ctx, printer.IPAddress, printer.Port, printer.Queue, userName, printerJobID)
+}
+
+func (d *LPDDriver) Capabilities(ctx context.Context, printer models.Printer) (*DriverCapabilities, error) {
+    return &DriverCapabilities{Cancel: true}, nil
+}
+
+// lprTicketOptions записывает параметры задания в виде опций CUPS (lp -o).
+// Диапазонов страниц и n-up здесь нет: строку "O" сервер может пропустить,
+// а они меняют стоимость, поэтому для LPD отклоняются заранее (checkPageLayout).
+func lprTicketOptions(ticket JobTicket) []string {
+    var opts []string
+    if ticket.Sides != "" {
+        opts = append(opts, "sides="+ticket.Sides)
+    }
+    if ticket.ColorMode != "" {
+        opts = append(opts, "print-color-mode="+ticket.ColorMode)
+    }
+    if ticket.MediaSize != "" {
+        opts = append(opts, "media="+ticket.MediaSize)
+    }
+    if value, ok := ippOrientations[ticket.Orientation]; ok && value != 3 {
+        opts = append(opts, fmt.Sprintf("orientation-requested=%d", value))
+    }
+    if ticket.Copies > 1 && !ticket.Collate {
+        opts = append(opts, "collate=false")
+    }
+    return opts
+}
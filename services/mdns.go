@@ -0,0 +1,256 @@
+package services
+
+import (
+    "context"
+    "encoding/binary"
+    "errors"
+    "net"
+    "strings"
+    "time"
+)
+
+// Типы записей DNS, нужные для DNS-SD
+const (
+    dnsTypeA    = 1
+    dnsTypePTR  = 12
+    dnsTypeTXT  = 16
+    dnsTypeAAAA = 28
+    dnsTypeSRV  = 33
+    dnsClassIN  = 1
+    // dnsClassQU — бит «ответить unicast» в классе вопроса mDNS
+    dnsClassQU = 0x8000
+)
+
+// mdnsGroup — адрес группы mDNS (IPv4)
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// MDNSService — экземпляр сервиса, найденный через DNS-SD
+type MDNSService struct {
+    // Instance — имя экземпляра ("HP LaserJet 400"), Service — тип ("_ipp._tcp")
+    Instance string
+    Service  string
+    Host     string
+    Port     int
+    IPs      []net.IP
+    TXT      map[string]string
+}
+
+// dnsRecord — запись ресурса из ответа
+type dnsRecord struct {
+    Name  string
+    Type  uint16
+    Data  []byte
+    // msg нужен для разбора сжатых имён внутри данных записи
+    msg    []byte
+    offset int
+}
+
+// BrowseMDNS рассылает запросы PTR для перечисленных типов сервисов
+// (например, "_ipp._tcp") и собирает ответы до истечения ctx.
+// Запрос отправляется не с порта 5353, поэтому ответчики отвечают unicast.
+func BrowseMDNS(ctx context.Context, services []string) ([]MDNSService, error) {
+    conn, err := net.ListenUDP("udp4", nil)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close()
+
+    query := buildMDNSQuery(services)
+    if _, err := conn.WriteTo(query, mdnsGroup); err != nil {
+        return nil, err
+    }
+
+    deadline, ok := ctx.Deadline()
+    if !ok {
+        deadline = time.Now().Add(3 * time.Second)
+    }
+    conn.SetReadDeadline(deadline)
+
+    // Записи из всех ответов сводятся вместе: PTR, SRV, TXT и адреса
+    // одного принтера могут прийти в разных пакетах
+    var (
+        ptrs   = map[string]string{}    // экземпляр → тип сервиса
+        srvs   = map[string]dnsRecord{} // экземпляр → SRV
+        txts   = map[string]dnsRecord{} // экземпляр → TXT
+        addrs  = map[string][]net.IP{}  // хост → адреса
+        source = map[string]net.IP{}    // экземпляр → адрес отправителя ответа
+    )
+    wanted := map[string]string{}
+    for _, s := range services {
+        wanted[strings.ToLower(s+".local")] = s
+    }
+
+    buf := make([]byte, 9000)
+    for {
+        n, from, err := conn.ReadFromUDP(buf)
+        if err != nil {
+            var netErr net.Error
+            if errors.As(err, &netErr) && netErr.Timeout() {
+                break
+            }
+            return nil, err
+        }
+        records, err := parseDNSMessage(buf[:n])
+        if err != nil {
+            continue
+        }
+        for _, r := range records {
+            switch r.Type {
+            case dnsTypePTR:
+                service, ok := wanted[strings.ToLower(r.Name)]
+                if !ok {
+                    continue
+                }
+                instance, _, err := readDNSName(r.msg, r.offset)
+                if err != nil {
+                    continue
+                }
+                ptrs[instance] = service
+                source[instance] = from.IP
+            case dnsTypeSRV:
+                srvs[r.Name] = r
+            case dnsTypeTXT:
+                txts[r.Name] = r
+            case dnsTypeA, dnsTypeAAAA:
+                if len(r.Data) == 4 || len(r.Data) == 16 {
+                    addrs[strings.ToLower(r.Name)] = append(addrs[strings.ToLower(r.Name)], net.IP(append([]byte(nil), r.Data...)))
+                }
+            }
+        }
+    }
+
+    var result []MDNSService
+    for instance, service := range ptrs {
+        svc := MDNSService{
+            Instance: strings.TrimSuffix(instance, "."+service+".local"),
+            Service:  service,
+            TXT:      map[string]string{},
+        }
+        if srv, ok := srvs[instance]; ok && len(srv.Data) >= 7 {
+            svc.Port = int(binary.BigEndian.Uint16(srv.Data[4:6]))
+            if host, _, err := readDNSName(srv.msg, srv.offset+6); err == nil {
+                svc.Host = host
+                svc.IPs = addrs[strings.ToLower(host)]
+            }
+        }
+        if len(svc.IPs) == 0 && source[instance] != nil {
+            svc.IPs = []net.IP{source[instance]}
+        }
+        if txt, ok := txts[instance]; ok {
+            svc.TXT = parseTXT(txt.Data)
+        }
+        result = append(result, svc)
+    }
+    return result, nil
+}
+
+func buildMDNSQuery(services []string) []byte {
+    msg := make([]byte, 12)
+    binary.BigEndian.PutUint16(msg[4:6], uint16(len(services)))
+    for _, s := range services {
+        for _, label := range strings.Split(s+".local", ".") {
+            msg = append(msg, byte(len(label)))
+            msg = append(msg, label...)
+        }
+        msg = append(msg, 0)
+        msg = binary.BigEndian.AppendUint16(msg, dnsTypePTR)
+        msg = binary.BigEndian.AppendUint16(msg, dnsClassIN|dnsClassQU)
+    }
+    return msg
+}
+
+// parseDNSMessage возвращает записи из всех секций ответа
+func parseDNSMessage(msg []byte) ([]dnsRecord, error) {
+    if len(msg) < 12 {
+        return nil, errors.New("DNS: короткое сообщение")
+    }
+    qd := int(binary.BigEndian.Uint16(msg[4:6]))
+    rr := int(binary.BigEndian.Uint16(msg[6:8])) + int(binary.BigEndian.Uint16(msg[8:10])) + int(binary.BigEndian.Uint16(msg[10:12]))
+
+    offset := 12
+    for i := 0; i < qd; i++ {
+        _, next, err := readDNSName(msg, offset)
+        if err != nil {
+            return nil, err
+        }
+        offset = next + 4
+    }
+
+    var records []dnsRecord
+    for i := 0; i < rr; i++ {
+        name, next, err := readDNSName(msg, offset)
+        if err != nil {
+            return records, err
+        }
+        if next+10 > len(msg) {
+            return records, errors.New("DNS: обрезанная запись")
+        }
+        rtype := binary.BigEndian.Uint16(msg[next : next+2])
+        length := int(binary.BigEndian.Uint16(msg[next+8 : next+10]))
+        start := next + 10
+        if start+length > len(msg) {
+            return records, errors.New("DNS: обрезанные данные записи")
+        }
+        records = append(records, dnsRecord{
+            Name:   name,
+            Type:   rtype,
+            Data:   msg[start : start+length],
+            msg:    msg,
+            offset: start,
+        })
+        offset = start + length
+    }
+    return records, nil
+}
+
+// readDNSName читает имя (с учётом сжатия) и возвращает смещение после него
+func readDNSName(msg []byte, offset int) (string, int, error) {
+    var labels []string
+    next := -1
+    for jumps := 0; ; {
+        if offset >= len(msg) {
+            return "", 0, errors.New("DNS: имя выходит за сообщение")
+        }
+        length := int(msg[offset])
+        switch {
+        case length == 0:
+            if next < 0 {
+                next = offset + 1
+            }
+            return strings.Join(labels, "."), next, nil
+        case length&0xC0 == 0xC0:
+            if offset+1 >= len(msg) || jumps > 20 {
+                return "", 0, errors.New("DNS: некорректная ссылка в имени")
+            }
+            if next < 0 {
+                next = offset + 2
+            }
+            offset = int(binary.BigEndian.Uint16(msg[offset:offset+2]) & 0x3FFF)
+            jumps++
+        default:
+            if offset+1+length > len(msg) {
+                return "", 0, errors.New("DNS: метка выходит за сообщение")
+            }
+            labels = append(labels, string(msg[offset+1:offset+1+length]))
+            offset += 1 + length
+        }
+    }
+}
+
+// parseTXT разбирает строки key=value записи TXT
+func parseTXT(data []byte) map[string]string {
+    result := map[string]string{}
+    for len(data) > 0 {
+        n := int(data[0])
+        if 1+n > len(data) {
+            break
+        }
+        entry := string(data[1 : 1+n])
+        data = data[1+n:]
+        key, value, _ := strings.Cut(entry, "=")
+        if key != "" {
+            result[strings.ToLower(key)] = value
+        }
+    }
+    return result
+}
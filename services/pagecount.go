@@ -0,0 +1,528 @@
+package services
+
+import (
+    "bufio"
+    "bytes"
+    "compress/zlib"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// ErrPageCountUnsupported — формат документа не позволяет посчитать страницы
+var ErrPageCountUnsupported = errors.New("подсчёт страниц для этого формата не поддерживается")
+
+// ErrPageCountFailed — документ поддерживаемого формата, но страницы посчитать не удалось
+var ErrPageCountFailed = errors.New("не удалось определить число страниц документа")
+
+// PageCountMismatchError — заявленное клиентом число страниц не совпало с фактическим
+type PageCountMismatchError struct {
+    Declared int
+    Counted  int
+}
+
+func (e *PageCountMismatchError) Error() string {
+    return fmt.Sprintf("заявлено страниц: %d, в документе: %d", e.Declared, e.Counted)
+}
+
+// CountPagesFile считает страницы документа по его MIME-типу
+func CountPagesFile(path, mimeType string) (int, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return 0, err
+    }
+    defer f.Close()
+
+    switch strings.ToLower(mimeType) {
+    case "application/pdf":
+        data, err := io.ReadAll(f)
+        if err != nil {
+            return 0, err
+        }
+        return CountPDFPages(data)
+    case "application/postscript":
+        return CountPostScriptPages(f)
+    case "application/vnd.hp-pcl":
+        return CountPCLPages(bufio.NewReader(f))
+    case "image/jpeg", "image/png", "image/gif":
+        return 1, nil
+    case "text/plain":
+        return countTextPages(f)
+    }
+    return 0, ErrPageCountUnsupported
+}
+
+var (
+    pdfObjRe      = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+    pdfPagesRefRe = regexp.MustCompile(`/Pages\s+(\d+)\s+\d+\s+R`)
+    pdfCountRe    = regexp.MustCompile(`/Count\s+(\d+)`)
+    pdfCatalogRe  = regexp.MustCompile(`/Type\s*/Catalog\b`)
+    pdfPageRe     = regexp.MustCompile(`/Type\s*/Page\b`)
+    pdfObjStmRe   = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+    pdfNRe        = regexp.MustCompile(`/N\s+(\d+)`)
+    pdfFirstRe    = regexp.MustCompile(`/First\s+(\d+)`)
+    pdfLengthRe   = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+)
+
+// CountPDFPages считает страницы PDF двумя способами — по /Count корня дерева
+// страниц (Catalog → /Pages) и по объектам /Type /Page — и берёт большее:
+// поддельный /Count не удешевит печать. Объекты из сжатых потоков объектов
+// (PDF 1.5+) тоже учитываются. Зашифрованный PDF считается, если он открывается
+// без пароля (защищены только права); с паролем на открытие — ErrPDFPasswordRequired.
+func CountPDFPages(data []byte) (int, error) {
+    if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\r\n\t "), []byte("%PDF-")) {
+        return 0, fmt.Errorf("документ не является PDF")
+    }
+
+    objects := pdfObjects(data, nil)
+    crypt, err := pdfEncryptionFor(data, objects)
+    if err != nil {
+        return 0, err
+    }
+    if crypt != nil {
+        // Потоки объектов зашифрованы — разбираем заново с ключом файла
+        objects = pdfObjects(data, crypt)
+    }
+
+    // Последний каталог — актуальный при инкрементальных обновлениях
+    rootCount := 0
+    catalog := pdfObject{order: -1}
+    for _, obj := range objects {
+        if pdfCatalogRe.MatchString(obj.text) && obj.order > catalog.order {
+            catalog = obj
+        }
+    }
+    if catalog.order >= 0 {
+        if m := pdfPagesRefRe.FindStringSubmatch(catalog.text); m != nil {
+            if root, ok := objects[m[1]]; ok {
+                if c := pdfCountRe.FindStringSubmatch(root.text); c != nil {
+                    rootCount, _ = strconv.Atoi(c[1])
+                }
+            }
+        }
+    }
+
+    pages := 0
+    for _, body := range objects {
+        pages += len(pdfPageRe.FindAllString(body.text, -1))
+    }
+    if rootCount > pages {
+        pages = rootCount
+    }
+    if pages == 0 {
+        return 0, fmt.Errorf("в PDF не найдено дерево страниц")
+    }
+    return pages, nil
+}
+
+type pdfObject struct {
+    text  string
+    order int
+}
+
+// pdfObjects собирает словари всех объектов: номер → текст до stream/endobj.
+// Более поздние определения объекта перекрывают ранние. С crypt потоки
+// объектов перед распаковкой расшифровываются.
+func pdfObjects(data []byte, crypt *pdfEncryption) map[string]pdfObject {
+    objects := map[string]pdfObject{}
+    order := 0
+
+    locs := pdfObjRe.FindAllSubmatchIndex(data, -1)
+    for i, loc := range locs {
+        num := string(data[loc[2]:loc[3]])
+        start := loc[1]
+        end := len(data)
+        if i+1 < len(locs) {
+            end = locs[i+1][0]
+        }
+        body := data[start:end]
+        if k := bytes.Index(body, []byte("endobj")); k >= 0 {
+            body = body[:k]
+        }
+
+        dict := body
+        var stream []byte
+        if k := bytes.Index(body, []byte("stream")); k >= 0 {
+            dict = body[:k]
+            stream = pdfStreamData(dict, body[k+len("stream"):])
+        }
+
+        order++
+        objects[num] = pdfObject{text: string(dict), order: order}
+
+        if stream != nil && pdfObjStmRe.Match(dict) && bytes.Contains(dict, []byte("/FlateDecode")) {
+            if crypt != nil {
+                gen, _ := strconv.Atoi(string(data[loc[4]:loc[5]]))
+                id, _ := strconv.Atoi(num)
+                plain, err := crypt.decrypt(id, gen, stream)
+                if err != nil {
+                    continue
+                }
+                stream = plain
+            }
+            for n, text := range pdfObjectStream(dict, stream) {
+                order++
+                objects[n] = pdfObject{text: text, order: order}
+            }
+        }
+    }
+    return objects
+}
+
+// pdfStreamData вырезает данные потока между "stream" и "endstream". Перевод
+// строки перед endstream в данные не входит: для AES важна точная длина, поэтому
+// прямое значение /Length словаря имеет приоритет.
+func pdfStreamData(dict, rest []byte) []byte {
+    if bytes.HasPrefix(rest, []byte("\r\n")) {
+        rest = rest[2:]
+    } else if bytes.HasPrefix(rest, []byte("\n")) || bytes.HasPrefix(rest, []byte("\r")) {
+        rest = rest[1:]
+    }
+    if m := pdfLengthRe.FindSubmatch(dict); m != nil && len(m[2]) == 0 {
+        if n, err := strconv.Atoi(string(m[1])); err == nil && n <= len(rest) {
+            return rest[:n]
+        }
+    }
+    if k := bytes.Index(rest, []byte("endstream")); k >= 0 {
+        rest = rest[:k]
+    }
+    if bytes.HasSuffix(rest, []byte("\r\n")) {
+        return rest[:len(rest)-2]
+    }
+    return bytes.TrimSuffix(bytes.TrimSuffix(rest, []byte("\n")), []byte("\r"))
+}
+
+// pdfObjectStream распаковывает поток объектов (/Type /ObjStm)
+func pdfObjectStream(dict, stream []byte) map[string]string {
+    nMatch := pdfNRe.FindSubmatch(dict)
+    firstMatch := pdfFirstRe.FindSubmatch(dict)
+    if nMatch == nil || firstMatch == nil {
+        return nil
+    }
+    n, _ := strconv.Atoi(string(nMatch[1]))
+    first, _ := strconv.Atoi(string(firstMatch[1]))
+
+    zr, err := zlib.NewReader(bytes.NewReader(stream))
+    if err != nil {
+        return nil
+    }
+    defer zr.Close()
+    // Хвост потока может быть повреждён — берём то, что удалось распаковать
+    plain, _ := io.ReadAll(io.LimitReader(zr, 64<<20))
+    if first > len(plain) {
+        return nil
+    }
+
+    header := strings.Fields(string(plain[:first]))
+    if len(header) < 2*n {
+        return nil
+    }
+    out := make(map[string]string, n)
+    for i := 0; i < n; i++ {
+        off, err := strconv.Atoi(header[2*i+1])
+        if err != nil {
+            return out
+        }
+        start := first + off
+        end := len(plain)
+        if i+1 < n {
+            if next, err := strconv.Atoi(header[2*i+3]); err == nil {
+                end = first + next
+            }
+        }
+        if start < 0 || start > end || end > len(plain) {
+            return out
+        }
+        out[header[2*i]] = string(plain[start:end])
+    }
+    return out
+}
+
+// CountPostScriptPages считает страницы по DSC-комментарию %%Pages, по числу
+// %%Page: и по операторам showpage и берёт большее: заголовок не может
+// уменьшить число страниц. Вложенные документы (%%BeginDocument … %%EndDocument) не учитываются.
+func CountPostScriptPages(r io.Reader) (int, error) {
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+    depth := 0
+    procDepth := 0
+    dscPages := -1
+    pageComments := 0
+    showpages := 0
+
+    for scanner.Scan() {
+        line := scanner.Text()
+        switch {
+        case strings.HasPrefix(line, "%%BeginDocument"):
+            depth++
+            continue
+        case strings.HasPrefix(line, "%%EndDocument"):
+            if depth > 0 {
+                depth--
+            }
+            continue
+        }
+        if depth > 0 {
+            continue
+        }
+
+        if strings.HasPrefix(line, "%%Pages:") {
+            // "(atend)" в заголовке — значение будет в трейлере
+            if fields := strings.Fields(strings.TrimPrefix(line, "%%Pages:")); len(fields) > 0 {
+                if n, err := strconv.Atoi(fields[0]); err == nil {
+                    dscPages = n
+                }
+            }
+            continue
+        }
+        if strings.HasPrefix(line, "%%Page:") {
+            pageComments++
+            continue
+        }
+        if strings.HasPrefix(line, "%") {
+            continue
+        }
+        showpages += countShowpage(line, &procDepth)
+    }
+    if err := scanner.Err(); err != nil {
+        return 0, err
+    }
+
+    pages := dscPages
+    if pageComments > pages {
+        pages = pageComments
+    }
+    if showpages > pages {
+        pages = showpages
+    }
+    if pages <= 0 {
+        return 0, fmt.Errorf("в PostScript не найдено ни одной страницы")
+    }
+    return pages, nil
+}
+
+// countShowpage считает вызовы showpage вне процедур { … }.
+// depth — глубина вложенности фигурных скобок, переносится между строками.
+func countShowpage(line string, depth *int) int {
+    if i := strings.Index(line, "%"); i >= 0 {
+        line = line[:i]
+    }
+    n := 0
+    token := strings.Builder{}
+    flush := func() {
+        if token.String() == "showpage" && *depth == 0 {
+            n++
+        }
+        token.Reset()
+    }
+    for _, r := range line {
+        switch r {
+        case '{':
+            flush()
+            *depth++
+        case '}':
+            flush()
+            if *depth > 0 {
+                *depth--
+            }
+        case ' ', '\t', '[', ']', '(', ')', '/':
+            flush()
+            if r == '/' {
+                // Имя вида /showpage — это не вызов оператора
+                token.WriteRune(r)
+            }
+        default:
+            token.WriteRune(r)
+        }
+    }
+    flush()
+    return n
+}
+
+// CountPCLPages считает страницы PCL 5: каждая страница завершается
+// переводом формата (FF) или сбросом принтера (ESC E) после вывода.
+// Бинарные данные растров, шрифтов и прозрачной печати пропускаются по длине.
+// PCL XL (PCL 6) не поддерживается.
+func CountPCLPages(r *bufio.Reader) (int, error) {
+    pages := 0
+    dirty := false
+
+    for {
+        b, err := r.ReadByte()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return 0, err
+        }
+
+        switch {
+        case b == 0x0C:
+            pages++
+            dirty = false
+        case b == 0x1B:
+            ejected, isXL, err := pclEscape(r, &dirty)
+            if err != nil {
+                return 0, err
+            }
+            if isXL {
+                return 0, ErrPageCountUnsupported
+            }
+            if ejected {
+                pages++
+                dirty = false
+            }
+        case b >= 0x20 || b == '\t':
+            dirty = true
+        }
+    }
+    if dirty {
+        pages++
+    }
+    if pages == 0 {
+        return 0, fmt.Errorf("в PCL не найдено ни одной страницы")
+    }
+    return pages, nil
+}
+
+// pclEscape разбирает escape-последовательность после ESC.
+// ejected — сброс принтера (ESC E) при незавершённой странице.
+func pclEscape(r *bufio.Reader, dirty *bool) (ejected, isXL bool, err error) {
+    c, err := r.ReadByte()
+    if err != nil {
+        return false, false, nilIfEOF(err)
+    }
+
+    switch {
+    case c == 'E':
+        return *dirty, false, nil
+    case c == '%':
+        // ESC %-12345X — UEL, затем PJL-заголовок до ENTER LANGUAGE
+        if peek, _ := r.Peek(7); string(peek) == "-12345X" {
+            r.Discard(7)
+            isXL, err := pclSkipPJL(r)
+            return false, isXL, err
+        }
+        // ESC %#A / ESC %#B — переключение PCL ↔ HP-GL/2, без группового символа
+        for {
+            _, term, err := pclValue(r)
+            if err != nil || (term >= 0x40 && term <= 0x5E) {
+                return false, false, nilIfEOF(err)
+            }
+        }
+    case c < 0x21 || c > 0x2F:
+        // Двухсимвольная последовательность
+        return false, false, nil
+    }
+
+    // Параметризованная последовательность: ESC <parameterized> <group> (<value><param>)+
+    group, err := r.ReadByte()
+    if err != nil {
+        return false, false, nilIfEOF(err)
+    }
+    for {
+        value, term, err := pclValue(r)
+        if err != nil {
+            return false, false, nilIfEOF(err)
+        }
+        upper := term &^ 0x20
+
+        // Команды с блоком бинарных данных длиной value байт
+        if upper == 'W' || (c == '&' && group == 'p' && upper == 'X') {
+            if value > 0 {
+                if _, err := r.Discard(value); err != nil {
+                    return false, false, nilIfEOF(err)
+                }
+            }
+            if (c == '*' && group == 'b') || (c == '&' && group == 'p') {
+                *dirty = true
+            }
+        }
+        if c == '*' && group == 'r' && upper == 'A' {
+            *dirty = true
+        }
+
+        // Заглавная буква завершает последовательность
+        if term >= 0x40 && term <= 0x5E {
+            return false, false, nil
+        }
+    }
+}
+
+// pclValue читает числовое значение и символ параметра, который за ним следует
+func pclValue(r *bufio.Reader) (int, byte, error) {
+    var digits []byte
+    for {
+        b, err := r.ReadByte()
+        if err != nil {
+            return 0, 0, err
+        }
+        if (b >= '0' && b <= '9') || b == '+' || b == '-' || b == '.' {
+            digits = append(digits, b)
+            continue
+        }
+        s := string(digits)
+        if i := strings.Index(s, "."); i >= 0 {
+            s = s[:i]
+        }
+        n, _ := strconv.Atoi(s)
+        return n, b, nil
+    }
+}
+
+// pclSkipPJL пропускает строки @PJL; сообщает, если задание переключается на PCL XL
+func pclSkipPJL(r *bufio.Reader) (bool, error) {
+    for {
+        peek, err := r.Peek(4)
+        if err != nil || string(peek) != "@PJL" {
+            return false, nilIfEOF(err)
+        }
+        line, err := r.ReadString('\n')
+        upper := strings.ToUpper(line)
+        if strings.Contains(upper, "ENTER LANGUAGE") {
+            return strings.Contains(upper, "PCLXL"), nil
+        }
+        if err != nil {
+            return false, nilIfEOF(err)
+        }
+    }
+}
+
+// countTextPages считает страницы простого текста: 66 строк или FF на страницу
+func countTextPages(r io.Reader) (int, error) {
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+    pages, lines := 0, 0
+    for scanner.Scan() {
+        line := scanner.Text()
+        for strings.Contains(line, "\f") {
+            line = line[strings.Index(line, "\f")+1:]
+            pages++
+            lines = 0
+        }
+        lines++
+        if lines == 66 {
+            pages++
+            lines = 0
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return 0, err
+    }
+    if lines > 0 || pages == 0 {
+        pages++
+    }
+    return pages, nil
+}
+
+func nilIfEOF(err error) error {
+    if err == io.EOF {
+        return nil
+    }
+    return err
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+    "bufio"
+    "bytes"
+    "compress/zlib"
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+)
+
+// testPDF собирает минимальный PDF из тел объектов (номера — с 1) и словаря трейлера
+func testPDF(trailer string, objects ...string) []byte {
+    var b bytes.Buffer
+    b.WriteString("%PDF-1.7\n")
+    for i, obj := range objects {
+        fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+    }
+    fmt.Fprintf(&b, "trailer\n%s\n%%%%EOF\n", trailer)
+    return b.Bytes()
+}
+
+// testObjStm упаковывает объекты (номер → тело) в поток объектов и сжимает его
+func testObjStm(nums []int, bodies []string) (dict string, stream []byte) {
+    var header, content strings.Builder
+    for i, body := range bodies {
+        fmt.Fprintf(&header, "%d %d ", nums[i], content.Len())
+        content.WriteString(body)
+        content.WriteString("\n")
+    }
+    var z bytes.Buffer
+    w := zlib.NewWriter(&z)
+    w.Write([]byte(header.String() + content.String()))
+    w.Close()
+    dict = fmt.Sprintf("<< /Type /ObjStm /N %d /First %d /Filter /FlateDecode /Length %d >>", len(bodies), header.Len(), z.Len())
+    return dict, z.Bytes()
+}
+
+func TestCountPDFPages(t *testing.T) {
+    page := "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>"
+    catalog := "<< /Type /Catalog /Pages 2 0 R >>"
+
+    objStmDict, objStm := testObjStm([]int{3, 4}, []string{page, page})
+
+    tests := []struct {
+        name    string
+        data    []byte
+        want    int
+        wantErr bool
+    }{
+        {
+            name: "page tree",
+            data: testPDF("<< /Root 1 0 R >>", catalog, "<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>", page, page),
+            want: 2,
+        },
+        {
+            name: "forged /Count lower than the pages",
+            data: testPDF("<< /Root 1 0 R >>", catalog, "<< /Type /Pages /Kids [3 0 R 4 0 R 5 0 R] /Count 1 >>", page, page, page),
+            want: 3,
+        },
+        {
+            name: "/Count above the page objects found",
+            data: testPDF("<< /Root 1 0 R >>", catalog, "<< /Type /Pages /Kids [3 0 R] /Count 4 >>", page),
+            want: 4,
+        },
+        {
+            name: "no catalog: page objects",
+            data: testPDF("<< >>", page, page, page),
+            want: 3,
+        },
+        {
+            name: "pages inside a compressed object stream",
+            data: testPDF("<< /Root 1 0 R >>", catalog, "<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 1 >>",
+                objStmDict+"\nstream\n"+string(objStm)+"\nendstream"),
+            want: 2,
+        },
+        {
+            name: "incremental update replaces a page",
+            data: append(testPDF("<< /Root 1 0 R >>", catalog, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>", page),
+                []byte("3 0 obj\n<< /Type /Page /Parent 2 0 R /Rotate 90 >>\nendobj\n")...),
+            want: 1,
+        },
+        {name: "not a PDF", data: []byte("hello"), wantErr: true},
+        {name: "no pages", data: testPDF("<< >>", "<< /Producer (x) >>"), wantErr: true},
+    }
+    for _, tt := range tests {
+        got, err := CountPDFPages(tt.data)
+        if tt.wantErr {
+            if err == nil {
+                t.Errorf("%s: got %d pages, want an error", tt.name, got)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("%s: %v", tt.name, err)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("%s: got %d pages, want %d", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestCountPostScriptPages(t *testing.T) {
+    tests := []struct {
+        name    string
+        doc     string
+        want    int
+        wantErr bool
+    }{
+        {
+            name: "DSC",
+            doc:  "%!PS-Adobe-3.0\n%%Pages: 2\n%%Page: 1 1\nshowpage\n%%Page: 2 2\nshowpage\n%%EOF\n",
+            want: 2,
+        },
+        {
+            name: "forged %%Pages lower than the page comments",
+            doc:  "%!PS-Adobe-3.0\n%%Pages: 1\n%%Page: 1 1\n%%Page: 2 2\n%%Page: 3 3\n",
+            want: 3,
+        },
+        {
+            name: "showpage count above the comments",
+            doc:  "%!PS-Adobe-3.0\n%%Pages: 1\n%%Page: 1 1\nshowpage\nshowpage\n",
+            want: 2,
+        },
+        {
+            name: "(atend) with the trailer value",
+            doc:  "%!PS-Adobe-3.0\n%%Pages: (atend)\nshowpage\n%%Trailer\n%%Pages: 1\n",
+            want: 1,
+        },
+        {
+            name: "showpage inside a procedure is not a page",
+            doc:  "%!PS\n/done { showpage } def\n/x /showpage def\ndone done\nshowpage % comment showpage\n",
+            want: 1,
+        },
+        {
+            name: "embedded documents are skipped",
+            doc:  "%!PS-Adobe-3.0\n%%Page: 1 1\n%%BeginDocument: logo.eps\n%%Pages: 9\n%%Page: 1 1\nshowpage\n%%EndDocument\nshowpage\n",
+            want: 1,
+        },
+        {name: "nothing to print", doc: "%!PS\n% empty\n", wantErr: true},
+    }
+    for _, tt := range tests {
+        got, err := CountPostScriptPages(strings.NewReader(tt.doc))
+        if tt.wantErr {
+            if err == nil {
+                t.Errorf("%s: got %d pages, want an error", tt.name, got)
+            }
+            continue
+        }
+        if err != nil || got != tt.want {
+            t.Errorf("%s: got %d, %v; want %d", tt.name, got, err, tt.want)
+        }
+    }
+}
+
+func TestCountPCLPages(t *testing.T) {
+    tests := []struct {
+        name    string
+        doc     string
+        want    int
+        wantErr error
+    }{
+        {name: "form feeds", doc: "\x1bEpage one\fpage two\f\x1bE", want: 2},
+        {name: "unterminated last page", doc: "\x1bEpage one\fpage two", want: 2},
+        {name: "reset ejects a dirty page", doc: "\x1bEtext\x1bE", want: 1},
+        {name: "raster data with FF bytes is skipped", doc: "\x1bE\x1b*r1A\x1b*b4W\f\f\f\f\x1b*rB\f", want: 1},
+        {name: "font download is not printed", doc: "\x1bE\x1b)s3W\f\f\f", wantErr: errors.New("")},
+        {name: "PCL XL", doc: "\x1b%-12345X@PJL ENTER LANGUAGE=PCLXL\n", wantErr: ErrPageCountUnsupported},
+        {name: "empty", doc: "\x1bE", wantErr: errors.New("")},
+    }
+    for _, tt := range tests {
+        got, err := CountPCLPages(bufio.NewReader(strings.NewReader(tt.doc)))
+        switch {
+        case tt.wantErr != nil && err == nil:
+            t.Errorf("%s: got %d pages, want an error", tt.name, got)
+        case tt.wantErr == ErrPageCountUnsupported && !errors.Is(err, ErrPageCountUnsupported):
+            t.Errorf("%s: err = %v, want ErrPageCountUnsupported", tt.name, err)
+        case tt.wantErr == nil && (err != nil || got != tt.want):
+            t.Errorf("%s: got %d, %v; want %d", tt.name, got, err, tt.want)
+        }
+    }
+}
+
+func TestCountTextPages(t *testing.T) {
+    tests := []struct {
+        name string
+        doc  string
+        want int
+    }{
+        {"one line", "hello\n", 1},
+        {"empty", "", 1},
+        {"66 lines fill a page", strings.Repeat("x\n", 66), 1},
+        {"67 lines", strings.Repeat("x\n", 67), 2},
+        {"form feed", "a\fb\n", 2},
+    }
+    for _, tt := range tests {
+        got, err := countTextPages(strings.NewReader(tt.doc))
+        if err != nil || got != tt.want {
+            t.Errorf("%s: got %d, %v; want %d", tt.name, got, err, tt.want)
+        }
+    }
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+var (
+    // ErrPaymentRequired — задание не оплачено и не покрыто бесплатной квотой
+    ErrPaymentRequired = errors.New("задание не оплачено")
+    // ErrPaymentNotRequired — оплата задания не нужна или уже проведена
+    ErrPaymentNotRequired = errors.New("оплата задания не требуется")
+)
+
+// moneyEpsilon — допуск при сравнении сумм, хранящихся с точностью до копейки
+const moneyEpsilon = 0.005
+
+// JobImpressions — число оттисков задания, которым меряется бесплатная квота
+func JobImpressions(job *models.PrintJob) int {
+    pages := SidesPerCopy(job)
+    copies := job.Copies
+    if copies < 1 {
+        copies = 1
+    }
+    return pages * copies
+}
+
+// IsJobPaid сообщает, что печать задания оплачена: стоимость нулевая, задание покрыто
+// бесплатной квотой или кошельком, или есть успешный платёж не меньше стоимости
+func IsJobPaid(job *models.PrintJob) (bool, error) {
+    if job.Cost < moneyEpsilon || job.FreePagesUsed > 0 {
+        return true, nil
+    }
+    if job.WalletCharge > 0 && job.WalletCharge >= job.Cost-moneyEpsilon {
+        return true, nil
+    }
+    var count int64
+    err := config.DB.Model(&models.Payment{}).
+        Where("print_job_id = ? AND status = ? AND amount >= ?", job.ID, models.PaymentStatusSucceeded, job.Cost-moneyEpsilon).
+        Count(&count).Error
+    if err != nil {
+        return false, err
+    }
+    return count > 0, nil
+}
+
+// ReleaseJob ставит задание в очередь печати, если оно оплачено
+// (задание с SecureRelease удерживается до выпуска у принтера).
+// Неоплаченное задание покрывается бесплатной квотой, если её хватает, или
+// списывается с кошелька. Иначе задание переводится в awaiting_payment и
+// возвращается ErrPaymentRequired (ErrInsufficientFunds, если кошелёк не пуст).
+func ReleaseJob(job *models.PrintJob, actor, reason string) error {
+    paid, err := IsJobPaid(job)
+    if err != nil {
+        return err
+    }
+    usedQuota := false
+    if !paid {
+        usedQuota, err = useFreeQuota(job)
+        if err != nil {
+            return err
+        }
+        paid = usedQuota
+    }
+    var walletErr error
+    if !paid {
+        paid, walletErr = chargeWallet(job, actor)
+        if walletErr != nil && !errors.Is(walletErr, ErrInsufficientFunds) {
+            return walletErr
+        }
+    }
+    if !paid {
+        if job.Status == models.JobStatusCreated {
+            if err := TransitionJob(job, models.JobStatusAwaitingPayment, actor, "ожидает оплаты"); err != nil {
+                return err
+            }
+        }
+        if walletErr != nil {
+            return walletErr
+        }
+        return ErrPaymentRequired
+    }
+    if job.SecureRelease {
+        err = holdForRelease(job, actor, reason)
+    } else {
+        err = EnqueuePrintJob(job, actor, reason)
+    }
+    if err != nil {
+        if usedQuota {
+            returnFreeQuota(job)
+        }
+        // Списание с кошелька остаётся за заданием: при отмене оно вернётся как возврат
+        return err
+    }
+    return nil
+}
+
+// useFreeQuota списывает оттиски задания с бесплатной квоты владельца.
+// Квота используется только целиком: частично покрытое задание оплачивается полностью.
+func useFreeQuota(job *models.PrintJob) (bool, error) {
+    impressions := JobImpressions(job)
+    used := false
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        res := tx.Model(&models.User{}).
+            Where("id = ? AND free_pages >= ?", job.UserID, impressions).
+            Update("free_pages", gorm.Expr("free_pages - ?", impressions))
+        if res.Error != nil {
+            return res.Error
+        }
+        if res.RowsAffected == 0 {
+            return nil
+        }
+        job.FreePagesUsed = impressions
+        if err := tx.Model(job).Select("FreePagesUsed", "UpdatedAt").Updates(job).Error; err != nil {
+            job.FreePagesUsed = 0
+            return err
+        }
+        used = true
+        return nil
+    })
+    return used, err
+}
+
+// returnFreeQuota возвращает владельцу всю квоту, списанную за задание
+func returnFreeQuota(job *models.PrintJob) {
+    returnFreeQuotaPart(job, job.FreePagesUsed)
+}
+
+// returnFreeQuotaPart возвращает владельцу n оттисков квоты, списанной за задание
+func returnFreeQuotaPart(job *models.PrintJob, n int) {
+    if n <= 0 || n > job.FreePagesUsed {
+        return
+    }
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        err := tx.Model(&models.User{}).Where("id = ?", job.UserID).
+            Update("free_pages", gorm.Expr("free_pages + ?", n)).Error
+        if err != nil {
+            return err
+        }
+        return tx.Model(job).Update("free_pages_used", job.FreePagesUsed-n).Error
+    })
+    if err != nil {
+        log.Printf("Задание %s: не удалось вернуть квоту %d: %v", job.ID, n, err)
+        return
+    }
+    job.FreePagesUsed -= n
+}
+
+// checkPaymentAllowed отклоняет платёж за бесплатное, уже оплаченное или ушедшее в печать задание
+func checkPaymentAllowed(job *models.PrintJob) error {
+    if job.Status != models.JobStatusCreated && job.Status != models.JobStatusAwaitingPayment {
+        return fmt.Errorf("%w: задание в статусе %s", ErrPaymentNotRequired, job.Status)
+    }
+    paid, err := IsJobPaid(job)
+    if err != nil {
+        return err
+    }
+    if paid {
+        return ErrPaymentNotRequired
+    }
+    return nil
+}
+
+// releaseAfterPayment ставит в очередь задание, оплату которого подтвердил провайдер.
+// Задание без документа остаётся ждать загрузки и отправляется позже вручную.
+func releaseAfterPayment(payment *models.Payment) {
+    var job models.PrintJob
+    if err := config.DB.First(&job, "id = ?", payment.PrintJobID).Error; err != nil {
+        log.Printf("Платёж %s: задание %s не найдено: %v", payment.ID, payment.PrintJobID, err)
+        return
+    }
+    // Оплата пришла после отмены задания — деньги возвращаются сразу
+    if job.Status == models.JobStatusCanceled || job.Status == models.JobStatusFailed {
+        ctx, cancel := context.WithTimeout(context.Background(), refundTimeout)
+        defer cancel()
+        if _, err := IssueRefund(ctx, payment, RefundableAmount(payment), "оплата поступила после завершения задания"); err != nil {
+            log.Printf("Платёж %s: возврат за завершённое задание %s не выполнен: %v", payment.ID, job.ID, err)
+        }
+        return
+    }
+    if job.Status != models.JobStatusCreated && job.Status != models.JobStatusAwaitingPayment {
+        return
+    }
+    if job.DocumentKey == "" && job.FileURL == "" {
+        return
+    }
+
+    err := ReleaseJob(&job, "payment:"+payment.ID, "оплата подтверждена")
+    switch {
+    case errors.Is(err, ErrPaymentRequired):
+        log.Printf("Платёж %s (%.2f) не покрывает стоимость задания %s (%.2f)", payment.ID, payment.Amount, job.ID, job.Cost)
+    case err != nil:
+        log.Printf("Задание %s не поставлено в очередь после оплаты: %v", job.ID, err)
+    }
+}
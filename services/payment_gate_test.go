@@ -0,0 +1,66 @@
+package services
+
+import (
+    "errors"
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestJobImpressions(t *testing.T) {
+    tests := []struct {
+        name string
+        job  models.PrintJob
+        want int
+    }{
+        {"single page", models.PrintJob{Pages: 1, Copies: 1}, 1},
+        {"copies", models.PrintJob{Pages: 5, Copies: 3}, 15},
+        {"no copies counted as one", models.PrintJob{Pages: 5}, 5},
+        {"2-up", models.PrintJob{Pages: 5, Copies: 2, NumberUp: 2}, 6},
+        {"page range", models.PrintJob{Pages: 10, Copies: 1, PageRanges: "1-3"}, 3},
+        {"unknown page count", models.PrintJob{Copies: 2}, 2},
+    }
+    for _, tt := range tests {
+        if got := JobImpressions(&tt.job); got != tt.want {
+            t.Errorf("%s: JobImpressions = %d, want %d", tt.name, got, tt.want)
+        }
+    }
+}
+
+// Случаи, решаемые без платежей в базе
+func TestIsJobPaidWithoutPayments(t *testing.T) {
+    tests := []struct {
+        name string
+        job  models.PrintJob
+    }{
+        {"free job", models.PrintJob{Cost: 0}},
+        {"cost below a cent", models.PrintJob{Cost: 0.004}},
+        {"covered by free quota", models.PrintJob{Cost: 5, FreePagesUsed: 10}},
+        {"charged to wallet", models.PrintJob{Cost: 5, WalletCharge: 5}},
+        {"wallet charge within a cent", models.PrintJob{Cost: 5, WalletCharge: 4.996}},
+    }
+    for _, tt := range tests {
+        paid, err := IsJobPaid(&tt.job)
+        if err != nil || !paid {
+            t.Errorf("%s: IsJobPaid = %v, %v; want paid", tt.name, paid, err)
+        }
+    }
+}
+
+func TestCheckPaymentAllowedStatus(t *testing.T) {
+    for _, status := range []string{
+        models.JobStatusQueued,
+        models.JobStatusPrinting,
+        models.JobStatusCompleted,
+        models.JobStatusCanceled,
+    } {
+        job := models.PrintJob{Status: status, Cost: 5}
+        if err := checkPaymentAllowed(&job); !errors.Is(err, ErrPaymentNotRequired) {
+            t.Errorf("%s: got %v, want ErrPaymentNotRequired", status, err)
+        }
+    }
+    free := models.PrintJob{Status: models.JobStatusAwaitingPayment}
+    if err := checkPaymentAllowed(&free); !errors.Is(err, ErrPaymentNotRequired) {
+        t.Errorf("free job: got %v, want ErrPaymentNotRequired", err)
+    }
+}
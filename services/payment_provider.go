@@ -0,0 +1,179 @@
+package services
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+    "sync"
+
+    "github.com/google/uuid"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+var (
+    // ErrUnknownPaymentProvider — провайдер с таким именем не зарегистрирован
+    ErrUnknownPaymentProvider = errors.New("неизвестный платёжный провайдер")
+    // ErrInvalidWebhookSignature — подпись уведомления не сошлась
+    ErrInvalidWebhookSignature = errors.New("неверная подпись уведомления")
+    // ErrMockPaymentsDisabled — тестовый провайдер вне режима разработки
+    ErrMockPaymentsDisabled = errors.New("тестовый платёжный провайдер доступен только в режиме разработки")
+)
+
+// MockPaymentProviderName — имя тестового провайдера
+const MockPaymentProviderName = "mock"
+
+// PaymentIntent — состояние платежа на стороне провайдера
+type PaymentIntent struct {
+    // TransactionID — идентификатор платежа у провайдера
+    TransactionID string
+    // Status — один из models.PaymentStatus*
+    Status string
+    // PaymentURL — страница оплаты, если провайдер её выдаёт
+    PaymentURL string
+}
+
+// WebhookEvent — проверенное уведомление провайдера о смене статуса платежа
+type WebhookEvent struct {
+    // EventID — идентификатор уведомления; повтор с тем же EventID отклоняется
+    EventID       string
+    TransactionID string
+    Status        string
+    // Amount — списанная сумма, для статуса refunded — сколько всего возвращено (0 — весь платёж)
+    Amount        float64
+    Message       string
+}
+
+// PaymentProvider — платёжный шлюз
+type PaymentProvider interface {
+    // CreateIntent регистрирует платёж у провайдера
+    CreateIntent(ctx context.Context, payment *models.Payment) (*PaymentIntent, error)
+    // Capture списывает ранее авторизованную сумму
+    Capture(ctx context.Context, payment *models.Payment) (*PaymentIntent, error)
+    // Refund возвращает amount по успешному платежу
+    Refund(ctx context.Context, payment *models.Payment, amount float64) error
+    // VerifyWebhook проверяет подпись уведомления и разбирает его
+    VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+}
+
+var (
+    paymentProvidersMu sync.RWMutex
+    paymentProviders   = map[string]PaymentProvider{}
+)
+
+// RegisterPaymentProvider подключает провайдера под именем из URL уведомлений
+func RegisterPaymentProvider(name string, p PaymentProvider) {
+    paymentProvidersMu.Lock()
+    defer paymentProvidersMu.Unlock()
+    paymentProviders[strings.ToLower(name)] = p
+}
+
+// PaymentProviderFor возвращает провайдера по имени
+func PaymentProviderFor(name string) (PaymentProvider, error) {
+    paymentProvidersMu.RLock()
+    defer paymentProvidersMu.RUnlock()
+    if p, ok := paymentProviders[strings.ToLower(name)]; ok {
+        return p, nil
+    }
+    return nil, fmt.Errorf("%w: %q", ErrUnknownPaymentProvider, name)
+}
+
+// clientPaymentProvider возвращает провайдера, выбранного клиентом для нового
+// платежа; тестовый провайдер вне режима разработки не принимается
+func clientPaymentProvider(name string) (PaymentProvider, error) {
+    if strings.EqualFold(name, MockPaymentProviderName) && !config.IsDevMode() {
+        return nil, ErrMockPaymentsDisabled
+    }
+    return PaymentProviderFor(name)
+}
+
+// InitPaymentProviders регистрирует встроенные провайдеры.
+// Тестовый провайдер mock подключается только в режиме разработки (APP_ENV=development)
+// и только при явном PAYMENT_MOCK_ENABLED=true.
+func InitPaymentProviders() {
+    if config.GetEnv("PAYMENT_MOCK_ENABLED", "false") != "true" {
+        return
+    }
+    if !config.IsDevMode() {
+        log.Println("PAYMENT_MOCK_ENABLED=true игнорируется: mock-провайдер доступен только при APP_ENV=development")
+        return
+    }
+    secret := config.GetEnv("PAYMENT_MOCK_SECRET", "")
+    if secret == "" {
+        key := make([]byte, 32)
+        if _, err := rand.Read(key); err != nil {
+            panic(err)
+        }
+        secret = hex.EncodeToString(key)
+        log.Println("PAYMENT_MOCK_SECRET не задан: уведомления mock-провайдера подписываются случайным ключом")
+    }
+    RegisterPaymentProvider(MockPaymentProviderName, &MockPaymentProvider{Secret: []byte(secret)})
+}
+
+// MockPaymentProvider — провайдер для локальной разработки: платежи не проводятся,
+// подтверждение приходит через Capture (только оператор) или подписанное уведомление
+type MockPaymentProvider struct {
+    Secret []byte
+}
+
+// MockSignatureHeader — заголовок с HMAC-SHA256 тела уведомления (hex)
+const MockSignatureHeader = "X-Mock-Signature"
+
+func (p *MockPaymentProvider) CreateIntent(ctx context.Context, payment *models.Payment) (*PaymentIntent, error) {
+    return &PaymentIntent{
+        TransactionID: "mock_" + uuid.New().String(),
+        Status:        models.PaymentStatusPending,
+    }, nil
+}
+
+func (p *MockPaymentProvider) Capture(ctx context.Context, payment *models.Payment) (*PaymentIntent, error) {
+    return &PaymentIntent{
+        TransactionID: payment.TransactionID,
+        Status:        models.PaymentStatusSucceeded,
+    }, nil
+}
+
+func (p *MockPaymentProvider) Refund(ctx context.Context, payment *models.Payment, amount float64) error {
+    if amount <= 0 || amount > payment.Amount {
+        return fmt.Errorf("некорректная сумма возврата %.2f", amount)
+    }
+    return nil
+}
+
+// VerifyWebhook ожидает JSON {"event_id", "transaction_id", "status", "amount", "message"}
+func (p *MockPaymentProvider) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+    signature, err := hex.DecodeString(header.Get(MockSignatureHeader))
+    if err != nil || !hmac.Equal(signature, hmacSHA256(p.Secret, string(body))) {
+        return nil, ErrInvalidWebhookSignature
+    }
+
+    var payload struct {
+        EventID       string  `json:"event_id"`
+        TransactionID string  `json:"transaction_id"`
+        Status        string  `json:"status"`
+        Amount        float64 `json:"amount"`
+        Message       string  `json:"message"`
+    }
+    if err := json.Unmarshal(body, &payload); err != nil {
+        return nil, fmt.Errorf("некорректное уведомление: %w", err)
+    }
+    return &WebhookEvent{
+        EventID:       payload.EventID,
+        TransactionID: payload.TransactionID,
+        Status:        payload.Status,
+        Amount:        payload.Amount,
+        Message:       payload.Message,
+    }, nil
+}
+
+// Sign подписывает тело уведомления — для отправки тестовых уведомлений
+func (p *MockPaymentProvider) Sign(body []byte) string {
+    return hex.EncodeToString(hmacSHA256(p.Secret, string(body)))
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+    "errors"
+    "net/http"
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestMockVerifyWebhook(t *testing.T) {
+    p := &MockPaymentProvider{Secret: []byte("webhook-secret")}
+    body := []byte(`{"event_id":"ev-1","transaction_id":"mock_1","status":"succeeded","amount":12.5}`)
+    other := &MockPaymentProvider{Secret: []byte("other-secret")}
+
+    tests := []struct {
+        name      string
+        body      []byte
+        signature string
+        wantErr   error
+    }{
+        {"valid", body, p.Sign(body), nil},
+        {"no signature", body, "", ErrInvalidWebhookSignature},
+        {"not hex", body, "zz", ErrInvalidWebhookSignature},
+        {"another key", body, other.Sign(body), ErrInvalidWebhookSignature},
+        {"body changed after signing", []byte(`{"event_id":"ev-1","transaction_id":"mock_1","status":"succeeded","amount":1250}`), p.Sign(body), ErrInvalidWebhookSignature},
+    }
+    for _, tt := range tests {
+        header := http.Header{}
+        if tt.signature != "" {
+            header.Set(MockSignatureHeader, tt.signature)
+        }
+        event, err := p.VerifyWebhook(header, tt.body)
+        if tt.wantErr != nil {
+            if !errors.Is(err, tt.wantErr) {
+                t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("%s: %v", tt.name, err)
+            continue
+        }
+        if event.EventID != "ev-1" || event.TransactionID != "mock_1" || event.Status != models.PaymentStatusSucceeded || event.Amount != 12.5 {
+            t.Errorf("%s: unexpected event %+v", tt.name, event)
+        }
+    }
+
+    // Подпись верна, но тело не JSON — ошибка разбора, а не подписи
+    garbage := []byte("not json")
+    if _, err := p.VerifyWebhook(http.Header{MockSignatureHeader: {p.Sign(garbage)}}, garbage); err == nil || errors.Is(err, ErrInvalidWebhookSignature) {
+        t.Errorf("malformed body: err = %v", err)
+    }
+}
+
+func withoutMockProvider(t *testing.T) {
+    t.Cleanup(func() {
+        paymentProvidersMu.Lock()
+        delete(paymentProviders, MockPaymentProviderName)
+        paymentProvidersMu.Unlock()
+    })
+}
+
+func TestClientPaymentProviderMockGating(t *testing.T) {
+    withoutMockProvider(t)
+    RegisterPaymentProvider(MockPaymentProviderName, &MockPaymentProvider{Secret: []byte("k")})
+
+    tests := []struct {
+        env     string
+        name    string
+        wantErr error
+    }{
+        {"development", "mock", nil},
+        {"development", "MOCK", nil},
+        {"production", "mock", ErrMockPaymentsDisabled},
+        {"production", "Mock", ErrMockPaymentsDisabled},
+        {"", "mock", ErrMockPaymentsDisabled},
+        {"development", "unknown", ErrUnknownPaymentProvider},
+    }
+    for _, tt := range tests {
+        t.Setenv("APP_ENV", tt.env)
+        _, err := clientPaymentProvider(tt.name)
+        if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+            t.Errorf("APP_ENV=%q, provider %q: err = %v, want %v", tt.env, tt.name, err, tt.wantErr)
+        }
+    }
+}
+
+func TestInitPaymentProvidersMockGating(t *testing.T) {
+    tests := []struct {
+        env, enabled string
+        registered   bool
+    }{
+        {"development", "true", true},
+        {"development", "false", false},
+        {"production", "true", false},
+        {"", "true", false},
+    }
+    withoutMockProvider(t)
+    for _, tt := range tests {
+        paymentProvidersMu.Lock()
+        delete(paymentProviders, MockPaymentProviderName)
+        paymentProvidersMu.Unlock()
+
+        t.Setenv("APP_ENV", tt.env)
+        t.Setenv("PAYMENT_MOCK_ENABLED", tt.enabled)
+        t.Setenv("PAYMENT_MOCK_SECRET", "")
+        InitPaymentProviders()
+
+        _, err := PaymentProviderFor(MockPaymentProviderName)
+        if got := err == nil; got != tt.registered {
+            t.Errorf("APP_ENV=%q PAYMENT_MOCK_ENABLED=%q: mock registered = %v, want %v", tt.env, tt.enabled, got, tt.registered)
+        }
+    }
+}
+
+func TestCanTransitionPayment(t *testing.T) {
+    tests := []struct {
+        from, to string
+        want     bool
+    }{
+        {models.PaymentStatusCreated, models.PaymentStatusPending, true},
+        {models.PaymentStatusPending, models.PaymentStatusSucceeded, true},
+        {models.PaymentStatusPending, models.PaymentStatusFailed, true},
+        {models.PaymentStatusSucceeded, models.PaymentStatusRefunded, true},
+        {models.PaymentStatusCreated, models.PaymentStatusSucceeded, false},
+        {models.PaymentStatusFailed, models.PaymentStatusSucceeded, false},
+        {models.PaymentStatusRefunded, models.PaymentStatusSucceeded, false},
+        {models.PaymentStatusSucceeded, models.PaymentStatusPending, false},
+    }
+    for _, tt := range tests {
+        if got := CanTransitionPayment(tt.from, tt.to); got != tt.want {
+            t.Errorf("CanTransitionPayment(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "math"
+    "strings"
+
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+var (
+    // ErrIllegalPaymentTransition — переход между статусами платежа запрещён
+    ErrIllegalPaymentTransition = errors.New("недопустимый переход статуса платежа")
+    // ErrPaymentNotFound — уведомление относится к неизвестному платежу
+    ErrPaymentNotFound = errors.New("платёж не найден")
+    // ErrPaymentEventReplayed — уведомление с этим EventID уже обработано
+    ErrPaymentEventReplayed = errors.New("уведомление уже обработано")
+)
+
+// PaymentTransitionError описывает отклонённый переход платежа
+type PaymentTransitionError struct {
+    From string
+    To   string
+}
+
+func (e *PaymentTransitionError) Error() string {
+    return fmt.Sprintf("недопустимый переход статуса платежа: %s → %s", e.From, e.To)
+}
+
+func (e *PaymentTransitionError) Is(target error) bool {
+    return target == ErrIllegalPaymentTransition
+}
+
+// paymentTransitions — жизненный цикл платежа
+var paymentTransitions = map[string][]string{
+    models.PaymentStatusCreated:   {models.PaymentStatusPending, models.PaymentStatusFailed},
+    models.PaymentStatusPending:   {models.PaymentStatusSucceeded, models.PaymentStatusFailed},
+    models.PaymentStatusSucceeded: {models.PaymentStatusRefunded},
+    models.PaymentStatusFailed:    {},
+    models.PaymentStatusRefunded:  {},
+}
+
+// IsPaymentStatus сообщает, что статус входит в жизненный цикл платежа
+func IsPaymentStatus(status string) bool {
+    _, ok := paymentTransitions[status]
+    return ok
+}
+
+// CanTransitionPayment проверяет, разрешён ли переход from → to
+func CanTransitionPayment(from, to string) bool {
+    for _, next := range paymentTransitions[from] {
+        if next == to {
+            return true
+        }
+    }
+    return false
+}
+
+// TransitionPayment переводит платёж в статус to, сохраняя также поля columns.
+// Как и у заданий, обновление условное: параллельный переход вернёт ошибку.
+func TransitionPayment(payment *models.Payment, to string, columns ...string) error {
+    from := payment.Status
+    if !CanTransitionPayment(from, to) {
+        return &PaymentTransitionError{From: from, To: to}
+    }
+
+    payment.Status = to
+    fields := append([]string{"Status", "UpdatedAt"}, columns...)
+    res := config.DB.Model(payment).Where("status = ?", from).Select(fields).Updates(payment)
+    if res.Error != nil {
+        payment.Status = from
+        return res.Error
+    }
+    if res.RowsAffected == 0 {
+        payment.Status = from
+        var current models.Payment
+        if err := config.DB.Select("status").First(&current, "id = ?", payment.ID).Error; err != nil {
+            return err
+        }
+        return &PaymentTransitionError{From: current.Status, To: to}
+    }
+    return nil
+}
+
+// advancePayment доводит платёж до статуса провайдера; created сначала проходит через pending
+func advancePayment(payment *models.Payment, to string, columns ...string) error {
+    if payment.Status == models.PaymentStatusCreated && to != models.PaymentStatusPending && to != models.PaymentStatusFailed {
+        if err := TransitionPayment(payment, models.PaymentStatusPending, columns...); err != nil {
+            return err
+        }
+        columns = nil
+    }
+    return TransitionPayment(payment, to, columns...)
+}
+
+// StartPayment создаёт платёж на стоимость задания и регистрирует его у провайдера
+func StartPayment(ctx context.Context, job *models.PrintJob, providerName, method string) (*models.Payment, *PaymentIntent, error) {
+    if _, err := clientPaymentProvider(providerName); err != nil {
+        return nil, nil, err
+    }
+    if err := checkPaymentAllowed(job); err != nil {
+        return nil, nil, err
+    }
+
+    return startPayment(ctx, &models.Payment{
+        PrintJobID:    job.ID,
+        UserID:        job.UserID,
+        Purpose:       models.PaymentPurposeJob,
+        Amount:        job.Cost,
+        Provider:      providerName,
+        PaymentMethod: method,
+    })
+}
+
+// StartTopUp создаёт платёж на пополнение кошелька пользователя
+func StartTopUp(ctx context.Context, userID string, amount float64, providerName, method string) (*models.Payment, *PaymentIntent, error) {
+    // Тестовый провайдер зачислил бы деньги без оплаты
+    if _, err := clientPaymentProvider(providerName); err != nil {
+        return nil, nil, err
+    }
+    amount = roundMoney(amount)
+    if amount < 0.01 {
+        return nil, nil, errors.New("сумма пополнения должна быть положительной")
+    }
+
+    return startPayment(ctx, &models.Payment{
+        UserID:        userID,
+        Purpose:       models.PaymentPurposeTopUp,
+        Amount:        amount,
+        Provider:      providerName,
+        PaymentMethod: method,
+    })
+}
+
+// startPayment сохраняет платёж и регистрирует его у провайдера
+func startPayment(ctx context.Context, payment *models.Payment) (*models.Payment, *PaymentIntent, error) {
+    provider, err := PaymentProviderFor(payment.Provider)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    payment.Status = models.PaymentStatusCreated
+    if err := config.DB.Create(payment).Error; err != nil {
+        return nil, nil, err
+    }
+
+    intent, err := provider.CreateIntent(ctx, payment)
+    if err != nil {
+        if terr := TransitionPayment(payment, models.PaymentStatusFailed); terr != nil {
+            log.Printf("Платёж %s: не удалось отметить ошибку: %v", payment.ID, terr)
+        }
+        return payment, nil, fmt.Errorf("провайдер %s отклонил платёж: %w", payment.Provider, err)
+    }
+
+    payment.TransactionID = intent.TransactionID
+    if err := TransitionPayment(payment, models.PaymentStatusPending, "TransactionID"); err != nil {
+        return payment, intent, err
+    }
+    // Провайдер мог провести платёж сразу
+    if intent.Status == models.PaymentStatusSucceeded || intent.Status == models.PaymentStatusFailed {
+        if err := applyPaymentStatus(payment, intent.Status, 0); err != nil {
+            return payment, intent, err
+        }
+    }
+    return payment, intent, nil
+}
+
+// CapturePayment запрашивает у провайдера списание авторизованной суммы (оператор).
+// Успех реального платежа подтверждает только уведомление провайдера; сразу
+// применяется лишь отказ. Тестовый провайдер уведомлений не шлёт, его результат
+// применяется сразу.
+func CapturePayment(ctx context.Context, payment *models.Payment) error {
+    if payment.Status != models.PaymentStatusPending {
+        return &PaymentTransitionError{From: payment.Status, To: models.PaymentStatusSucceeded}
+    }
+    provider, err := PaymentProviderFor(payment.Provider)
+    if err != nil {
+        return err
+    }
+    intent, err := provider.Capture(ctx, payment)
+    if err != nil {
+        return fmt.Errorf("провайдер %s не провёл списание: %w", payment.Provider, err)
+    }
+    if strings.EqualFold(payment.Provider, MockPaymentProviderName) || intent.Status == models.PaymentStatusFailed {
+        return applyPaymentStatus(payment, intent.Status, 0)
+    }
+    return nil
+}
+
+// RefundPayment возвращает остаток суммы успешного платежа
+func RefundPayment(ctx context.Context, payment *models.Payment) error {
+    if !CanTransitionPayment(payment.Status, models.PaymentStatusRefunded) {
+        return &PaymentTransitionError{From: payment.Status, To: models.PaymentStatusRefunded}
+    }
+    _, err := IssueRefund(ctx, payment, RefundableAmount(payment), "возврат оператором")
+    return err
+}
+
+// ApplyPaymentEvent применяет проверенное уведомление провайдера к платежу.
+// Уведомление с уже обработанным EventID отклоняется (ErrPaymentEventReplayed),
+// возврат у провайдера проводится через учёт возвратов (recordProviderRefund).
+func ApplyPaymentEvent(providerName string, event *WebhookEvent) (*models.Payment, error) {
+    if event.EventID == "" || event.TransactionID == "" || !IsPaymentStatus(event.Status) {
+        return nil, fmt.Errorf("уведомление без идентификатора, платежа или с неизвестным статусом %q", event.Status)
+    }
+
+    var payment models.Payment
+    err := config.DB.Where("provider = ? AND transaction_id = ?", providerName, event.TransactionID).First(&payment).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrPaymentNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    // Отметка ставится до применения: параллельный повтор того же уведомления не пройдёт
+    record := models.PaymentEvent{Provider: providerName, EventID: event.EventID, PaymentID: payment.ID, Status: event.Status}
+    res := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+    if res.Error != nil {
+        return &payment, res.Error
+    }
+    if res.RowsAffected == 0 {
+        return &payment, ErrPaymentEventReplayed
+    }
+
+    if event.Status == models.PaymentStatusRefunded {
+        err = recordProviderRefund(&payment, event.Amount)
+    } else {
+        err = applyPaymentStatus(&payment, event.Status, event.Amount)
+    }
+    if err != nil && !errors.Is(err, ErrIllegalPaymentTransition) {
+        // Уведомление не применено: снимаем отметку, чтобы повтор провайдера прошёл
+        if derr := config.DB.Delete(&record).Error; derr != nil {
+            log.Printf("Уведомление %s/%s: не удалось снять отметку обработки: %v", providerName, event.EventID, derr)
+        }
+    }
+    return &payment, err
+}
+
+// SetPaymentStatus меняет статус платежа вручную (кроме возврата — см. RefundPayment)
+func SetPaymentStatus(payment *models.Payment, status string) error {
+    if status == models.PaymentStatusRefunded && status != payment.Status {
+        return &PaymentTransitionError{From: payment.Status, To: status}
+    }
+    return applyPaymentStatus(payment, status, 0)
+}
+
+// applyPaymentStatus переводит платёж в статус провайдера; для успешного платежа
+// сохраняется фактически списанная сумма, если провайдер её сообщил.
+// Подтверждённая оплата сразу ставит задание в очередь или зачисляет пополнение.
+func applyPaymentStatus(payment *models.Payment, status string, paidAmount float64) error {
+    if status == payment.Status {
+        // Повтор уведомления досылает пополнение, если прошлое зачисление не удалось
+        if status == models.PaymentStatusSucceeded && payment.Purpose == models.PaymentPurposeTopUp {
+            return creditTopUp(payment)
+        }
+        return nil
+    }
+    var columns []string
+    if status == models.PaymentStatusSucceeded && paidAmount > 0 && math.Abs(paidAmount-payment.Amount) >= 0.005 {
+        log.Printf("Платёж %s: списано %.2f вместо %.2f", payment.ID, paidAmount, payment.Amount)
+        payment.Amount = roundMoney(paidAmount)
+        columns = append(columns, "Amount")
+    }
+    if err := advancePayment(payment, status, columns...); err != nil {
+        return err
+    }
+    if status == models.PaymentStatusSucceeded {
+        if payment.Purpose == models.PaymentPurposeTopUp {
+            if err := creditTopUp(payment); err != nil {
+                return fmt.Errorf("пополнение по платежу %s не зачислено: %w", payment.ID, err)
+            }
+        } else {
+            releaseAfterPayment(payment)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,463 @@
+package services
+
+import (
+    "bytes"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/md5"
+    "crypto/rc4"
+    "crypto/sha256"
+    "crypto/sha512"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "hash"
+    "regexp"
+    "strconv"
+)
+
+// ErrPDFPasswordRequired — PDF открывается только с паролем пользователя
+var ErrPDFPasswordRequired = errors.New("PDF защищён паролем на открытие")
+
+var (
+    pdfEncryptRefRe  = regexp.MustCompile(`/Encrypt\s+(\d+)\s+\d+\s+R`)
+    pdfEncryptDictRe = regexp.MustCompile(`/Encrypt\s*<<`)
+    pdfIDRe          = regexp.MustCompile(`/ID\s*\[\s*`)
+    pdfAESV2Re       = regexp.MustCompile(`/CFM\s*/AESV2\b`)
+    pdfNoMetaRe      = regexp.MustCompile(`/EncryptMetadata\s+false\b`)
+)
+
+// pdfPasswordPadding — строка дополнения пароля стандартного обработчика (PDF 1.7, 7.6.3.3)
+var pdfPasswordPadding = []byte{
+    0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41, 0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+    0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80, 0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// pdfEncryption — стандартный обработчик защиты PDF, открытый пустым паролем
+// пользователя (защищён только пароль владельца — правами на печать и правку)
+type pdfEncryption struct {
+    revision int
+    // aes — потоки шифруются AES (AESV2/AESV3), иначе RC4
+    aes bool
+    // key — ключ файла
+    key []byte
+}
+
+// pdfEncryptionFor находит словарь /Encrypt и проверяет, что документ открывается
+// без пароля. Для незашифрованного PDF возвращает nil, nil.
+func pdfEncryptionFor(data []byte, objects map[string]pdfObject) (*pdfEncryption, error) {
+    var dict []byte
+    if m := lastSubmatch(pdfEncryptRefRe, data); m != nil {
+        obj, ok := objects[string(m[1])]
+        if !ok {
+            return nil, fmt.Errorf("зашифрованный PDF: словарь /Encrypt не найден")
+        }
+        dict = []byte(obj.text)
+    } else if loc := pdfEncryptDictRe.FindAllIndex(data, -1); loc != nil {
+        start := loc[len(loc)-1][1] - 2
+        dict = data[start : start+pdfDictEnd(data[start:])]
+    } else {
+        return nil, nil
+    }
+
+    entries := pdfDictEntries(dict)
+    if string(entries["Filter"]) != "/Standard" {
+        return nil, fmt.Errorf("зашифрованный PDF: обработчик защиты %s не поддерживается", entries["Filter"])
+    }
+    v, _ := strconv.Atoi(string(entries["V"]))
+    r, _ := strconv.Atoi(string(entries["R"]))
+    o, u := entries["O"], entries["U"]
+    if len(o) < 32 || len(u) < 32 {
+        return nil, fmt.Errorf("зашифрованный PDF: повреждён словарь /Encrypt")
+    }
+
+    enc := &pdfEncryption{revision: r, aes: v == 5 || (v == 4 && pdfAESV2Re.Match(dict))}
+    switch {
+    case r >= 2 && r <= 4:
+        p, _ := strconv.ParseInt(string(entries["P"]), 10, 64)
+        length := 5
+        if v >= 4 {
+            length = 16
+        } else if n, err := strconv.Atoi(string(entries["Length"])); err == nil && r >= 3 && n >= 40 && n <= 128 {
+            length = n / 8
+        }
+        id := pdfFirstID(data)
+        enc.key = pdfLegacyKey(nil, o, uint32(int32(p)), id, r, length, !pdfNoMetaRe.Match(dict))
+        if !bytes.Equal(pdfLegacyUserCheck(enc.key, id, r), pdfCheckPrefix(u, r)) {
+            return nil, ErrPDFPasswordRequired
+        }
+    case r == 5 || r == 6:
+        ue := entries["UE"]
+        if len(ue) < 32 || len(u) < 48 {
+            return nil, fmt.Errorf("зашифрованный PDF: повреждён словарь /Encrypt")
+        }
+        if !bytes.Equal(pdfHashV5(r, nil, u[32:40]), u[:32]) {
+            return nil, ErrPDFPasswordRequired
+        }
+        intermediate := pdfHashV5(r, nil, u[40:48])
+        block, err := aes.NewCipher(intermediate)
+        if err != nil {
+            return nil, err
+        }
+        enc.key = make([]byte, 32)
+        cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(enc.key, ue[:32])
+    default:
+        return nil, fmt.Errorf("зашифрованный PDF: ревизия защиты %d не поддерживается", r)
+    }
+    return enc, nil
+}
+
+// decrypt расшифровывает строку или поток объекта num gen
+func (e *pdfEncryption) decrypt(num, gen int, data []byte) ([]byte, error) {
+    key := e.key
+    if e.revision < 5 {
+        h := md5.New()
+        h.Write(e.key)
+        h.Write([]byte{byte(num), byte(num >> 8), byte(num >> 16), byte(gen), byte(gen >> 8)})
+        if e.aes {
+            h.Write([]byte("sAlT"))
+        }
+        key = h.Sum(nil)
+        if n := len(e.key) + 5; n < len(key) {
+            key = key[:n]
+        }
+    }
+
+    if !e.aes {
+        c, err := rc4.NewCipher(key)
+        if err != nil {
+            return nil, err
+        }
+        out := make([]byte, len(data))
+        c.XORKeyStream(out, data)
+        return out, nil
+    }
+
+    if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+        return nil, fmt.Errorf("повреждённые данные AES")
+    }
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    out := make([]byte, len(data)-aes.BlockSize)
+    cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(out, data[aes.BlockSize:])
+    if pad := int(out[len(out)-1]); pad >= 1 && pad <= aes.BlockSize && pad <= len(out) {
+        out = out[:len(out)-pad]
+    }
+    return out, nil
+}
+
+// pdfLegacyKey — ключ файла для ревизий 2–4 (алгоритм 2)
+func pdfLegacyKey(password, o []byte, p uint32, id []byte, r, length int, encryptMetadata bool) []byte {
+    h := md5.New()
+    h.Write(pdfPadPassword(password))
+    h.Write(o[:32])
+    var pb [4]byte
+    binary.LittleEndian.PutUint32(pb[:], p)
+    h.Write(pb[:])
+    h.Write(id)
+    if r >= 4 && !encryptMetadata {
+        h.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+    }
+    key := h.Sum(nil)
+    if r >= 3 {
+        for i := 0; i < 50; i++ {
+            sum := md5.Sum(key[:length])
+            key = sum[:]
+        }
+    }
+    return key[:length]
+}
+
+// pdfLegacyUserCheck вычисляет значение /U для ключа (алгоритмы 4 и 5)
+func pdfLegacyUserCheck(key, id []byte, r int) []byte {
+    if r == 2 {
+        c, _ := rc4.NewCipher(key)
+        out := make([]byte, 32)
+        c.XORKeyStream(out, pdfPasswordPadding)
+        return out
+    }
+    h := md5.New()
+    h.Write(pdfPasswordPadding)
+    h.Write(id)
+    out := h.Sum(nil)
+    tmp := make([]byte, len(key))
+    for i := 0; i < 20; i++ {
+        for j := range key {
+            tmp[j] = key[j] ^ byte(i)
+        }
+        c, _ := rc4.NewCipher(tmp)
+        c.XORKeyStream(out, out)
+    }
+    return out
+}
+
+// pdfCheckPrefix — сравниваемая часть /U: ревизии 3 и 4 проверяют первые 16 байт
+func pdfCheckPrefix(u []byte, r int) []byte {
+    if r == 2 {
+        return u[:32]
+    }
+    return u[:16]
+}
+
+func pdfPadPassword(password []byte) []byte {
+    out := make([]byte, 0, 32)
+    out = append(out, password...)
+    if len(out) > 32 {
+        out = out[:32]
+    }
+    return append(out, pdfPasswordPadding[:32-len(out)]...)
+}
+
+// pdfHashV5 — хэш пароля ревизий 5 (SHA-256) и 6 (алгоритм 2.B) без данных /U
+func pdfHashV5(r int, password, salt []byte) []byte {
+    first := sha256.Sum256(append(append([]byte{}, password...), salt...))
+    k := first[:]
+    if r == 5 {
+        return k
+    }
+
+    // Не меньше 64 раундов; дальше — пока последний байт E больше номера раунда − 32
+    var e []byte
+    for i := 0; i < 64 || int(e[len(e)-1]) > i-32; i++ {
+        round := append(append([]byte{}, password...), k...)
+        k1 := bytes.Repeat(round, 64)
+        block, _ := aes.NewCipher(k[:16])
+        e = make([]byte, len(k1))
+        cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+
+        sum := 0
+        for _, b := range e[:16] {
+            sum += int(b)
+        }
+        var h hash.Hash
+        switch sum % 3 {
+        case 0:
+            h = sha256.New()
+        case 1:
+            h = sha512.New384()
+        default:
+            h = sha512.New()
+        }
+        h.Write(e)
+        k = h.Sum(nil)
+    }
+    return k[:32]
+}
+
+// lastSubmatch — последнее совпадение (актуальное при инкрементальных обновлениях)
+func lastSubmatch(re *regexp.Regexp, data []byte) [][]byte {
+    all := re.FindAllSubmatch(data, -1)
+    if len(all) == 0 {
+        return nil
+    }
+    return all[len(all)-1]
+}
+
+// pdfFirstID — первый элемент последнего массива /ID трейлера
+func pdfFirstID(data []byte) []byte {
+    locs := pdfIDRe.FindAllIndex(data, -1)
+    if len(locs) == 0 {
+        return nil
+    }
+    id, _ := pdfParseString(data[locs[len(locs)-1][1]:])
+    return id
+}
+
+// pdfDictEnd возвращает длину словаря "<< … >>" в начале data (с учётом вложенных
+// словарей и строк); если словарь не закрыт — len(data)
+func pdfDictEnd(data []byte) int {
+    depth := 0
+    for i := 0; i < len(data); {
+        switch {
+        case data[i] == '(' || (data[i] == '<' && (i+1 >= len(data) || data[i+1] != '<')):
+            _, n := pdfParseString(data[i:])
+            if n == 0 {
+                return len(data)
+            }
+            i += n
+            continue
+        case bytes.HasPrefix(data[i:], []byte("<<")):
+            depth++
+            i += 2
+            continue
+        case bytes.HasPrefix(data[i:], []byte(">>")):
+            depth--
+            i += 2
+            if depth == 0 {
+                return i
+            }
+            continue
+        }
+        i++
+    }
+    return len(data)
+}
+
+// pdfDictEntries разбирает верхний уровень словаря: строки декодируются,
+// прочие значения (числа, имена, ссылки) возвращаются как текст
+func pdfDictEntries(dict []byte) map[string][]byte {
+    entries := map[string][]byte{}
+    start := bytes.Index(dict, []byte("<<"))
+    if start < 0 {
+        return entries
+    }
+    data := dict[start+2 : start+pdfDictEnd(dict[start:])]
+    if bytes.HasSuffix(data, []byte(">>")) {
+        data = data[:len(data)-2]
+    }
+
+    i := 0
+    skipSpace := func() {
+        for i < len(data) && isPDFSpace(data[i]) {
+            i++
+        }
+    }
+    for {
+        skipSpace()
+        if i >= len(data) || data[i] != '/' {
+            return entries
+        }
+        j := i + 1
+        for j < len(data) && !isPDFSpace(data[j]) && !isPDFDelimiter(data[j]) {
+            j++
+        }
+        name := string(data[i+1 : j])
+        i = j
+        skipSpace()
+        if i >= len(data) {
+            return entries
+        }
+
+        switch {
+        case bytes.HasPrefix(data[i:], []byte("<<")):
+            n := pdfDictEnd(data[i:])
+            entries[name] = data[i : i+n]
+            i += n
+        case data[i] == '(' || data[i] == '<':
+            value, n := pdfParseString(data[i:])
+            if n == 0 {
+                return entries
+            }
+            entries[name] = value
+            i += n
+        case data[i] == '[':
+            k := bytes.IndexByte(data[i:], ']')
+            if k < 0 {
+                return entries
+            }
+            entries[name] = data[i : i+k+1]
+            i += k + 1
+        default:
+            // Число, имя или косвенная ссылка "n g R" — до следующего ключа
+            j := i + 1
+            for j < len(data) && data[j] != '/' && data[j] != '<' && data[j] != '(' && data[j] != '[' {
+                j++
+            }
+            if data[i] == '/' {
+                j = i + 1
+                for j < len(data) && !isPDFSpace(data[j]) && !isPDFDelimiter(data[j]) {
+                    j++
+                }
+            }
+            entries[name] = bytes.TrimSpace(data[i:j])
+            i = j
+        }
+    }
+}
+
+// pdfParseString декодирует строку PDF — литеральную "( … )" или шестнадцатеричную
+// "< … >" — в начале data; n — сколько байт занято (0 — строки нет)
+func pdfParseString(data []byte) (value []byte, n int) {
+    if len(data) == 0 {
+        return nil, 0
+    }
+    if data[0] == '<' {
+        end := bytes.IndexByte(data, '>')
+        if end < 0 {
+            return nil, 0
+        }
+        digits := make([]byte, 0, end)
+        for _, b := range data[1:end] {
+            if !isPDFSpace(b) {
+                digits = append(digits, b)
+            }
+        }
+        if len(digits)%2 == 1 {
+            digits = append(digits, '0')
+        }
+        out, err := hex.DecodeString(string(digits))
+        if err != nil {
+            return nil, 0
+        }
+        return out, end + 1
+    }
+    if data[0] != '(' {
+        return nil, 0
+    }
+
+    var out []byte
+    depth := 1
+    for i := 1; i < len(data); i++ {
+        c := data[i]
+        switch c {
+        case '\\':
+            i++
+            if i >= len(data) {
+                return nil, 0
+            }
+            switch e := data[i]; e {
+            case 'n':
+                out = append(out, '\n')
+            case 'r':
+                out = append(out, '\r')
+            case 't':
+                out = append(out, '\t')
+            case 'b':
+                out = append(out, '\b')
+            case 'f':
+                out = append(out, '\f')
+            case '\r':
+                // Перенос строки после "\\" не входит в строку
+                if i+1 < len(data) && data[i+1] == '\n' {
+                    i++
+                }
+            case '\n':
+            default:
+                if e >= '0' && e <= '7' {
+                    v := 0
+                    k := 0
+                    for ; k < 3 && i+k < len(data) && data[i+k] >= '0' && data[i+k] <= '7'; k++ {
+                        v = v*8 + int(data[i+k]-'0')
+                    }
+                    i += k - 1
+                    out = append(out, byte(v))
+                } else {
+                    out = append(out, e)
+                }
+            }
+        case '(':
+            depth++
+            out = append(out, c)
+        case ')':
+            depth--
+            if depth == 0 {
+                return out, i + 1
+            }
+            out = append(out, c)
+        default:
+            out = append(out, c)
+        }
+    }
+    return nil, 0
+}
+
+func isPDFSpace(b byte) bool {
+    return b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '\f' || b == 0
+}
+
+func isPDFDelimiter(b byte) bool {
+    return bytes.IndexByte([]byte("()<>[]{}/%"), b) >= 0
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+    "bytes"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/md5"
+    "crypto/rc4"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+)
+
+// Стандартный обработчик защиты PDF 1.7 (7.6.3) — независимая сборка для тестов
+
+func testRC4(key, data []byte) []byte {
+    c, _ := rc4.NewCipher(key)
+    out := make([]byte, len(data))
+    c.XORKeyStream(out, data)
+    return out
+}
+
+// testRC4Rounds — RC4 ключом и 19 его вариантами key XOR i (алгоритмы 3 и 5, ревизия 3+)
+func testRC4Rounds(key, data []byte) []byte {
+    out := testRC4(key, data)
+    for i := 1; i <= 19; i++ {
+        k := make([]byte, len(key))
+        for j := range key {
+            k[j] = key[j] ^ byte(i)
+        }
+        out = testRC4(k, out)
+    }
+    return out
+}
+
+type testLegacyEncryption struct {
+    v, r      int
+    length    int // байт
+    aes       bool
+    ownerPass string
+    userPass  string
+}
+
+// build возвращает словарь /Encrypt и ключ файла
+func (e testLegacyEncryption) build(id []byte) (dict string, key []byte) {
+    const p = -3904 // печать разрешена, правка запрещена
+
+    // Алгоритм 3: /O
+    sum := md5.Sum(pdfPadPassword([]byte(e.ownerPass)))
+    ownerKey := sum[:]
+    var o []byte
+    if e.r == 2 {
+        o = testRC4(ownerKey[:e.length], pdfPadPassword([]byte(e.userPass)))
+    } else {
+        for i := 0; i < 50; i++ {
+            s := md5.Sum(ownerKey[:e.length])
+            ownerKey = s[:]
+        }
+        o = testRC4Rounds(ownerKey[:e.length], pdfPadPassword([]byte(e.userPass)))
+    }
+
+    // Алгоритм 2: ключ файла по паролю пользователя
+    h := md5.New()
+    h.Write(pdfPadPassword([]byte(e.userPass)))
+    h.Write(o)
+    binary.Write(h, binary.LittleEndian, int32(p))
+    h.Write(id)
+    key = h.Sum(nil)
+    if e.r >= 3 {
+        for i := 0; i < 50; i++ {
+            s := md5.Sum(key[:e.length])
+            key = s[:]
+        }
+    }
+    key = key[:e.length]
+
+    // Алгоритм 4 (ревизия 2) и 5: /U
+    var u []byte
+    if e.r == 2 {
+        u = testRC4(key, pdfPasswordPadding)
+    } else {
+        h = md5.New()
+        h.Write(pdfPasswordPadding)
+        h.Write(id)
+        u = append(testRC4Rounds(key, h.Sum(nil)), make([]byte, 16)...)
+    }
+
+    filter := ""
+    if e.v == 4 {
+        cfm := "/V2"
+        if e.aes {
+            cfm = "/AESV2"
+        }
+        filter = fmt.Sprintf(" /CF << /StdCF << /CFM %s /Length 16 /AuthEvent /DocOpen >> >> /StmF /StdCF /StrF /StdCF", cfm)
+    }
+    dict = fmt.Sprintf("<< /Filter /Standard /V %d /R %d /Length %d /P %d /O <%x> /U <%x>%s >>",
+        e.v, e.r, e.length*8, p, o, u, filter)
+    return dict, key
+}
+
+// testObjectKey — ключ объекта (алгоритм 1)
+func testObjectKey(fileKey []byte, num, gen int, aes bool) []byte {
+    h := md5.New()
+    h.Write(fileKey)
+    h.Write([]byte{byte(num), byte(num >> 8), byte(num >> 16), byte(gen), byte(gen >> 8)})
+    if aes {
+        h.Write([]byte("sAlT"))
+    }
+    key := h.Sum(nil)
+    if n := len(fileKey) + 5; n < 16 {
+        key = key[:n]
+    }
+    return key
+}
+
+func testAESEncrypt(key, data []byte) []byte {
+    pad := aes.BlockSize - len(data)%aes.BlockSize
+    plain := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+    iv := bytes.Repeat([]byte{7}, aes.BlockSize)
+    block, _ := aes.NewCipher(key)
+    out := make([]byte, len(plain))
+    cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, plain)
+    return append(iv, out...)
+}
+
+// testEncryptedPDF — каталог и дерево страниц в открытом виде, сами страницы —
+// в зашифрованном потоке объектов 5 (как у PDF 1.5+ с защитой). При indirectLength
+// длина потока задана ссылкой, и его конец определяется по endstream.
+func testEncryptedPDF(encryptDict string, id []byte, indirectLength bool, encrypt func(num int, data []byte) []byte) []byte {
+    page := "<< /Type /Page /Parent 2 0 R >>"
+    dict, stream := testObjStm([]int{3, 4}, []string{page, page})
+    plainLength := fmt.Sprintf("/Length %d", len(stream))
+    stream = encrypt(5, stream)
+    length := fmt.Sprintf("/Length %d", len(stream))
+    if indirectLength {
+        length = "/Length 4 0 R"
+    }
+    dict = strings.Replace(dict, plainLength, length, 1)
+    return testPDF(fmt.Sprintf("<< /Root 1 0 R /Encrypt 6 0 R /ID [<%x> <%x>] >>", id, id),
+        "<< /Type /Catalog /Pages 2 0 R >>",
+        "<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 1 >>",
+        "<< /Producer (test) >>",
+        "<< /Producer (test) >>",
+        dict+"\nstream\n"+string(stream)+"\nendstream",
+        encryptDict,
+    )
+}
+
+func TestCountPDFPagesEncrypted(t *testing.T) {
+    id, _ := hex.DecodeString("6b1e2c1a0f4e4d7f9b3c8a5e2d1f0a77")
+
+    tests := []struct {
+        name    string
+        enc     testLegacyEncryption
+        wantErr error
+    }{
+        {"RC4 40-bit, owner password only", testLegacyEncryption{v: 1, r: 2, length: 5, ownerPass: "owner"}, nil},
+        {"RC4 128-bit, owner password only", testLegacyEncryption{v: 2, r: 3, length: 16, ownerPass: "owner"}, nil},
+        {"AES-128, owner password only", testLegacyEncryption{v: 4, r: 4, length: 16, aes: true, ownerPass: "owner"}, nil},
+        {"RC4 128-bit, user password", testLegacyEncryption{v: 2, r: 3, length: 16, ownerPass: "owner", userPass: "secret"}, ErrPDFPasswordRequired},
+        {"AES-128, user password", testLegacyEncryption{v: 4, r: 4, length: 16, aes: true, ownerPass: "owner", userPass: "secret"}, ErrPDFPasswordRequired},
+    }
+    for _, tt := range tests {
+        dict, key := tt.enc.build(id)
+        data := testEncryptedPDF(dict, id, false, func(num int, plain []byte) []byte {
+            objKey := testObjectKey(key, num, 0, tt.enc.aes)
+            if tt.enc.aes {
+                return testAESEncrypt(objKey, plain)
+            }
+            return testRC4(objKey, plain)
+        })
+
+        got, err := CountPDFPages(data)
+        if tt.wantErr != nil {
+            if !errors.Is(err, tt.wantErr) {
+                t.Errorf("%s: got %d, %v; want %v", tt.name, got, err, tt.wantErr)
+            }
+            continue
+        }
+        if err != nil || got != 2 {
+            t.Errorf("%s: got %d, %v; want 2 pages", tt.name, got, err)
+        }
+    }
+}
+
+func TestCountPDFPagesAES256(t *testing.T) {
+    fileKey := bytes.Repeat([]byte{0x5a}, 32)
+    validationSalt := []byte("vsalt123")
+    keySalt := []byte("ksalt456")
+
+    for _, r := range []int{5, 6} {
+        for _, userPass := range []string{"", "secret"} {
+            u := append(append(pdfHashV5(r, []byte(userPass), validationSalt), validationSalt...), keySalt...)
+            block, _ := aes.NewCipher(pdfHashV5(r, []byte(userPass), keySalt))
+            ue := make([]byte, 32)
+            cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(ue, fileKey)
+            o := bytes.Repeat([]byte{1}, 48)
+            dict := fmt.Sprintf("<< /Filter /Standard /V 5 /R %d /Length 256 /P -3904 /O <%x> /U <%x> /OE <%x> /UE <%x>"+
+                " /CF << /StdCF << /CFM /AESV3 /Length 32 >> >> /StmF /StdCF /StrF /StdCF >>", r, o, u, ue, ue)
+
+            data := testEncryptedPDF(dict, []byte("0123456789abcdef"), true, func(num int, plain []byte) []byte {
+                return testAESEncrypt(fileKey, plain)
+            })
+            got, err := CountPDFPages(data)
+            if userPass != "" {
+                if !errors.Is(err, ErrPDFPasswordRequired) {
+                    t.Errorf("R%d with user password: got %d, %v; want ErrPDFPasswordRequired", r, got, err)
+                }
+                continue
+            }
+            if err != nil || got != 2 {
+                t.Errorf("R%d owner password only: got %d, %v; want 2 pages", r, got, err)
+            }
+        }
+    }
+}
+
+func TestCountPDFPagesUnsupportedSecurityHandler(t *testing.T) {
+    data := testPDF("<< /Root 1 0 R /Encrypt 3 0 R >>",
+        "<< /Type /Catalog /Pages 2 0 R >>",
+        "<< /Type /Pages /Kids [] /Count 1 >>",
+        "<< /Filter /Adobe.PubSec /V 4 /R 4 >>",
+    )
+    if n, err := CountPDFPages(data); err == nil {
+        t.Errorf("got %d pages, want an error for a public-key security handler", n)
+    }
+}
+
+func TestPDFParseString(t *testing.T) {
+    tests := []struct {
+        in   string
+        want string
+        n    int
+    }{
+        {"(plain) rest", "plain", 7},
+        {`(a\)b\\c)`, `a)b\c`, 9},
+        {"(nested (parens) ok)", "nested (parens) ok", 20},
+        {`(\101\102\7)`, "AB\x07", 12},
+        {"(line\\\ncontinued)", "linecontinued", 17},
+        {"<48 65 6C6C 6F>", "Hello", 15},
+        {"<ABC>", "\xAB\xC0", 5},
+        {"(unterminated", "", 0},
+        {"plain", "", 0},
+    }
+    for _, tt := range tests {
+        got, n := pdfParseString([]byte(tt.in))
+        if string(got) != tt.want || n != tt.n {
+            t.Errorf("pdfParseString(%q) = %q, %d; want %q, %d", tt.in, got, n, tt.want, tt.n)
+        }
+    }
+}
+
+func TestPDFDictEntries(t *testing.T) {
+    dict := []byte("<< /Filter /Standard /V 2 /R 3 /O (o\\)wner) /U <0A0B> /CF << /StdCF << /CFM /V2 >> >> /Recipients [(a) (b)] /Encrypt 5 0 R >> trailing")
+    entries := pdfDictEntries(dict)
+    tests := map[string]string{
+        "Filter":     "/Standard",
+        "V":          "2",
+        "R":          "3",
+        "O":          "o)wner",
+        "U":          "\x0a\x0b",
+        "CF":         "<< /StdCF << /CFM /V2 >> >>",
+        "Recipients": "[(a) (b)]",
+        "Encrypt":    "5 0 R",
+    }
+    for key, want := range tests {
+        if got := string(entries[key]); got != want {
+            t.Errorf("entry %s = %q, want %q", key, got, want)
+        }
+    }
+    if end := pdfDictEnd(dict); string(dict[end:]) != " trailing" {
+        t.Errorf("pdfDictEnd stopped at %q", dict[end:])
+    }
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "strings"
+
+    "golang.org/x/crypto/bcrypt"
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// Permission — право на группу операций API
+type Permission string
+
+const (
+    PermSubmitJobs     Permission = "jobs:submit"     // создание и отправка своих заданий
+    PermPayJobs        Permission = "jobs:pay"        // оплата своих заданий
+    PermViewAllJobs    Permission = "jobs:view_all"   // задания и платежи всех пользователей
+    PermManageQueue    Permission = "queue:manage"    // любые переходы статусов, проверка принтеров
+    PermManagePayments Permission = "payments:manage" // ручная смена статуса платежа, возвраты
+    PermManagePrinters Permission = "printers:manage" // регистрация, изменение, удаление принтеров
+    PermManagePricing  Permission = "pricing:manage"  // прайс-листы
+    PermManageUsers    Permission = "users:manage"    // профили и роли пользователей
+)
+
+// rolePermissions — права каждой роли; администратор имеет права оператора и клиента
+var rolePermissions = map[string][]Permission{
+    models.RoleCustomer: {PermSubmitJobs, PermPayJobs},
+    models.RoleOperator: {PermSubmitJobs, PermPayJobs, PermViewAllJobs, PermManageQueue, PermManagePayments},
+    models.RoleAdmin: {PermSubmitJobs, PermPayJobs, PermViewAllJobs, PermManageQueue, PermManagePayments,
+        PermManagePrinters, PermManagePricing, PermManageUsers},
+}
+
+// IsRole сообщает, что роль известна
+func IsRole(role string) bool {
+    _, ok := rolePermissions[role]
+    return ok
+}
+
+// HasPermission проверяет, что у роли есть право
+func HasPermission(role string, perm Permission) bool {
+    for _, p := range rolePermissions[role] {
+        if p == perm {
+            return true
+        }
+    }
+    return false
+}
+
+// BootstrapAdmin создаёт первого администратора из ADMIN_EMAIL и ADMIN_PASSWORD.
+// Регистрация всегда выдаёт роль customer, поэтому существующая учётная запись
+// с этим email не повышается: её мог занять кто угодно. Остальных
+// администраторов назначают через PUT /users/:id/role.
+func BootstrapAdmin() error {
+    email := strings.TrimSpace(config.GetEnv("ADMIN_EMAIL", ""))
+    password := config.GetEnv("ADMIN_PASSWORD", "")
+    if email == "" {
+        return nil
+    }
+    if len(password) < 12 {
+        return fmt.Errorf("ADMIN_PASSWORD должен быть не короче 12 символов")
+    }
+
+    var existing models.User
+    err := config.DB.Where("email = ?", email).First(&existing).Error
+    if err == nil {
+        if existing.Role != models.RoleAdmin {
+            log.Printf("ADMIN_EMAIL %s уже зарегистрирован с ролью %s: роль не меняется, назначьте её вручную", email, existing.Role)
+        }
+        return nil
+    }
+    if !errors.Is(err, gorm.ErrRecordNotFound) {
+        return err
+    }
+
+    hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return err
+    }
+    admin := models.User{Email: email, PasswordHash: string(hash), Role: models.RoleAdmin}
+    if err := config.DB.Create(&admin).Error; err != nil {
+        return err
+    }
+    log.Printf("Создан администратор %s", email)
+    return nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestHasPermission(t *testing.T) {
+    tests := []struct {
+        role string
+        perm Permission
+        want bool
+    }{
+        {models.RoleCustomer, PermSubmitJobs, true},
+        {models.RoleCustomer, PermPayJobs, true},
+        {models.RoleCustomer, PermViewAllJobs, false},
+        {models.RoleCustomer, PermManageQueue, false},
+        {models.RoleCustomer, PermManagePayments, false},
+        {models.RoleCustomer, PermManageUsers, false},
+        {models.RoleOperator, PermViewAllJobs, true},
+        {models.RoleOperator, PermManageQueue, true},
+        {models.RoleOperator, PermManagePayments, true},
+        {models.RoleOperator, PermManagePrinters, false},
+        {models.RoleOperator, PermManagePricing, false},
+        {models.RoleOperator, PermManageUsers, false},
+        {models.RoleAdmin, PermManagePrinters, true},
+        {models.RoleAdmin, PermManagePricing, true},
+        {models.RoleAdmin, PermManageUsers, true},
+        {"", PermSubmitJobs, false},
+        {"superuser", PermManageUsers, false},
+    }
+    for _, tt := range tests {
+        if got := HasPermission(tt.role, tt.perm); got != tt.want {
+            t.Errorf("HasPermission(%q, %s) = %v, want %v", tt.role, tt.perm, got, tt.want)
+        }
+    }
+}
+
+// Администратор может всё, что может оператор, а оператор — всё, что может клиент
+func TestRolePermissionsNested(t *testing.T) {
+    chain := []string{models.RoleCustomer, models.RoleOperator, models.RoleAdmin}
+    for i := 1; i < len(chain); i++ {
+        for _, perm := range rolePermissions[chain[i-1]] {
+            if !HasPermission(chain[i], perm) {
+                t.Errorf("%s lacks %s granted to %s", chain[i], perm, chain[i-1])
+            }
+        }
+    }
+}
+
+func TestIsRole(t *testing.T) {
+    for _, role := range []string{models.RoleAdmin, models.RoleOperator, models.RoleCustomer} {
+        if !IsRole(role) {
+            t.Errorf("IsRole(%q) = false", role)
+        }
+    }
+    for _, role := range []string{"", "Admin", "root"} {
+        if IsRole(role) {
+            t.Errorf("IsRole(%q) = true", role)
+        }
+    }
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "fmt"
+    "net"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "print-automation/models"
+)
+
+// pjlUEL — Universal Exit Language: переключает принтер в режим PJL
+const pjlUEL = "\x1b%-12345X"
+
+// pjlLanguages — значения @PJL ENTER LANGUAGE для форматов документа
+var pjlLanguages = map[string]string{
+    "application/pdf":        "PDF",
+    "application/postscript": "POSTSCRIPT",
+    "application/vnd.hp-PCL": "PCL",
+}
+
+// pjlPaper — значения @PJL SET PAPER для форматов бумаги задания
+var pjlPaper = map[string]string{
+    "A3":        "A3",
+    "A4":        "A4",
+    "A5":        "A5",
+    "B5":        "B5",
+    "JIS-B5":    "JISB5",
+    "LETTER":    "LETTER",
+    "LEGAL":     "LEGAL",
+    "LEDGER":    "LEDGER",
+    "EXECUTIVE": "EXECUTIVE",
+}
+
+// buildPJLHeader оборачивает задание в @PJL JOB / @PJL EOJ с именем jobName,
+// включает USTATUS JOB и переводит параметры задания в команды @PJL SET.
+// Для форматов, которые PJL не умеет выбирать (текст, изображения), возвращает nil:
+// такие документы уходят на принтер как есть.
+func buildPJLHeader(ticket JobTicket, jobName string) (header, trailer []byte) {
+    language, ok := pjlLanguages[ticket.DocumentFormat]
+    if !ok {
+        return nil, nil
+    }
+    name := pjlQuote(jobName)
+
+    var b bytes.Buffer
+    b.WriteString(pjlUEL + "@PJL\r\n")
+    // Принтер сообщит о начале и конце задания в это же соединение
+    b.WriteString("@PJL USTATUS JOB=ON\r\n")
+    fmt.Fprintf(&b, "@PJL JOB NAME=%s\r\n", name)
+    if ticket.Copies > 1 {
+        // QTY — число комплектов, COPIES — число копий каждой страницы
+        if ticket.Collate {
+            fmt.Fprintf(&b, "@PJL SET QTY=%d\r\n", ticket.Copies)
+        } else {
+            fmt.Fprintf(&b, "@PJL SET COPIES=%d\r\n", ticket.Copies)
+        }
+    }
+    switch ticket.Sides {
+    case models.SidesOneSided:
+        b.WriteString("@PJL SET DUPLEX=OFF\r\n")
+    case models.SidesTwoSidedLongEdge:
+        b.WriteString("@PJL SET DUPLEX=ON\r\n@PJL SET BINDING=LONGEDGE\r\n")
+    case models.SidesTwoSidedShortEdge:
+        b.WriteString("@PJL SET DUPLEX=ON\r\n@PJL SET BINDING=SHORTEDGE\r\n")
+    }
+    switch ticket.ColorMode {
+    case models.ColorModeColor:
+        b.WriteString("@PJL SET RENDERMODE=COLOR\r\n")
+    case models.ColorModeMonochrome:
+        b.WriteString("@PJL SET RENDERMODE=GRAYSCALE\r\n")
+    }
+    if paper, ok := pjlPaper[strings.ToUpper(ticket.MediaSize)]; ok {
+        fmt.Fprintf(&b, "@PJL SET PAPER=%s\r\n", paper)
+    }
+    // PJL различает только книжную и альбомную ориентацию
+    switch ticket.Orientation {
+    case models.OrientationLandscape, models.OrientationReverseLandscape:
+        b.WriteString("@PJL SET ORIENTATION=LANDSCAPE\r\n")
+    case models.OrientationPortrait, models.OrientationReversePortrait:
+        b.WriteString("@PJL SET ORIENTATION=PORTRAIT\r\n")
+    }
+    fmt.Fprintf(&b, "@PJL ENTER LANGUAGE=%s\r\n", language)
+
+    trailer = []byte(fmt.Sprintf("%s@PJL EOJ NAME=%s\r\n%s", pjlUEL, name, pjlUEL))
+    return b.Bytes(), trailer
+}
+
+// pjlQuote записывает строку в кавычках PJL (без кавычек и управляющих символов внутри)
+func pjlQuote(s string) string {
+    s = strings.Map(func(r rune) rune {
+        if r < 0x20 || r == '"' || r > 0x7e {
+            return -1
+        }
+        return r
+    }, s)
+    if len(s) > 80 {
+        s = s[:80]
+    }
+    return `"` + s + `"`
+}
+
+// Сроки ожидания ответов USTATUS
+const (
+    // pjlSilenceTimeout — сколько ждать первого ответа после передачи; молчащий
+    // принтер считается не поддерживающим USTATUS
+    pjlSilenceTimeout = 60 * time.Second
+    // pjlSessionTimeout — предельное время удержания соединения с принтером
+    pjlSessionTimeout = 30 * time.Minute
+    // pjlSessionTTL — сколько хранится итог задания, которое никто не опросил
+    pjlSessionTTL = time.Hour
+)
+
+// pjlSession — состояние задания RAW по сообщениям @PJL USTATUS JOB
+type pjlSession struct {
+    mu       sync.Mutex
+    state    string
+    pages    int
+    // silent — принтер не ответил ни одним сообщением
+    silent   bool
+    err      error
+}
+
+var (
+    pjlSessionsMu sync.Mutex
+    pjlSessions   = map[int]*pjlSession{}
+    pjlJobNumber  uint32
+)
+
+// startPJLSession регистрирует задание и читает сообщения принтера из conn,
+// пока он не сообщит о конце задания. Возвращает номер задания для QueryStatus.
+// Соединение закрывается по окончании чтения.
+func startPJLSession(conn net.Conn, jobName string, sent <-chan struct{}) int {
+    id := int(atomic.AddUint32(&pjlJobNumber, 1) & 0x7fffffff)
+    session := &pjlSession{state: DriverJobPending}
+
+    pjlSessionsMu.Lock()
+    pjlSessions[id] = session
+    pjlSessionsMu.Unlock()
+
+    go func() {
+        defer conn.Close()
+        session.read(conn, jobName, sent)
+        time.AfterFunc(pjlSessionTTL, func() { dropPJLSession(id) })
+    }()
+    return id
+}
+
+func dropPJLSession(id int) {
+    pjlSessionsMu.Lock()
+    delete(pjlSessions, id)
+    pjlSessionsMu.Unlock()
+}
+
+// pjlSessionStatus возвращает состояние задания; итоговое состояние отдаётся один раз.
+// Молчащий принтер даёт ErrDriverNotSupported, а неизвестный номер (сессии живут
+// только в памяти и теряются при перезапуске) и обрыв связи — ErrDriverJobLost.
+func pjlSessionStatus(id int) (*DriverJobStatus, error) {
+    pjlSessionsMu.Lock()
+    session, ok := pjlSessions[id]
+    pjlSessionsMu.Unlock()
+    if !ok {
+        return nil, fmt.Errorf("%w: сессия PJL %d не найдена", ErrDriverJobLost, id)
+    }
+
+    session.mu.Lock()
+    defer session.mu.Unlock()
+    if session.silent {
+        dropPJLSession(id)
+        return nil, ErrDriverNotSupported
+    }
+    status := &DriverJobStatus{PrinterJobID: id, State: session.state, PagesPrinted: session.pages}
+    switch session.state {
+    case DriverJobCompleted, DriverJobCanceled, DriverJobAborted:
+        dropPJLSession(id)
+    case DriverJobUnknown:
+        dropPJLSession(id)
+        return nil, fmt.Errorf("%w: связь с принтером потеряна до конца задания: %v", ErrDriverJobLost, session.err)
+    }
+    return status, nil
+}
+
+// read разбирает сообщения USTATUS: каждое начинается строкой "@PJL USTATUS ..."
+// и заканчивается символом перевода страницы (FF)
+func (s *pjlSession) read(conn net.Conn, jobName string, sent <-chan struct{}) {
+    deadline := time.Now().Add(pjlSessionTimeout)
+    var sentAt time.Time
+    heard := false
+    partial := ""
+    r := bufio.NewReader(conn)
+    for {
+        // Чтение короткими интервалами, чтобы проверять сроки ожидания
+        conn.SetReadDeadline(time.Now().Add(time.Second))
+        chunk, err := r.ReadString('\f')
+        partial += chunk
+        if err != nil {
+            var netErr net.Error
+            if errors.As(err, &netErr) && netErr.Timeout() {
+                if sentAt.IsZero() {
+                    select {
+                    case <-sent:
+                        sentAt = time.Now()
+                    default:
+                    }
+                }
+                silent := !heard && !sentAt.IsZero() && time.Since(sentAt) > pjlSilenceTimeout
+                if !silent && time.Now().Before(deadline) {
+                    continue
+                }
+            }
+            s.finishRead(heard, err)
+            return
+        }
+        heard = true
+        msg := partial
+        partial = ""
+
+        kind, fields := parsePJLStatus(msg)
+        if kind != "JOB" || fields["NAME"] != jobName {
+            continue
+        }
+        s.mu.Lock()
+        if pages, err := strconv.Atoi(fields["PAGES"]); err == nil {
+            s.pages = pages
+        }
+        switch {
+        case fields["START"] == "true":
+            s.state = DriverJobProcessing
+        case fields["END"] == "true":
+            s.state = DriverJobCompleted
+            if strings.Contains(strings.ToUpper(fields["RESULT"]), "CANCEL") {
+                s.state = DriverJobCanceled
+            }
+        }
+        final := s.state == DriverJobCompleted || s.state == DriverJobCanceled
+        s.mu.Unlock()
+        if final {
+            return
+        }
+    }
+}
+
+// finishRead фиксирует состояние, если чтение закончилось раньше конца задания
+func (s *pjlSession) finishRead(heard bool, err error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if !heard {
+        s.silent = true
+        return
+    }
+    if s.state == DriverJobPending || s.state == DriverJobProcessing {
+        s.state = DriverJobUnknown
+        s.err = err
+    }
+}
+
+// parsePJLStatus разбирает сообщение "@PJL USTATUS JOB\r\nSTART\r\nNAME="..."\r\nPAGES=3".
+// Строки без "=" возвращаются как ключ со значением "true".
+func parsePJLStatus(msg string) (kind string, fields map[string]string) {
+    fields = map[string]string{}
+    for _, line := range strings.Split(strings.TrimRight(msg, "\f"), "\n") {
+        line = strings.TrimSpace(line)
+        if line == "" {
+            continue
+        }
+        upper := strings.ToUpper(line)
+        if strings.HasPrefix(upper, "@PJL USTATUS") {
+            kind = strings.TrimSpace(upper[len("@PJL USTATUS"):])
+            continue
+        }
+        key, value, ok := strings.Cut(line, "=")
+        if !ok {
+            fields[strings.ToUpper(key)] = "true"
+            continue
+        }
+        fields[strings.ToUpper(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"`)
+    }
+    return kind, fields
+}
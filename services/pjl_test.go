@@ -0,0 +1,240 @@
+package services
+
+import (
+    "errors"
+    "io"
+    "net"
+    "strings"
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestBuildPJLHeader(t *testing.T) {
+    tests := []struct {
+        name   string
+        ticket JobTicket
+        want   []string
+        absent  []string
+    }{
+        {
+            name:   "PDF with defaults",
+            ticket: JobTicket{DocumentFormat: "application/pdf", Copies: 1},
+            want:   []string{"@PJL USTATUS JOB=ON\r\n", `@PJL JOB NAME="job-1"` + "\r\n", "@PJL ENTER LANGUAGE=PDF\r\n"},
+            absent: []string{"QTY", "COPIES", "DUPLEX", "PAPER", "ORIENTATION", "RENDERMODE"},
+        },
+        {
+            name: "collated copies, duplex short edge, colour, A3 landscape",
+            ticket: JobTicket{DocumentFormat: "application/postscript", Copies: 3, Collate: true,
+                Sides: models.SidesTwoSidedShortEdge, ColorMode: models.ColorModeColor, MediaSize: "a3",
+                Orientation: models.OrientationReverseLandscape},
+            want: []string{"@PJL SET QTY=3\r\n", "@PJL SET DUPLEX=ON\r\n@PJL SET BINDING=SHORTEDGE\r\n",
+                "@PJL SET RENDERMODE=COLOR\r\n", "@PJL SET PAPER=A3\r\n", "@PJL SET ORIENTATION=LANDSCAPE\r\n",
+                "@PJL ENTER LANGUAGE=POSTSCRIPT\r\n"},
+            absent: []string{"COPIES="},
+        },
+        {
+            name: "uncollated copies, simplex, grayscale, JIS B5",
+            ticket: JobTicket{DocumentFormat: "application/vnd.hp-PCL", Copies: 2, Sides: models.SidesOneSided,
+                ColorMode: models.ColorModeMonochrome, MediaSize: "JIS-B5", Orientation: models.OrientationPortrait},
+            want: []string{"@PJL SET COPIES=2\r\n", "@PJL SET DUPLEX=OFF\r\n", "@PJL SET RENDERMODE=GRAYSCALE\r\n",
+                "@PJL SET PAPER=JISB5\r\n", "@PJL SET ORIENTATION=PORTRAIT\r\n", "@PJL ENTER LANGUAGE=PCL\r\n"},
+            absent: []string{"QTY", "BINDING"},
+        },
+        {
+            name:   "unknown paper is left to the printer",
+            ticket: JobTicket{DocumentFormat: "application/pdf", MediaSize: "na_index-4x6_4x6in"},
+            absent: []string{"PAPER"},
+        },
+    }
+    for _, tt := range tests {
+        header, trailer := buildPJLHeader(tt.ticket, "job-1")
+        h := string(header)
+        if !strings.HasPrefix(h, pjlUEL+"@PJL\r\n") || !strings.HasSuffix(h, "\r\n") {
+            t.Errorf("%s: header framing %q", tt.name, h)
+        }
+        // ENTER LANGUAGE должна быть последней командой заголовка
+        if i := strings.Index(h, "@PJL ENTER LANGUAGE="); i < 0 || strings.Contains(h[i+1:], "@PJL") {
+            t.Errorf("%s: ENTER LANGUAGE is not the last command: %q", tt.name, h)
+        }
+        for _, s := range tt.want {
+            if !strings.Contains(h, s) {
+                t.Errorf("%s: header lacks %q", tt.name, s)
+            }
+        }
+        for _, s := range tt.absent {
+            if strings.Contains(h, s) {
+                t.Errorf("%s: header unexpectedly contains %q", tt.name, s)
+            }
+        }
+        if want := pjlUEL + `@PJL EOJ NAME="job-1"` + "\r\n" + pjlUEL; string(trailer) != want {
+            t.Errorf("%s: trailer %q, want %q", tt.name, trailer, want)
+        }
+    }
+
+    for _, format := range []string{"text/plain", "image/jpeg", ""} {
+        if header, trailer := buildPJLHeader(JobTicket{DocumentFormat: format}, "job-1"); header != nil || trailer != nil {
+            t.Errorf("%q: got a PJL wrapper, want the document sent as is", format)
+        }
+    }
+}
+
+func TestPJLQuote(t *testing.T) {
+    tests := []struct {
+        in, want string
+    }{
+        {"report", `"report"`},
+        {`say "hi"`, `"say hi"`},
+        {"line\r\n@PJL SET", `"line@PJL SET"`},
+        {"отчёт-1", `"-1"`},
+        {strings.Repeat("x", 100), `"` + strings.Repeat("x", 80) + `"`},
+    }
+    for _, tt := range tests {
+        if got := pjlQuote(tt.in); got != tt.want {
+            t.Errorf("pjlQuote(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestParsePJLStatus(t *testing.T) {
+    tests := []struct {
+        name   string
+        msg    string
+        kind   string
+        fields map[string]string
+    }{
+        {
+            name:   "job start",
+            msg:    "@PJL USTATUS JOB\r\nSTART\r\nNAME=\"job-1\"\r\n\f",
+            kind:   "JOB",
+            fields: map[string]string{"START": "true", "NAME": "job-1"},
+        },
+        {
+            name:   "job end with pages",
+            msg:    "@PJL USTATUS JOB\r\nEND\r\nNAME=\"job-1\"\r\nPAGES=3\r\n\f",
+            kind:   "JOB",
+            fields: map[string]string{"END": "true", "NAME": "job-1", "PAGES": "3"},
+        },
+        {
+            name:   "lower case and spaces around =",
+            msg:    "@pjl ustatus job\nend\nname = \"job-2\"\nresult = CANCELED\n",
+            kind:   "JOB",
+            fields: map[string]string{"END": "true", "NAME": "job-2", "RESULT": "CANCELED"},
+        },
+        {
+            name:   "device status",
+            msg:    "@PJL USTATUS DEVICE\r\nCODE=10001\r\nDISPLAY=\"READY\"\r\nONLINE=TRUE\r\n\f",
+            kind:   "DEVICE",
+            fields: map[string]string{"CODE": "10001", "DISPLAY": "READY", "ONLINE": "TRUE"},
+        },
+        {name: "not USTATUS", msg: "garbage\f", kind: "", fields: map[string]string{"GARBAGE": "true"}},
+    }
+    for _, tt := range tests {
+        kind, fields := parsePJLStatus(tt.msg)
+        if kind != tt.kind || len(fields) != len(tt.fields) {
+            t.Errorf("%s: got %q %v, want %q %v", tt.name, kind, fields, tt.kind, tt.fields)
+            continue
+        }
+        for k, v := range tt.fields {
+            if fields[k] != v {
+                t.Errorf("%s: %s = %q, want %q", tt.name, k, fields[k], v)
+            }
+        }
+    }
+}
+
+// runPJLSession проигрывает ответы принтера и возвращает итог сессии
+func runPJLSession(replies string, closeAfter bool) *pjlSession {
+    client, printer := net.Pipe()
+    sent := make(chan struct{})
+    close(sent)
+    session := &pjlSession{state: DriverJobPending}
+    done := make(chan struct{})
+    go func() {
+        session.read(client, "job-1", sent)
+        close(done)
+    }()
+    printer.Write([]byte(replies))
+    if closeAfter {
+        printer.Close()
+    }
+    <-done
+    printer.Close()
+    client.Close()
+    return session
+}
+
+func TestPJLSessionRead(t *testing.T) {
+    start := "@PJL USTATUS JOB\r\nSTART\r\nNAME=\"job-1\"\r\n\f"
+    other := "@PJL USTATUS JOB\r\nEND\r\nNAME=\"job-0\"\r\nPAGES=9\r\n\f"
+    device := "@PJL USTATUS DEVICE\r\nCODE=10023\r\n\f"
+
+    tests := []struct {
+        name       string
+        replies    string
+        closeAfter bool
+        state      string
+        pages      int
+    }{
+        {
+            name:    "completed",
+            replies: start + device + other + "@PJL USTATUS JOB\r\nEND\r\nNAME=\"job-1\"\r\nPAGES=4\r\n\f",
+            state:   DriverJobCompleted, pages: 4,
+        },
+        {
+            name:    "canceled at the panel",
+            replies: start + "@PJL USTATUS JOB\r\nEND\r\nNAME=\"job-1\"\r\nRESULT=CANCELED\r\nPAGES=1\r\n\f",
+            state:   DriverJobCanceled, pages: 1,
+        },
+        {
+            name:    "connection lost mid-job",
+            replies: start, closeAfter: true,
+            state:   DriverJobUnknown,
+        },
+    }
+    for _, tt := range tests {
+        s := runPJLSession(tt.replies, tt.closeAfter)
+        if s.state != tt.state || s.pages != tt.pages || s.silent {
+            t.Errorf("%s: state %s, pages %d, silent %v; want %s, %d", tt.name, s.state, s.pages, s.silent, tt.state, tt.pages)
+        }
+    }
+
+    if s := runPJLSession("", true); !s.silent {
+        t.Errorf("printer closed without a reply: silent = false, state %s", s.state)
+    }
+}
+
+func TestPJLSessionStatusLost(t *testing.T) {
+    // Сессии живут в памяти: после перезапуска номер задания неизвестен
+    if _, err := pjlSessionStatus(0x7ffffff0); !errors.Is(err, ErrDriverJobLost) {
+        t.Errorf("unknown session: err = %v, want ErrDriverJobLost", err)
+    }
+
+    tests := []struct {
+        name    string
+        session *pjlSession
+        state   string
+        wantErr error
+    }{
+        {"printing", &pjlSession{state: DriverJobProcessing, pages: 2}, DriverJobProcessing, nil},
+        {"completed", &pjlSession{state: DriverJobCompleted, pages: 3}, DriverJobCompleted, nil},
+        {"printer never answered", &pjlSession{state: DriverJobPending, silent: true}, "", ErrDriverNotSupported},
+        {"connection lost mid-job", &pjlSession{state: DriverJobUnknown, err: io.ErrUnexpectedEOF}, "", ErrDriverJobLost},
+    }
+    for i, tt := range tests {
+        id := 0x7fffff00 + i
+        pjlSessionsMu.Lock()
+        pjlSessions[id] = tt.session
+        pjlSessionsMu.Unlock()
+
+        status, err := pjlSessionStatus(id)
+        if tt.wantErr != nil {
+            if !errors.Is(err, tt.wantErr) {
+                t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+            }
+        } else if err != nil || status.State != tt.state || status.PagesPrinted != tt.session.pages {
+            t.Errorf("%s: got %+v, %v", tt.name, status, err)
+        }
+        dropPJLSession(id)
+    }
+}
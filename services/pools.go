@@ -0,0 +1,234 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "sync"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+var (
+    // ErrPoolEmpty — в пуле нет ни одного принтера
+    ErrPoolEmpty = errors.New("в пуле нет принтеров")
+    // ErrPoolUnavailable — ни один принтер пула сейчас не может взять задание
+    ErrPoolUnavailable = errors.New("в пуле нет доступного принтера для задания")
+)
+
+// IsPoolPolicy сообщает, что политика выбора принтера известна
+func IsPoolPolicy(policy string) bool {
+    switch policy {
+    case models.PoolPolicyLeastPages, models.PoolPolicyRoundRobin, models.PoolPolicyNearest, models.PoolPolicyCapability:
+        return true
+    }
+    return false
+}
+
+// poolPolicy возвращает политику пула; пулу без записи в printer_pools
+// (например, заданному только полем Printer.Pool) соответствует least_pages
+func poolPolicy(name string) (string, error) {
+    var pool models.PrinterPool
+    err := config.DB.Select("policy").Where("name = ?", name).First(&pool).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return models.PoolPolicyLeastPages, nil
+    }
+    if err != nil {
+        return "", err
+    }
+    return pool.Policy, nil
+}
+
+// poolRoundRobin — счётчики выдачи для политики round_robin
+var (
+    poolRoundRobinMu sync.Mutex
+    poolRoundRobin   = map[string]int{}
+)
+
+// SelectPoolPrinter выбирает принтер пула job.Pool для задания по политике пула.
+// Пропускаются занятые (busy), неготовые по данным монитора и не умеющие
+// выполнить параметры задания принтеры. Если priced, принтер должен печатать
+// задание не дороже его стоимости (она уже оплачена).
+func SelectPoolPrinter(job *models.PrintJob, busy map[string]bool, priced bool) (*models.Printer, error) {
+    var members []models.Printer
+    if err := config.DB.Where("pool = ?", job.Pool).Order("name").Find(&members).Error; err != nil {
+        return nil, err
+    }
+    if len(members) == 0 {
+        return nil, ErrPoolEmpty
+    }
+
+    var eligible []models.Printer
+    for _, m := range members {
+        if busy[m.ID] || !IsPrinterReady(m.Status) {
+            continue
+        }
+        if err := checkPrinterSwitch(job, m, priced); err != nil {
+            continue
+        }
+        eligible = append(eligible, m)
+    }
+    if len(eligible) == 0 {
+        return nil, ErrPoolUnavailable
+    }
+
+    policy, err := poolPolicy(job.Pool)
+    if err != nil {
+        return nil, err
+    }
+    if policy == models.PoolPolicyRoundRobin {
+        poolRoundRobinMu.Lock()
+        i := poolRoundRobin[job.Pool] % len(eligible)
+        poolRoundRobin[job.Pool] = i + 1
+        poolRoundRobinMu.Unlock()
+        return &eligible[i], nil
+    }
+
+    loads, err := printerLoads(eligible)
+    if err != nil {
+        return nil, err
+    }
+    // score — главный критерий политики (меньше — лучше), при равенстве решает загрузка
+    score := func(p models.Printer) int { return 0 }
+    switch policy {
+    case models.PoolPolicyNearest:
+        score = func(p models.Printer) int { return -locationAffinity(job.Location, p.Location) }
+    case models.PoolPolicyCapability:
+        score = func(p models.Printer) int { return capabilitySurplus(job, p.ID) }
+    }
+
+    best := 0
+    bestScore := score(eligible[0])
+    for i := 1; i < len(eligible); i++ {
+        s := score(eligible[i])
+        if s < bestScore || (s == bestScore && loads[eligible[i].ID] < loads[eligible[best].ID]) {
+            best, bestScore = i, s
+        }
+    }
+    return &eligible[best], nil
+}
+
+// QuotePoolPrinter выбирает принтер пула job.Pool, по которому считается цена
+// задания при создании и предварительном расчёте: самый дорогой из принтеров,
+// способных выполнить параметры. Готовность и занятость не учитываются — их
+// проверяет выбор при отправке, а там годится любой принтер не дороже этой цены.
+func QuotePoolPrinter(job *models.PrintJob) (*models.Printer, error) {
+    var members []models.Printer
+    if err := config.DB.Where("pool = ?", job.Pool).Order("name").Find(&members).Error; err != nil {
+        return nil, err
+    }
+    if len(members) == 0 {
+        return nil, ErrPoolEmpty
+    }
+
+    var best *models.Printer
+    bestTotal := 0.0
+    for i := range members {
+        target := *job
+        target.PrinterID = members[i].ID
+        if err := CheckJobCapabilities(&target); err != nil {
+            var optErr *JobOptionsError
+            if errors.As(err, &optErr) {
+                continue
+            }
+            return nil, err
+        }
+        quote, err := QuoteJob(&target)
+        if errors.Is(err, ErrNoPriceList) {
+            // Без прайс-листа принтер не возьмёт задание и при отправке
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        if best == nil || quote.Total > bestTotal+moneyEpsilon {
+            best, bestTotal = &members[i], quote.Total
+        }
+    }
+    if best == nil {
+        return nil, ErrPoolUnavailable
+    }
+    return best, nil
+}
+
+// checkPrinterSwitch проверяет, что задание можно перенести на принтер:
+// принтер выполняет параметры задания, а при priced — печать стоит не дороже оплаченной
+func checkPrinterSwitch(job *models.PrintJob, printer models.Printer, priced bool) error {
+    target := *job
+    target.PrinterID = printer.ID
+    if err := CheckJobCapabilities(&target); err != nil {
+        return err
+    }
+    if !priced || printer.ID == job.PrinterID {
+        return nil
+    }
+    quote, err := QuoteJob(&target)
+    if err != nil {
+        return err
+    }
+    if quote.Total > job.Cost+moneyEpsilon {
+        return &JobOptionsError{Field: "printer_id", Message: fmt.Sprintf("печать на принтере %s стоит %.2f, оплачено %.2f", printer.Name, quote.Total, job.Cost)}
+    }
+    return nil
+}
+
+// printerLoads — оттиски в очереди и в печати по принтерам. Задания пулов в очереди
+// не учитываются: их принтер ещё не выбран.
+func printerLoads(printers []models.Printer) (map[string]int, error) {
+    ids := make([]string, 0, len(printers))
+    for _, p := range printers {
+        ids = append(ids, p.ID)
+    }
+    var jobs []models.PrintJob
+    err := config.DB.Select("printer_id", "pages", "copies", "page_ranges", "number_up").
+        Where("printer_id IN ?", ids).
+        Where("status IN ? OR (status = ? AND pool = ?)", activeJobStatuses, models.JobStatusQueued, "").
+        Find(&jobs).Error
+    if err != nil {
+        return nil, err
+    }
+    loads := map[string]int{}
+    for i := range jobs {
+        loads[jobs[i].PrinterID] += JobImpressions(&jobs[i])
+    }
+    return loads, nil
+}
+
+// locationAffinity — сколько первых уровней расположения ("Офис/3 этаж/Восток") совпадает
+func locationAffinity(a, b string) int {
+    as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+    n := 0
+    for n < len(as) && n < len(bs) {
+        x, y := strings.TrimSpace(as[n]), strings.TrimSpace(bs[n])
+        if x == "" || !strings.EqualFold(x, y) {
+            break
+        }
+        n++
+    }
+    return n
+}
+
+// capabilitySurplus — сколько возможностей принтера задание не использует
+// (цвет для ч/б, дуплекс для односторонней печати): такие принтеры лучше оставить
+// заданиям, которым они нужны
+func capabilitySurplus(job *models.PrintJob, printerID string) int {
+    caps, err := PrinterCapabilitiesFor(printerID)
+    if err != nil || caps == nil {
+        return 0
+    }
+    surplus := 0
+    if caps.ColorSupported && job.ColorMode != models.ColorModeColor {
+        surplus++
+    }
+    if !IsDuplex(job.Sides) {
+        for _, mode := range SplitList(caps.DuplexModes) {
+            if IsDuplex(mode) {
+                surplus++
+                break
+            }
+        }
+    }
+    return surplus
+}
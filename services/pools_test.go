@@ -0,0 +1,49 @@
+package services
+
+import (
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestIsPoolPolicy(t *testing.T) {
+    tests := map[string]bool{
+        models.PoolPolicyLeastPages: true,
+        models.PoolPolicyRoundRobin: true,
+        models.PoolPolicyNearest:    true,
+        models.PoolPolicyCapability: true,
+        "":                          false,
+        "random":                    false,
+        "ROUND_ROBIN":               false,
+    }
+    for policy, want := range tests {
+        if got := IsPoolPolicy(policy); got != want {
+            t.Errorf("IsPoolPolicy(%q) = %v, want %v", policy, got, want)
+        }
+    }
+}
+
+func TestLocationAffinity(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        {"HQ/Floor 2/Room 201", "HQ/Floor 2/Room 201", 3},
+        {"HQ/Floor 2/Room 201", "HQ/Floor 2/Room 205", 2},
+        {"HQ/Floor 2", "hq / floor 2 / Room 201", 2},
+        {"HQ/Floor 2", "HQ/Floor 3", 1},
+        {"HQ", "Branch", 0},
+        {"", "", 0},
+        {"HQ", "", 0},
+        // Пустой сегмент не считается совпадением
+        {"/Floor 2", "/Floor 2", 0},
+    }
+    for _, tt := range tests {
+        if got := locationAffinity(tt.a, tt.b); got != tt.want {
+            t.Errorf("locationAffinity(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+        }
+        if got := locationAffinity(tt.b, tt.a); got != tt.want {
+            t.Errorf("locationAffinity(%q, %q) = %d, want %d (not symmetric)", tt.b, tt.a, got, tt.want)
+        }
+    }
+}
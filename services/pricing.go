@@ -0,0 +1,165 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "strings"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// ErrNoPriceList — для принтера не настроен прайс-лист
+var ErrNoPriceList = errors.New("для принтера не настроен прайс-лист")
+
+// Quote — расчёт стоимости задания с разбивкой
+type Quote struct {
+    PriceListID      string  `json:"price_list_id"`
+    Pages            int     `json:"pages"`
+    SelectedPages    int     `json:"selected_pages"`
+    NumberUp         int     `json:"number_up"`
+    Copies           int     `json:"copies"`
+    Impressions      int     `json:"impressions"`
+    UnitPrice        float64 `json:"unit_price"`
+    PaperSurcharge   float64 `json:"paper_surcharge"`
+    PageCharge       float64 `json:"page_charge"`
+    DuplexDiscount   float64 `json:"duplex_discount"`
+    VolumeDiscount   float64 `json:"volume_discount"`
+    CopyCharge       float64 `json:"copy_charge"`
+    MinChargeApplied bool    `json:"min_charge_applied"`
+    Total            float64 `json:"total"`
+}
+
+// PriceListFor возвращает прайс-лист принтера, а если его нет — общий
+func PriceListFor(printerID string) (*models.PriceList, error) {
+    var list models.PriceList
+    err := config.DB.Preload("Tiers").Preload("PaperSurcharges").
+        Where("printer_id = ?", printerID).First(&list).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        err = config.DB.Preload("Tiers").Preload("PaperSurcharges").
+            Where("printer_id = ?", "").First(&list).Error
+    }
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrNoPriceList
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &list, nil
+}
+
+// QuoteJob считает стоимость задания по прайс-листу его принтера.
+// Пока документ не загружен и число страниц неизвестно, считается одна страница.
+func QuoteJob(job *models.PrintJob) (*Quote, error) {
+    list, err := PriceListFor(job.PrinterID)
+    if err != nil {
+        return nil, err
+    }
+    return CalculatePrice(list, job), nil
+}
+
+// CalculatePrice применяет прайс-лист к параметрам задания:
+// (цена оттиска + доплата за формат) × оттиски − скидка за дуплекс − объёмная скидка
+// + плата за копии, но не меньше минимальной стоимости.
+// Оттиски считаются по выбранным страницам с учётом n-up.
+func CalculatePrice(list *models.PriceList, job *models.PrintJob) *Quote {
+    pages := job.Pages
+    if pages < 1 {
+        pages = 1
+    }
+    selected := SelectedPages(job)
+    if selected < 1 {
+        selected = 1
+    }
+    nup := job.NumberUp
+    if nup < 1 {
+        nup = 1
+    }
+
+    q := &Quote{
+        PriceListID:   list.ID,
+        Pages:         pages,
+        SelectedPages: selected,
+        NumberUp:      nup,
+        Copies:        job.Copies,
+        Impressions:   JobImpressions(job),
+        UnitPrice:     list.PerPage,
+    }
+    if q.Copies < 1 {
+        q.Copies = 1
+    }
+    copies := q.Copies
+    if job.ColorMode == models.ColorModeColor {
+        q.UnitPrice = list.ColorPerPage
+    }
+    for _, s := range list.PaperSurcharges {
+        if strings.EqualFold(s.MediaSize, job.MediaSize) {
+            q.PaperSurcharge = s.PerPage
+            break
+        }
+    }
+
+    q.PageCharge = float64(q.Impressions) * (q.UnitPrice + q.PaperSurcharge)
+    charge := q.PageCharge
+
+    if IsDuplex(job.Sides) && list.DuplexDiscount > 0 {
+        q.DuplexDiscount = charge * list.DuplexDiscount / 100
+        charge -= q.DuplexDiscount
+    }
+
+    // Действует самая выгодная ступень, до которой дотягивает задание
+    tierPercent := 0.0
+    for _, t := range list.Tiers {
+        if q.Impressions >= t.MinPages && t.DiscountPercent > tierPercent {
+            tierPercent = t.DiscountPercent
+        }
+    }
+    if tierPercent > 0 {
+        q.VolumeDiscount = charge * tierPercent / 100
+        charge -= q.VolumeDiscount
+    }
+
+    q.CopyCharge = float64(copies) * list.PerCopy
+    total := charge + q.CopyCharge
+    if total < list.MinCharge {
+        total = list.MinCharge
+        q.MinChargeApplied = true
+    }
+
+    q.PageCharge = roundMoney(q.PageCharge)
+    q.DuplexDiscount = roundMoney(q.DuplexDiscount)
+    q.VolumeDiscount = roundMoney(q.VolumeDiscount)
+    q.CopyCharge = roundMoney(q.CopyCharge)
+    q.Total = roundMoney(total)
+    return q
+}
+
+// ValidatePriceList проверяет значения прайс-листа перед сохранением
+func ValidatePriceList(list *models.PriceList) error {
+    if strings.TrimSpace(list.Name) == "" {
+        return fmt.Errorf("не указано название прайс-листа")
+    }
+    if list.PerPage < 0 || list.ColorPerPage < 0 || list.PerCopy < 0 || list.MinCharge < 0 {
+        return fmt.Errorf("цены не могут быть отрицательными")
+    }
+    if list.DuplexDiscount < 0 || list.DuplexDiscount > 100 {
+        return fmt.Errorf("скидка за дуплекс должна быть от 0 до 100%%")
+    }
+    for _, t := range list.Tiers {
+        if t.MinPages < 1 || t.DiscountPercent < 0 || t.DiscountPercent > 100 {
+            return fmt.Errorf("некорректная объёмная скидка: от %d оттисков, %.2f%%", t.MinPages, t.DiscountPercent)
+        }
+    }
+    for _, s := range list.PaperSurcharges {
+        if strings.TrimSpace(s.MediaSize) == "" || s.PerPage < 0 {
+            return fmt.Errorf("некорректная доплата за формат %q", s.MediaSize)
+        }
+    }
+    return nil
+}
+
+func roundMoney(v float64) float64 {
+    return math.Round(v*100) / 100
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+    "testing"
+
+    "print-automation/models"
+)
+
+func testPriceList() *models.PriceList {
+    return &models.PriceList{
+        ID:             "pl-1",
+        Name:           "Основной",
+        PerPage:        0.10,
+        ColorPerPage:   0.50,
+        PerCopy:        0.05,
+        DuplexDiscount: 20,
+        MinCharge:      0.30,
+        Tiers: []models.PriceTier{
+            {MinPages: 100, DiscountPercent: 10},
+            {MinPages: 500, DiscountPercent: 25},
+        },
+        PaperSurcharges: []models.PaperSurcharge{
+            {MediaSize: "iso_a3_297x420mm", PerPage: 0.05},
+        },
+    }
+}
+
+func TestCalculatePrice(t *testing.T) {
+    tests := []struct {
+        name        string
+        job         models.PrintJob
+        impressions int
+        total       float64
+        minCharge   bool
+    }{
+        {
+            name:        "minimum charge",
+            job:         models.PrintJob{Pages: 1, Copies: 1},
+            impressions: 1, total: 0.30, minCharge: true,
+        },
+        {
+            name:        "unknown page count is one page",
+            job:         models.PrintJob{Copies: 1},
+            impressions: 1, total: 0.30, minCharge: true,
+        },
+        {
+            name:        "copies",
+            job:         models.PrintJob{Pages: 10, Copies: 2},
+            impressions: 20, total: 2.10, // 20 × 0.10 + 2 × 0.05
+        },
+        {
+            name:        "color duplex",
+            job:         models.PrintJob{Pages: 10, Copies: 1, ColorMode: models.ColorModeColor, Sides: models.SidesTwoSidedLongEdge},
+            impressions: 10, total: 4.05, // 10 × 0.50 − 20% + 0.05
+        },
+        {
+            name:        "paper surcharge and first volume tier",
+            job:         models.PrintJob{Pages: 200, Copies: 1, MediaSize: "ISO_A3_297x420mm"},
+            impressions: 200, total: 27.05, // 200 × 0.15 − 10% + 0.05
+        },
+        {
+            name:        "n-up reaches the best tier by sides, not pages",
+            job:         models.PrintJob{Pages: 1200, Copies: 1, NumberUp: 2},
+            impressions: 600, total: 45.05, // 600 × 0.10 − 25% + 0.05
+        },
+        {
+            name:        "page ranges",
+            job:         models.PrintJob{Pages: 10, Copies: 1, PageRanges: "1-3,8"},
+            impressions: 4, total: 0.45,
+        },
+        {
+            name:        "page ranges beyond the document",
+            job:         models.PrintJob{Pages: 5, Copies: 1, PageRanges: "4-9"},
+            impressions: 2, total: 0.30, minCharge: true,
+        },
+    }
+    for _, tt := range tests {
+        q := CalculatePrice(testPriceList(), &tt.job)
+        if q.Impressions != tt.impressions || q.Total != tt.total || q.MinChargeApplied != tt.minCharge {
+            t.Errorf("%s: impressions %d, total %.2f, min charge %v; want %d, %.2f, %v",
+                tt.name, q.Impressions, q.Total, q.MinChargeApplied, tt.impressions, tt.total, tt.minCharge)
+        }
+        if q.PriceListID != "pl-1" {
+            t.Errorf("%s: PriceListID = %q", tt.name, q.PriceListID)
+        }
+    }
+}
+
+// Разбивка должна сходиться с итогом, если минимальная стоимость не применялась
+func TestCalculatePriceBreakdownAddsUp(t *testing.T) {
+    jobs := []models.PrintJob{
+        {Pages: 37, Copies: 3, Sides: models.SidesTwoSidedShortEdge},
+        {Pages: 250, Copies: 2, ColorMode: models.ColorModeColor, MediaSize: "iso_a3_297x420mm"},
+        {Pages: 999, Copies: 1, NumberUp: 4, PageRanges: "1-500"},
+    }
+    for _, job := range jobs {
+        q := CalculatePrice(testPriceList(), &job)
+        sum := roundMoney(q.PageCharge - q.DuplexDiscount - q.VolumeDiscount + q.CopyCharge)
+        if q.MinChargeApplied || sum-q.Total > 0.011 || q.Total-sum > 0.011 {
+            t.Errorf("job %+v: breakdown %.2f vs total %.2f", job, sum, q.Total)
+        }
+    }
+}
+
+func TestValidatePriceList(t *testing.T) {
+    tests := []struct {
+        name   string
+        modify func(*models.PriceList)
+        valid  bool
+    }{
+        {"valid", func(*models.PriceList) {}, true},
+        {"no name", func(l *models.PriceList) { l.Name = " " }, false},
+        {"negative price", func(l *models.PriceList) { l.ColorPerPage = -1 }, false},
+        {"duplex discount above 100%", func(l *models.PriceList) { l.DuplexDiscount = 101 }, false},
+        {"tier from zero pages", func(l *models.PriceList) { l.Tiers[0].MinPages = 0 }, false},
+        {"negative tier", func(l *models.PriceList) { l.Tiers[1].DiscountPercent = -5 }, false},
+        {"surcharge without format", func(l *models.PriceList) { l.PaperSurcharges[0].MediaSize = "" }, false},
+    }
+    for _, tt := range tests {
+        list := testPriceList()
+        tt.modify(list)
+        if err := ValidatePriceList(list); (err == nil) != tt.valid {
+            t.Errorf("%s: ValidatePriceList = %v, want valid=%v", tt.name, err, tt.valid)
+        }
+    }
+}
+
+func TestRoundMoney(t *testing.T) {
+    tests := []struct {
+        in, want float64
+    }{
+        {1.004, 1.00},
+        {1.005000001, 1.01},
+        {0.1 + 0.2, 0.30},
+        {-2.499, -2.50},
+    }
+    for _, tt := range tests {
+        if got := roundMoney(tt.in); got != tt.want {
+            t.Errorf("roundMoney(%v) = %v, want %v", tt.in, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,538 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "sort"
+    "sync"
+    "time"
+
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// QueueConfig — параметры пула обработчиков очереди печати
+type QueueConfig struct {
+    // Workers — число одновременно обрабатываемых заданий (на разных принтерах)
+    Workers int
+    // PollInterval — как часто свободный обработчик проверяет таблицу заданий
+    PollInterval time.Duration
+    // SubmitTimeout ограничивает передачу одного документа на принтер
+    SubmitTimeout time.Duration
+    // TrackInterval и TrackTimeout — опрос состояния задания на принтере
+    TrackInterval time.Duration
+    TrackTimeout  time.Duration
+    // Retry — повторы при временных ошибках отправки
+    Retry RetryPolicy
+    // Failover разрешает переносить задание на другой доступный принтер того же пула
+    Failover bool
+    // PriorityAging — за какое ожидание задание поднимается на уровень приоритета
+    PriorityAging time.Duration
+}
+
+// PrintQueue — очередь печати поверх таблицы print_jobs.
+// Задание в статусе queued ждёт обработчика; на каждом принтере одновременно
+// может быть не больше одного задания в статусе sending или printing.
+type PrintQueue struct {
+    cfg  QueueConfig
+    wake chan struct{}
+    // claimMu сериализует выбор следующего задания, чтобы два обработчика
+    // не взяли задания одного принтера
+    claimMu sync.Mutex
+
+    // inflight — отмена передачи заданий, которые сейчас отправляются на принтер
+    inflightMu sync.Mutex
+    inflight   map[string]context.CancelFunc
+}
+
+var printQueue *PrintQueue
+
+// activeJobStatuses — статусы, при которых принтер считается занятым
+var activeJobStatuses = []string{models.JobStatusSending, models.JobStatusPrinting}
+
+// StartPrintQueue восстанавливает прерванные задания и запускает обработчики
+func StartPrintQueue(cfg QueueConfig) *PrintQueue {
+    if cfg.Workers < 1 {
+        cfg.Workers = 1
+    }
+    if cfg.PollInterval <= 0 {
+        cfg.PollInterval = 5 * time.Second
+    }
+    if cfg.SubmitTimeout <= 0 {
+        cfg.SubmitTimeout = 10 * time.Minute
+    }
+    if cfg.TrackInterval <= 0 {
+        cfg.TrackInterval = 5 * time.Second
+    }
+    if cfg.TrackTimeout <= 0 {
+        cfg.TrackTimeout = 30 * time.Minute
+    }
+    if cfg.Retry.MaxAttempts < 1 {
+        cfg.Retry = DefaultRetryPolicy
+    }
+    if cfg.PriorityAging <= 0 {
+        cfg.PriorityAging = DefaultPriorityAging
+    }
+
+    q := &PrintQueue{
+        cfg:      cfg,
+        wake:     make(chan struct{}, cfg.Workers),
+        inflight: map[string]context.CancelFunc{},
+    }
+    printQueue = q
+
+    q.recoverJobs()
+    for i := 0; i < cfg.Workers; i++ {
+        go q.worker()
+    }
+    go q.expireReleases()
+    log.Printf("Очередь печати запущена: %d обработчик(ов)", cfg.Workers)
+    return q
+}
+
+// EnqueuePrintJob ставит задание в очередь печати, сохраняя также поля columns
+func EnqueuePrintJob(job *models.PrintJob, actor, reason string, columns ...string) error {
+    now := time.Now()
+    prevQueuedAt := job.QueuedAt
+    job.QueuedAt = &now
+    if err := TransitionJob(job, models.JobStatusQueued, actor, reason, append([]string{"QueuedAt"}, columns...)...); err != nil {
+        job.QueuedAt = prevQueuedAt
+        return err
+    }
+    if printQueue != nil {
+        printQueue.notify()
+    }
+    return nil
+}
+
+// expireReleases раз в минуту отменяет задания, не выпущенные у принтера вовремя
+func (q *PrintQueue) expireReleases() {
+    for range time.Tick(time.Minute) {
+        CancelExpiredReleases()
+    }
+}
+
+// setInflight запоминает отмену передачи задания (nil — передача закончилась)
+func (q *PrintQueue) setInflight(jobID string, cancel context.CancelFunc) {
+    q.inflightMu.Lock()
+    defer q.inflightMu.Unlock()
+    if cancel == nil {
+        delete(q.inflight, jobID)
+        return
+    }
+    q.inflight[jobID] = cancel
+}
+
+// abort прерывает передачу задания на принтер, если она идёт
+func (q *PrintQueue) abort(jobID string) bool {
+    q.inflightMu.Lock()
+    cancel, ok := q.inflight[jobID]
+    q.inflightMu.Unlock()
+    if ok {
+        cancel()
+    }
+    return ok
+}
+
+// notify будит свободные обработчики
+func (q *PrintQueue) notify() {
+    for i := 0; i < q.cfg.Workers; i++ {
+        select {
+        case q.wake <- struct{}{}:
+        default:
+            return
+        }
+    }
+}
+
+func (q *PrintQueue) worker() {
+    for {
+        job, err := q.claim()
+        if err != nil {
+            log.Printf("Очередь печати: ошибка выбора задания: %v", err)
+        }
+        if job == nil {
+            select {
+            case <-q.wake:
+            case <-time.After(q.cfg.PollInterval):
+            }
+            continue
+        }
+        q.process(job)
+    }
+}
+
+// claim выбирает задание на свободном принтере и переводит его в sending.
+// Первым идёт задание с наибольшим приоритетом с учётом ожидания, при равенстве —
+// дольше ждущее; отложенные задания до NotBefore не выбираются.
+func (q *PrintQueue) claim() (*models.PrintJob, error) {
+    q.claimMu.Lock()
+    defer q.claimMu.Unlock()
+
+    busyPrinters := config.DB.Model(&models.PrintJob{}).
+        Select("printer_id").
+        Where("status IN ?", activeJobStatuses)
+
+    // С каждого уровня берём самые старые задания: среди них и лучшее по
+    // приоритету с учётом ожидания, и запасные на случай занятых принтеров
+    now := time.Now()
+    var candidates []models.PrintJob
+    for level := models.JobPriorityUrgent; level >= models.JobPriorityLow; level-- {
+        var batch []models.PrintJob
+        err := config.DB.
+            Where("status = ? AND priority = ?", models.JobStatusQueued, level).
+            Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+            Where("not_before IS NULL OR not_before <= ?", now).
+            // Для заданий пула принтер выбирается ниже, предварительный может быть занят
+            Where("printer_id NOT IN (?) OR pool <> ''", busyPrinters).
+            Order("queued_at").
+            Limit(10).
+            Find(&batch).Error
+        if err != nil {
+            return nil, err
+        }
+        candidates = append(candidates, batch...)
+    }
+    sort.SliceStable(candidates, func(i, j int) bool {
+        pi := effectivePriority(&candidates[i], now, q.cfg.PriorityAging)
+        pj := effectivePriority(&candidates[j], now, q.cfg.PriorityAging)
+        if pi != pj {
+            return pi > pj
+        }
+        return waitingSince(&candidates[i]).Before(waitingSince(&candidates[j]))
+    })
+
+    var busy map[string]bool
+    for i := range candidates {
+        job := candidates[i]
+        reason := "передача на принтер"
+        if job.Pool != "" {
+            if busy == nil {
+                set, err := busyPrinterSet()
+                if err != nil {
+                    return nil, err
+                }
+                busy = set
+            }
+            printer, err := SelectPoolPrinter(&job, busy, true)
+            if errors.Is(err, ErrPoolEmpty) || errors.Is(err, ErrPoolUnavailable) {
+                // Ждём, пока освободится или восстановится принтер пула
+                continue
+            }
+            if err != nil {
+                return nil, err
+            }
+            job.PrinterID = printer.ID
+            reason = "передача на принтер " + printer.Name + " пула " + job.Pool
+        }
+        err := TransitionJob(&job, models.JobStatusSending, ActorSystem, reason, "PrinterID")
+        if errors.Is(err, ErrIllegalTransition) {
+            // Задание успели отменить или удержать — берём следующее
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        return &job, nil
+    }
+    return nil, nil
+}
+
+// busyPrinterSet — принтеры, которые сейчас передают или печатают задание
+func busyPrinterSet() (map[string]bool, error) {
+    var ids []string
+    err := config.DB.Model(&models.PrintJob{}).
+        Where("status IN ?", activeJobStatuses).
+        Pluck("printer_id", &ids).Error
+    if err != nil {
+        return nil, err
+    }
+    busy := make(map[string]bool, len(ids))
+    for _, id := range ids {
+        busy[id] = true
+    }
+    return busy, nil
+}
+
+// process передаёт задание на принтер. Сопровождение до завершения идёт
+// в отдельной горутине, как при восстановлении: воркер сразу берёт следующее
+// задание, а принтер остаётся занятым, пока задание в статусе printing.
+func (q *PrintQueue) process(job *models.PrintJob) {
+    var printer models.Printer
+    if err := config.DB.First(&printer, "id = ?", job.PrinterID).Error; err != nil {
+        q.fail(job, "принтер не найден")
+        return
+    }
+
+    driver, err := DriverFor(printer.Protocol)
+    if err != nil {
+        q.fail(job, err.Error())
+        return
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), q.cfg.SubmitTimeout)
+    q.setInflight(job.ID, cancel)
+    status, err := submitJob(ctx, driver, job, printer)
+    q.setInflight(job.ID, nil)
+    aborted := errors.Is(ctx.Err(), context.Canceled)
+    cancel()
+    job.Attempts++
+    if err != nil {
+        if aborted {
+            // Задание отменено, передачу прервал CancelJob
+            log.Printf("Очередь печати: передача задания %s прервана отменой", job.ID)
+            q.notify()
+            return
+        }
+        q.retryOrFail(job, printer, err)
+        return
+    }
+
+    job.PrinterJobID = status.PrinterJobID
+    job.PrinterJobState = status.State
+    job.LastError = ""
+    job.NextAttemptAt = nil
+    err = TransitionJob(job, models.JobStatusPrinting, ActorSystem, "документ принят принтером",
+        "PrinterJobID", "PrinterJobState", "Attempts", "LastError", "NextAttemptAt")
+    if err != nil {
+        // Задание отменили во время передачи: принятое принтером задание тоже отменяем
+        log.Printf("Очередь печати: не удалось перевести задание %s в printing: %v", job.ID, err)
+        if status.PrinterJobID != 0 {
+            ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+            if err := driver.Cancel(ctx, printer, status.PrinterJobID, job.UserID); err != nil {
+                log.Printf("Очередь печати: задание %s не отменено на принтере %s: %v", job.ID, printer.Name, err)
+            }
+            cancel()
+        }
+        q.notify()
+        return
+    }
+
+    go q.track(job, printer, driver)
+}
+
+// retryOrFail возвращает задание в очередь после временной ошибки
+// (с паузой или на другой принтер пула) либо завершает его как failed
+func (q *PrintQueue) retryOrFail(job *models.PrintJob, printer models.Printer, sendErr error) {
+    job.LastError = truncateRunes(sendErr.Error(), 500)
+
+    if !IsTransientSendError(sendErr) {
+        q.fail(job, "неустранимая ошибка отправки: "+sendErr.Error())
+        return
+    }
+    if job.Attempts >= q.cfg.Retry.MaxAttempts {
+        q.fail(job, fmt.Sprintf("исчерпаны попытки отправки (%d): %v", job.Attempts, sendErr))
+        return
+    }
+
+    reason := fmt.Sprintf("попытка %d из %d не удалась: %v", job.Attempts, q.cfg.Retry.MaxAttempts, sendErr)
+    next := time.Now().Add(q.cfg.Retry.Backoff(job.Attempts))
+    if q.cfg.Failover {
+        if alt := findFailoverPrinter(job, printer); alt != nil {
+            job.PrinterID = alt.ID
+            next = time.Now()
+            reason += "; задание перенесено на принтер " + alt.Name
+        }
+    }
+    job.NextAttemptAt = &next
+
+    err := TransitionJob(job, models.JobStatusQueued, ActorSystem, reason, "Attempts", "LastError", "NextAttemptAt", "PrinterID")
+    if err != nil {
+        log.Printf("Очередь печати: задание %s не возвращено в очередь: %v", job.ID, err)
+    } else {
+        log.Printf("Очередь печати: задание %s: %s", job.ID, reason)
+    }
+    q.notify()
+}
+
+// fail завершает задание с ошибкой, сохраняя её текст
+func (q *PrintQueue) fail(job *models.PrintJob, reason string) {
+    if job.LastError == "" {
+        job.LastError = truncateRunes(reason, 500)
+    }
+    if err := TransitionJob(job, models.JobStatusFailed, ActorSystem, reason, "Attempts", "LastError"); err != nil {
+        log.Printf("Очередь печати: задание %s не переведено в failed: %v", job.ID, err)
+    } else {
+        log.Printf("Очередь печати: задание %s → failed: %s", job.ID, reason)
+    }
+    q.notify()
+}
+
+// findFailoverPrinter ищет другой принтер из пула текущего, как при выборе
+// принтера пула: готовый, свободный, выполняющий параметры задания и печатающий
+// его не дороже оплаченного. Выбранный принтер дополнительно опрашивается.
+func findFailoverPrinter(job *models.PrintJob, current models.Printer) *models.Printer {
+    if current.Pool == "" {
+        return nil
+    }
+
+    busy, err := busyPrinterSet()
+    if err != nil {
+        log.Printf("Очередь печати: ошибка поиска принтеров пула %s: %v", current.Pool, err)
+        return nil
+    }
+    busy[current.ID] = true
+
+    target := *job
+    target.Pool = current.Pool
+    for {
+        alt, err := SelectPoolPrinter(&target, busy, true)
+        if err != nil {
+            if !errors.Is(err, ErrPoolEmpty) && !errors.Is(err, ErrPoolUnavailable) {
+                log.Printf("Очередь печати: ошибка поиска принтеров пула %s: %v", current.Pool, err)
+            }
+            return nil
+        }
+        driver, err := DriverFor(alt.Protocol)
+        if err == nil {
+            ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+            err = driver.Probe(ctx, *alt)
+            cancel()
+        }
+        if err == nil {
+            return alt
+        }
+        busy[alt.ID] = true
+    }
+}
+
+// submitJob берёт документ из хранилища и передаёт его драйверу
+func submitJob(ctx context.Context, driver PrinterDriver, job *models.PrintJob, printer models.Printer) (*DriverJobStatus, error) {
+    path, cleanup, err := FetchDocument(ctx, job)
+    if err != nil {
+        return nil, err
+    }
+    defer cleanup()
+
+    ranges, err := ParsePageRanges(job.PageRanges)
+    if err != nil {
+        return nil, Permanent(err)
+    }
+    return driver.Submit(ctx, printer, JobTicket{
+        JobID:          job.ID,
+        JobName:        job.ID,
+        UserName:       job.UserID,
+        Copies:         job.Copies,
+        DocumentPath:   path,
+        DocumentName:   job.DocumentName,
+        DocumentFormat: job.DocumentMIME,
+        ColorMode:      job.ColorMode,
+        Sides:          job.Sides,
+        MediaSize:      job.MediaSize,
+        Orientation:    job.Orientation,
+        PageRanges:     ranges,
+        NumberUp:       job.NumberUp,
+        Collate:        IsCollated(job),
+    })
+}
+
+// track опрашивает принтер, пока задание не завершится.
+// Если протокол не сообщает состояние, задание считается выполненным после передачи.
+func (q *PrintQueue) track(job *models.PrintJob, printer models.Printer, driver PrinterDriver) {
+    caps, err := driver.Capabilities(context.Background(), printer)
+    if err != nil || !caps.JobStatus || job.PrinterJobID == 0 {
+        q.finish(job, models.JobStatusCompleted, "документ передан, протокол не сообщает о ходе печати")
+        return
+    }
+
+    deadline := time.Now().Add(q.cfg.TrackTimeout)
+    for time.Now().Before(deadline) {
+        time.Sleep(q.cfg.TrackInterval)
+
+        // Задание могли отменить через API — дальше следить не за чем
+        var current models.PrintJob
+        if err := config.DB.Select("status").First(&current, "id = ?", job.ID).Error; err == nil && current.Status != models.JobStatusPrinting {
+            q.notify()
+            return
+        }
+
+        ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+        status, err := driver.QueryStatus(ctx, printer, job.PrinterJobID)
+        cancel()
+        if errors.Is(err, ErrDriverNotSupported) {
+            q.finish(job, models.JobStatusCompleted, "документ передан, принтер не сообщает о ходе печати")
+            return
+        }
+        if errors.Is(err, ErrDriverJobLost) {
+            q.finish(job, models.JobStatusNeedsCheck, err.Error())
+            return
+        }
+        if err != nil {
+            // Принтер мог уже удалить задание из истории — считаем его завершённым
+            var ippErr *IPPStatusError
+            if errors.As(err, &ippErr) && ippErr.IsClientError() {
+                q.finish(job, models.JobStatusCompleted, "принтер больше не хранит задание")
+                return
+            }
+            log.Printf("Очередь печати: не удалось опросить задание %s: %v", job.ID, err)
+            continue
+        }
+
+        if status.State != job.PrinterJobState || job.PagesPrinted == nil || status.PagesPrinted != *job.PagesPrinted {
+            printed := status.PagesPrinted
+            job.PrinterJobState = status.State
+            job.PagesPrinted = &printed
+            config.DB.Model(job).Select("PrinterJobState", "PagesPrinted").Updates(job)
+        }
+
+        switch status.State {
+        case DriverJobCompleted:
+            q.finish(job, models.JobStatusCompleted, "принтер сообщил о завершении")
+            return
+        case DriverJobCanceled:
+            q.finish(job, models.JobStatusCanceled, "задание отменено на принтере")
+            return
+        case DriverJobAborted:
+            q.fail(job, "задание прервано принтером")
+            return
+        }
+    }
+
+    q.finish(job, models.JobStatusCompleted, fmt.Sprintf("принтер не сообщил о завершении за %s", q.cfg.TrackTimeout))
+}
+
+// finish записывает итоговый статус задания и освобождает принтер
+func (q *PrintQueue) finish(job *models.PrintJob, status, reason string) {
+    if err := TransitionJob(job, status, ActorSystem, reason); err != nil {
+        log.Printf("Очередь печати: задание %s не переведено в %s: %v", job.ID, status, err)
+    } else {
+        log.Printf("Очередь печати: задание %s → %s: %s", job.ID, status, reason)
+    }
+    q.notify()
+}
+
+// recoverJobs возвращает в очередь задания, прерванные остановкой сервера
+func (q *PrintQueue) recoverJobs() {
+    var interrupted []models.PrintJob
+    if err := config.DB.Where("status = ?", models.JobStatusSending).Find(&interrupted).Error; err != nil {
+        log.Printf("Очередь печати: ошибка восстановления заданий: %v", err)
+    }
+    for i := range interrupted {
+        if err := TransitionJob(&interrupted[i], models.JobStatusQueued, ActorSystem, "передача прервана перезапуском сервера"); err != nil {
+            log.Printf("Очередь печати: задание %s не возвращено в очередь: %v", interrupted[i].ID, err)
+        }
+    }
+
+    // Задания, уже принятые принтером, продолжаем отслеживать
+    var printing []models.PrintJob
+    if err := config.DB.Where("status = ?", models.JobStatusPrinting).Find(&printing).Error; err != nil {
+        log.Printf("Очередь печати: ошибка восстановления заданий: %v", err)
+        return
+    }
+    for i := range printing {
+        job := printing[i]
+        var printer models.Printer
+        if err := config.DB.First(&printer, "id = ?", job.PrinterID).Error; err != nil {
+            q.fail(&job, "принтер не найден")
+            continue
+        }
+        driver, err := DriverFor(printer.Protocol)
+        if err != nil {
+            q.fail(&job, err.Error())
+            continue
+        }
+        go q.track(&job, printer, driver)
+    }
+}
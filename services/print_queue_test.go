@@ -0,0 +1,50 @@
+package services
+
+import (
+    "context"
+    "testing"
+)
+
+func TestQueueNotify(t *testing.T) {
+    tests := []struct {
+        name    string
+        workers int
+        calls   int
+        want    int
+    }{
+        {"one call wakes every worker", 3, 1, 3},
+        {"repeated calls do not block", 2, 5, 2},
+        {"single worker", 1, 1, 1},
+    }
+    for _, tt := range tests {
+        q := &PrintQueue{cfg: QueueConfig{Workers: tt.workers}, wake: make(chan struct{}, tt.workers)}
+        for i := 0; i < tt.calls; i++ {
+            q.notify()
+        }
+        if got := len(q.wake); got != tt.want {
+            t.Errorf("%s: %d wake-ups pending, want %d", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestQueueAbort(t *testing.T) {
+    q := &PrintQueue{inflight: map[string]context.CancelFunc{}}
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    q.setInflight("job-1", cancel)
+
+    if q.abort("job-2") {
+        t.Error("abort of a job that is not being sent reported success")
+    }
+    if !q.abort("job-1") {
+        t.Fatal("abort of a job being sent reported nothing to abort")
+    }
+    if ctx.Err() == nil {
+        t.Error("transfer context was not canceled")
+    }
+
+    q.setInflight("job-1", nil)
+    if q.abort("job-1") {
+        t.Error("abort after the transfer finished reported success")
+    }
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// OID из HOST-RESOURCES-MIB (RFC 2790) и Printer-MIB (RFC 3805)
+const (
+    oidHrDeviceStatus              = "1.3.6.1.2.1.25.3.2.1.5"
+    oidHrPrinterStatus             = "1.3.6.1.2.1.25.3.5.1.1"
+    oidHrPrinterDetectedErrorState = "1.3.6.1.2.1.25.3.5.1.2"
+    oidPrtMarkerLifeCount          = "1.3.6.1.2.1.43.10.2.1.4"
+    oidPrtMarkerSuppliesDesc       = "1.3.6.1.2.1.43.11.1.1.6"
+    oidPrtMarkerSuppliesMaxCap     = "1.3.6.1.2.1.43.11.1.1.8"
+    oidPrtMarkerSuppliesLevel      = "1.3.6.1.2.1.43.11.1.1.9"
+)
+
+// Состояние принтера в models.Printer.Status
+const (
+    PrinterStatusUnknown  = "UNKNOWN"
+    PrinterStatusIdle     = "IDLE"
+    PrinterStatusPrinting = "PRINTING"
+    PrinterStatusWarmup   = "WARMUP"
+    PrinterStatusError    = "ERROR"
+    PrinterStatusOffline  = "OFFLINE"
+)
+
+// ErrSNMPDisabled — для принтера опрос SNMP выключен
+var ErrSNMPDisabled = errors.New("опрос SNMP для принтера выключен")
+
+// hrPrinterDetectedErrorState — битовая строка, старший бит первого байта — бит 0
+var printerErrorFlags = []string{
+    "lowPaper", "noPaper", "lowToner", "noToner", "doorOpen", "jammed", "offline", "serviceRequested",
+    "inputTrayMissing", "outputTrayMissing", "markerSupplyMissing", "outputNearFull", "outputFull",
+    "inputTrayEmpty", "overduePreventMaint",
+}
+
+// blockingPrinterErrors — состояния, при которых печать невозможна
+var blockingPrinterErrors = map[string]bool{
+    "noPaper": true, "noToner": true, "doorOpen": true, "jammed": true, "offline": true,
+    "inputTrayMissing": true, "outputTrayMissing": true, "markerSupplyMissing": true, "outputFull": true,
+}
+
+// PrinterSupplyLevel — остаток расходного материала
+type PrinterSupplyLevel struct {
+    Index       int    `json:"index"`
+    Description string `json:"description"`
+    Level       int    `json:"level"`
+    MaxCapacity int    `json:"max_capacity"`
+    Percent     int    `json:"percent"`
+}
+
+// PrinterSNMPStatus — результат опроса Printer-MIB
+type PrinterSNMPStatus struct {
+    Status     string               `json:"status"`
+    ErrorFlags []string             `json:"error_flags"`
+    PageCount  int64                `json:"page_count"`
+    Supplies   []PrinterSupplyLevel `json:"supplies"`
+}
+
+// snmpClientFor создаёт клиент по настройкам принтера
+func snmpClientFor(printer models.Printer) (*SNMPClient, error) {
+    version := SNMPv2c
+    switch strings.ToLower(printer.SNMPVersion) {
+    case "off", "none", "disabled":
+        return nil, ErrSNMPDisabled
+    case "v1", "1":
+        version = SNMPv1
+    }
+    return NewSNMPClient(printer.IPAddress, printer.SNMPCommunity, version), nil
+}
+
+// PollPrinterSNMP читает состояние, ошибки, счётчик страниц и расходные материалы принтера
+func PollPrinterSNMP(ctx context.Context, printer models.Printer) (*PrinterSNMPStatus, error) {
+    client, err := snmpClientFor(printer)
+    if err != nil {
+        return nil, err
+    }
+
+    // Индекс устройства в hrDeviceTable обычно 1, но не всегда — берём первую строку таблицы
+    statusBind, err := client.GetNext(ctx, oidHrPrinterStatus)
+    if err != nil {
+        return nil, err
+    }
+    if !strings.HasPrefix(statusBind.OID, oidHrPrinterStatus+".") {
+        return nil, fmt.Errorf("принтер не поддерживает HOST-RESOURCES-MIB")
+    }
+    deviceIndex := strings.TrimPrefix(statusBind.OID, oidHrPrinterStatus+".")
+
+    result := &PrinterSNMPStatus{Status: PrinterStatusUnknown}
+    binds, err := client.Get(ctx,
+        oidHrDeviceStatus+"."+deviceIndex,
+        oidHrPrinterDetectedErrorState+"."+deviceIndex,
+    )
+    if err != nil {
+        return nil, err
+    }
+    if len(binds) != 2 {
+        return nil, fmt.Errorf("SNMP: ожидалось 2 переменные, получено %d", len(binds))
+    }
+    deviceStatus, _ := binds[0].Int()
+    if binds[1].Exists() {
+        result.ErrorFlags = decodePrinterErrorState(binds[1].Bytes())
+    }
+    printerStatus, _ := statusBind.Int()
+    result.Status = printerStatusName(printerStatus, deviceStatus, result.ErrorFlags)
+
+    // Счётчик страниц: первый маркер (как правило, единственный)
+    if life, err := client.Walk(ctx, oidPrtMarkerLifeCount); err == nil && len(life) > 0 {
+        result.PageCount, _ = life[0].Int()
+    }
+
+    supplies, err := pollSupplies(ctx, client)
+    if err != nil {
+        return nil, err
+    }
+    result.Supplies = supplies
+    return result, nil
+}
+
+// pollSupplies читает prtMarkerSuppliesTable (описание, ёмкость и уровень)
+func pollSupplies(ctx context.Context, client *SNMPClient) ([]PrinterSupplyLevel, error) {
+    columns := map[string]map[string]SNMPVarBind{}
+    for _, oid := range []string{oidPrtMarkerSuppliesDesc, oidPrtMarkerSuppliesMaxCap, oidPrtMarkerSuppliesLevel} {
+        rows, err := client.Walk(ctx, oid)
+        if err != nil {
+            return nil, err
+        }
+        columns[oid] = map[string]SNMPVarBind{}
+        for _, row := range rows {
+            columns[oid][strings.TrimPrefix(row.OID, oid+".")] = row
+        }
+    }
+
+    var supplies []PrinterSupplyLevel
+    for suffix, desc := range columns[oidPrtMarkerSuppliesDesc] {
+        // Суффикс строки — hrDeviceIndex.prtMarkerSuppliesIndex
+        index, err := strconv.Atoi(suffix[strings.LastIndex(suffix, ".")+1:])
+        if err != nil {
+            continue
+        }
+        maxCap, _ := columns[oidPrtMarkerSuppliesMaxCap][suffix].Int()
+        level, _ := columns[oidPrtMarkerSuppliesLevel][suffix].Int()
+        supply := PrinterSupplyLevel{
+            Index:       index,
+            Description: truncateRunes(desc.String(), 255),
+            Level:       int(level),
+            MaxCapacity: int(maxCap),
+            Percent:     -1,
+        }
+        if maxCap > 0 && level >= 0 {
+            supply.Percent = int(level * 100 / maxCap)
+        }
+        supplies = append(supplies, supply)
+    }
+    sort.Slice(supplies, func(i, j int) bool { return supplies[i].Index < supplies[j].Index })
+    return supplies, nil
+}
+
+// decodePrinterErrorState переводит биты hrPrinterDetectedErrorState в имена флагов
+func decodePrinterErrorState(bits []byte) []string {
+    var flags []string
+    for i, name := range printerErrorFlags {
+        if i/8 < len(bits) && bits[i/8]&(0x80>>(i%8)) != 0 {
+            flags = append(flags, name)
+        }
+    }
+    return flags
+}
+
+// printerStatusName сводит hrPrinterStatus, hrDeviceStatus и ошибки в одно состояние
+func printerStatusName(printerStatus, deviceStatus int64, flags []string) string {
+    for _, f := range flags {
+        if f == "offline" {
+            return PrinterStatusOffline
+        }
+    }
+    for _, f := range flags {
+        if blockingPrinterErrors[f] {
+            return PrinterStatusError
+        }
+    }
+    // hrDeviceStatus: down(5)
+    if deviceStatus == 5 {
+        return PrinterStatusError
+    }
+    // hrPrinterStatus: other(1), unknown(2), idle(3), printing(4), warmup(5)
+    switch printerStatus {
+    case 3:
+        return PrinterStatusIdle
+    case 4:
+        return PrinterStatusPrinting
+    case 5:
+        return PrinterStatusWarmup
+    }
+    return PrinterStatusUnknown
+}
+
+// IsPrinterReady сообщает, что по данным SNMP принтер может печатать
+func IsPrinterReady(status string) bool {
+    return status != PrinterStatusError && status != PrinterStatusOffline
+}
+
+// RefreshPrinterSNMP опрашивает принтер и сохраняет состояние, ошибки,
+// счётчик страниц и уровни расходных материалов
+func RefreshPrinterSNMP(ctx context.Context, printer *models.Printer) (*PrinterSNMPStatus, error) {
+    status, err := PollPrinterSNMP(ctx, *printer)
+    if err != nil {
+        return nil, err
+    }
+
+    now := time.Now()
+    printer.Status = status.Status
+    printer.ErrorState = truncateRunes(strings.Join(status.ErrorFlags, ","), 255)
+    printer.PageCount = status.PageCount
+    printer.SNMPCheckedAt = &now
+
+    err = config.DB.Transaction(func(tx *gorm.DB) error {
+        err := tx.Model(printer).Select("Status", "ErrorState", "PageCount", "SNMPCheckedAt", "UpdatedAt").Updates(printer).Error
+        if err != nil {
+            return err
+        }
+        if err := tx.Delete(&models.PrinterSupply{}, "printer_id = ?", printer.ID).Error; err != nil {
+            return err
+        }
+        for _, s := range status.Supplies {
+            supply := models.PrinterSupply{
+                PrinterID:   printer.ID,
+                SupplyIndex: s.Index,
+                Description: s.Description,
+                Level:       s.Level,
+                MaxCapacity: s.MaxCapacity,
+                Percent:     s.Percent,
+            }
+            if err := tx.Create(&supply).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return status, nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "log"
+    "sync"
+    "time"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// PrinterStatusOnline — принтер отвечает, но подробного состояния (SNMP) нет
+const PrinterStatusOnline = "ONLINE"
+
+// MonitorConfig — параметры фоновой проверки принтеров
+type MonitorConfig struct {
+    // Interval — период проверки доступного принтера
+    Interval time.Duration
+    // ProbeTimeout ограничивает одну проверку (соединение и опрос SNMP)
+    ProbeTimeout time.Duration
+    // MaxBackoff — предельный интервал для принтера, который раз за разом не отвечает
+    MaxBackoff time.Duration
+    // Concurrency — сколько принтеров проверяется одновременно
+    Concurrency int
+}
+
+// PrinterHealth — результат проверки принтера
+type PrinterHealth struct {
+    Online    bool               `json:"online"`
+    Status    string             `json:"status"`
+    Message   string             `json:"message"`
+    SNMP      *PrinterSNMPStatus `json:"snmp,omitempty"`
+    SNMPError string             `json:"snmp_error,omitempty"`
+}
+
+// PrinterMonitor периодически проверяет все принтеры и поддерживает
+// IsOnline, Status и LastSeenAt в актуальном состоянии
+type PrinterMonitor struct {
+    cfg MonitorConfig
+
+    mu sync.Mutex
+    // failures и nextCheck — счётчик неудачных проверок подряд и время следующей
+    failures  map[string]int
+    nextCheck map[string]time.Time
+}
+
+// StartPrinterMonitor запускает фоновую проверку принтеров
+func StartPrinterMonitor(cfg MonitorConfig) *PrinterMonitor {
+    if cfg.Interval <= 0 {
+        cfg.Interval = time.Minute
+    }
+    if cfg.ProbeTimeout <= 0 {
+        cfg.ProbeTimeout = 5 * time.Second
+    }
+    if cfg.MaxBackoff < cfg.Interval {
+        cfg.MaxBackoff = 30 * time.Minute
+        if cfg.MaxBackoff < cfg.Interval {
+            cfg.MaxBackoff = cfg.Interval
+        }
+    }
+    if cfg.Concurrency < 1 {
+        cfg.Concurrency = 8
+    }
+
+    m := &PrinterMonitor{
+        cfg:       cfg,
+        failures:  map[string]int{},
+        nextCheck: map[string]time.Time{},
+    }
+    go m.run()
+    log.Printf("Монитор принтеров запущен: проверка каждые %s", cfg.Interval)
+    return m
+}
+
+func (m *PrinterMonitor) run() {
+    // Проверяем чаще интервала, чтобы принтеры с разными сроками проверялись вовремя
+    tick := m.cfg.Interval / 4
+    if tick < time.Second {
+        tick = time.Second
+    }
+    for {
+        m.checkDue()
+        time.Sleep(tick)
+    }
+}
+
+// checkDue проверяет принтеры, у которых подошёл срок
+func (m *PrinterMonitor) checkDue() {
+    var printers []models.Printer
+    if err := config.DB.Find(&printers).Error; err != nil {
+        log.Printf("Монитор принтеров: ошибка чтения принтеров: %v", err)
+        return
+    }
+
+    // Забываем удалённые принтеры
+    known := make(map[string]bool, len(printers))
+    for _, p := range printers {
+        known[p.ID] = true
+    }
+    m.mu.Lock()
+    for id := range m.nextCheck {
+        if !known[id] {
+            delete(m.nextCheck, id)
+            delete(m.failures, id)
+        }
+    }
+    m.mu.Unlock()
+
+    now := time.Now()
+    sem := make(chan struct{}, m.cfg.Concurrency)
+    var wg sync.WaitGroup
+    for i := range printers {
+        printer := &printers[i]
+        m.mu.Lock()
+        due := !now.Before(m.nextCheck[printer.ID])
+        m.mu.Unlock()
+        if !due {
+            continue
+        }
+
+        wg.Add(1)
+        sem <- struct{}{}
+        go func() {
+            defer wg.Done()
+            defer func() { <-sem }()
+
+            ctx, cancel := context.WithTimeout(context.Background(), m.cfg.ProbeTimeout)
+            health, err := CheckPrinter(ctx, printer)
+            cancel()
+            if err != nil {
+                log.Printf("Монитор принтеров: %s: %v", printer.Name, err)
+            }
+            m.schedule(printer.ID, health != nil && health.Online)
+        }()
+    }
+    wg.Wait()
+}
+
+// schedule назначает следующую проверку: через Interval для доступного принтера
+// и с удвоением паузы (до MaxBackoff) для недоступного
+func (m *PrinterMonitor) schedule(printerID string, online bool) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    delay := m.cfg.Interval
+    if online {
+        delete(m.failures, printerID)
+    } else {
+        m.failures[printerID]++
+        for i := 1; i < m.failures[printerID] && delay < m.cfg.MaxBackoff; i++ {
+            delay *= 2
+        }
+        if delay > m.cfg.MaxBackoff {
+            delay = m.cfg.MaxBackoff
+        }
+    }
+    m.nextCheck[printerID] = time.Now().Add(delay)
+}
+
+// CheckPrinter проверяет доступность принтера драйвером его протокола и, если
+// принтер отвечает, опрашивает его по SNMP. Результат сохраняется в принтере,
+// а смена доступности или состояния записывается в printer_events.
+func CheckPrinter(ctx context.Context, printer *models.Printer) (*PrinterHealth, error) {
+    driver, err := DriverFor(printer.Protocol)
+    if err != nil {
+        return nil, err
+    }
+
+    prevOnline, prevStatus := printer.IsOnline, printer.Status
+    health := &PrinterHealth{Status: PrinterStatusOffline}
+    if err := driver.Probe(ctx, *printer); err != nil {
+        health.Message = err.Error()
+    } else {
+        health.Online = true
+        health.Status = PrinterStatusOnline
+        health.Message = "Успешное соединение"
+
+        // Порт открыт, но принтер может стоять без бумаги или с замятием
+        snmp, err := RefreshPrinterSNMP(ctx, printer)
+        switch {
+        case err == nil:
+            health.SNMP = snmp
+            health.Status = snmp.Status
+            if !IsPrinterReady(snmp.Status) {
+                health.Message = "Принтер сообщает об ошибке"
+            }
+        case !errors.Is(err, ErrSNMPDisabled):
+            health.SNMPError = err.Error()
+        }
+    }
+
+    printer.IsOnline = health.Online
+    printer.Status = health.Status
+    columns := []string{"IsOnline", "Status", "UpdatedAt"}
+    if health.Online {
+        now := time.Now()
+        printer.LastSeenAt = &now
+        columns = append(columns, "LastSeenAt")
+    }
+
+    err = config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(printer).Select(columns).Updates(printer).Error; err != nil {
+            return err
+        }
+        if prevOnline == printer.IsOnline && prevStatus == printer.Status {
+            return nil
+        }
+        return tx.Create(&models.PrinterEvent{
+            PrinterID:  printer.ID,
+            FromStatus: prevStatus,
+            ToStatus:   printer.Status,
+            IsOnline:   printer.IsOnline,
+            Message:    truncateRunes(health.Message, 500),
+        }).Error
+    })
+    if err != nil {
+        return health, err
+    }
+    return health, nil
+}
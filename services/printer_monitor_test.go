@@ -0,0 +1,48 @@
+package services
+
+import (
+    "testing"
+    "time"
+)
+
+func TestMonitorScheduleBackoff(t *testing.T) {
+    m := &PrinterMonitor{
+        cfg:       MonitorConfig{Interval: time.Minute, MaxBackoff: 10 * time.Minute},
+        failures:  map[string]int{},
+        nextCheck: map[string]time.Time{},
+    }
+    // Шаги выполняются по порядку на одном принтере
+    steps := []struct {
+        name   string
+        online bool
+        want   time.Duration
+    }{
+        {"online", true, time.Minute},
+        {"first failure", false, time.Minute},
+        {"second failure", false, 2 * time.Minute},
+        {"third failure", false, 4 * time.Minute},
+        {"fourth failure", false, 8 * time.Minute},
+        {"capped", false, 10 * time.Minute},
+        {"stays capped", false, 10 * time.Minute},
+        {"back online", true, time.Minute},
+        {"failure after recovery", false, time.Minute},
+    }
+    for _, tt := range steps {
+        before := time.Now()
+        m.schedule("p1", tt.online)
+        after := time.Now()
+        next := m.nextCheck["p1"]
+        if next.Before(before.Add(tt.want)) || next.After(after.Add(tt.want)) {
+            t.Errorf("%s: next check in %s, want %s", tt.name, next.Sub(before), tt.want)
+        }
+    }
+    if m.failures["p1"] != 1 {
+        t.Errorf("failures after recovery = %d, want 1", m.failures["p1"])
+    }
+
+    // Счётчики разных принтеров независимы
+    m.schedule("p2", false)
+    if got := m.nextCheck["p2"].Sub(time.Now()); got > time.Minute {
+        t.Errorf("p2 inherited backoff of p1: next check in %s", got)
+    }
+}
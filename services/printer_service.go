@@ -33,7 +33,7 @@ func SendToPrinterRaw(filePath, printerIP string, printerPort int) error {
         return fmt.Errorf("ошибка при передаче данных на принтер: %w", err)
     }
 
-    // Возможен дополнительный завершающий символ, но зачастую HP принтеры завершают задание автоматически
+    // Конец задания здесь неявный; документы PDF/PS/PCL RawDriver оборачивает в @PJL JOB/EOJ
     return nil
 }
 
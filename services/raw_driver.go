@@ -0,0 +1,98 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "net"
+    "os"
+    "strconv"
+    "time"
+
+    "print-automation/models"
+)
+
+// RawDriver — печать через RAW-порт (JetDirect, 9100)
+type RawDriver struct{}
+
+func (d *RawDriver) Submit(ctx context.Context, printer models.Printer, ticket JobTicket) (*DriverJobStatus, error) {
+    f, err := os.Open(ticket.DocumentPath)
+    if err != nil {
+        return nil, fmt.Errorf("не удалось открыть файл для печати: %w", err)
+    }
+    defer f.Close()
+
+    addr := net.JoinHostPort(printer.IPAddress, strconv.Itoa(rawPort(printer)))
+    conn, err := (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, "tcp", addr)
+    if err != nil {
+        return nil, fmt.Errorf("не удалось подключиться к принтеру [%s]: %w", addr, err)
+    }
+
+    // Задание оборачивается в @PJL JOB/EOJ с параметрами печати, а о ходе печати
+    // принтер сообщает через USTATUS в то же соединение
+    header, trailer := buildPJLHeader(ticket, ticket.JobID)
+    if header == nil {
+        err = streamRaw(ctx, conn, f)
+        conn.Close()
+        if err != nil {
+            return nil, err
+        }
+        // Без PJL принтер ничего не сообщает: задание считается принятым, как только данные переданы
+        return &DriverJobStatus{State: DriverJobProcessing}, nil
+    }
+
+    sent := make(chan struct{})
+    jobNum := startPJLSession(conn, ticket.JobID, sent)
+    err = streamRaw(ctx, conn, io.MultiReader(bytes.NewReader(header), f, bytes.NewReader(trailer)))
+    close(sent)
+    if err != nil {
+        conn.Close()
+        dropPJLSession(jobNum)
+        return nil, err
+    }
+    return &DriverJobStatus{PrinterJobID: jobNum, State: DriverJobPending}, nil
+}
+
+// streamRaw передаёт данные на RAW-порт; отмена ctx обрывает передачу
+func streamRaw(ctx context.Context, conn net.Conn, data io.Reader) error {
+    stop := context.AfterFunc(ctx, func() { conn.SetWriteDeadline(time.Now()) })
+    _, err := io.Copy(conn, data)
+    stop()
+    if err != nil {
+        if ctx.Err() != nil {
+            return fmt.Errorf("передача данных на принтер прервана: %w", ctx.Err())
+        }
+        return fmt.Errorf("ошибка при передаче данных на принтер: %w", err)
+    }
+    return nil
+}
+
+func (d *RawDriver) Probe(ctx context.Context, printer models.Printer) error {
+    return CheckPrinterConnection(printer.IPAddress, rawPort(printer))
+}
+
+func (d *RawDriver) QueryStatus(ctx context.Context, printer models.Printer, printerJobID int) (*DriverJobStatus, error) {
+    return pjlSessionStatus(printerJobID)
+}
+
+// Cancel не поддерживается: принятое по RAW задание уже в памяти принтера,
+// прервать можно только передачу (отменой ctx в Submit)
+func (d *RawDriver) Cancel(ctx context.Context, printer models.Printer, printerJobID int, userName string) error {
+    return ErrDriverNotSupported
+}
+
+func (d *RawDriver) Capabilities(ctx context.Context, printer models.Printer) (*DriverCapabilities, error) {
+    return &DriverCapabilities{
+        // Состояние известно только для заданий с PJL (PDF, PostScript, PCL)
+        JobStatus:       true,
+        DocumentFormats: []string{"application/pdf", "application/postscript", "application/vnd.hp-PCL"},
+    }, nil
+}
+
+func rawPort(printer models.Printer) int {
+    if printer.Port == 0 {
+        return 9100
+    }
+    return printer.Port
+}
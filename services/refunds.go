@@ -0,0 +1,295 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "time"
+
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// ErrNothingToRefund — по платежу не осталось суммы для возврата
+var ErrNothingToRefund = errors.New("нет суммы для возврата")
+
+// refundTimeout ограничивает ожидание ответа провайдера на возврат
+const refundTimeout = 30 * time.Second
+
+// RefundableAmount — сколько ещё можно вернуть по платежу
+func RefundableAmount(payment *models.Payment) float64 {
+    if payment.Status != models.PaymentStatusSucceeded {
+        return 0
+    }
+    return roundMoney(payment.Amount - payment.RefundedAmount)
+}
+
+// reserveRefund блокирует строку платежа до конца транзакции и проверяет, что amount
+// укладывается в остаток за вычетом проведённых и ещё не завершённых возвратов.
+// save сохраняет возврат в той же транзакции, поэтому параллельные возвраты
+// не превысят сумму платежа.
+func reserveRefund(paymentID string, amount float64, save func(tx *gorm.DB) error) error {
+    return config.DB.Transaction(func(tx *gorm.DB) error {
+        var locked models.Payment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", paymentID).Error; err != nil {
+            return err
+        }
+        reserved, err := reservedRefunds(tx, paymentID)
+        if err != nil {
+            return err
+        }
+
+        available := 0.0
+        if locked.Status == models.PaymentStatusSucceeded {
+            available = roundMoney(locked.Amount - reserved)
+        }
+        if amount < moneyEpsilon || amount > available+moneyEpsilon {
+            return fmt.Errorf("%w: запрошено %.2f, доступно %.2f", ErrNothingToRefund, amount, available)
+        }
+        return save(tx)
+    })
+}
+
+// reservedRefunds — сумма проведённых и ещё не завершённых возвратов по платежу
+func reservedRefunds(tx *gorm.DB, paymentID string) (float64, error) {
+    var reserved float64
+    err := tx.Model(&models.Refund{}).
+        Select("COALESCE(SUM(amount), 0)").
+        Where("payment_id = ? AND status IN ?", paymentID, []string{models.RefundStatusPending, models.RefundStatusSucceeded}).
+        Scan(&reserved).Error
+    return reserved, err
+}
+
+// IssueRefund проводит возврат amount по платежу через провайдера и сохраняет его запись.
+// Неудавшийся у провайдера возврат остаётся в статусе failed и может быть повторён.
+func IssueRefund(ctx context.Context, payment *models.Payment, amount float64, reason string) (*models.Refund, error) {
+    amount = roundMoney(amount)
+    refund := models.Refund{
+        PaymentID:  payment.ID,
+        PrintJobID: payment.PrintJobID,
+        Amount:     amount,
+        Status:     models.RefundStatusPending,
+        Reason:     truncateRunes(reason, 500),
+    }
+    err := reserveRefund(payment.ID, amount, func(tx *gorm.DB) error {
+        return tx.Create(&refund).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &refund, processRefund(ctx, payment, &refund)
+}
+
+// RetryRefund повторяет неудавшийся возврат
+func RetryRefund(ctx context.Context, refund *models.Refund) error {
+    if refund.Status != models.RefundStatusFailed {
+        return fmt.Errorf("повторить можно только неудавшийся возврат (статус %s)", refund.Status)
+    }
+    lastError := refund.LastError
+    err := reserveRefund(refund.PaymentID, refund.Amount, func(tx *gorm.DB) error {
+        refund.Status = models.RefundStatusPending
+        refund.LastError = ""
+        // Условное обновление: параллельный повтор того же возврата не пройдёт
+        res := tx.Model(refund).Where("status = ?", models.RefundStatusFailed).Select("Status", "LastError", "UpdatedAt").Updates(refund)
+        if res.Error != nil {
+            return res.Error
+        }
+        if res.RowsAffected == 0 {
+            return fmt.Errorf("возврат %s уже повторяется", refund.ID)
+        }
+        return nil
+    })
+    if err != nil {
+        refund.Status = models.RefundStatusFailed
+        refund.LastError = lastError
+        return err
+    }
+
+    var payment models.Payment
+    if err := config.DB.First(&payment, "id = ?", refund.PaymentID).Error; err != nil {
+        return err
+    }
+    return processRefund(ctx, &payment, refund)
+}
+
+// processRefund отправляет возврат провайдеру и учитывает его в платеже.
+// Возврат пополнения сначала списывается с кошелька: потраченные деньги вернуть нельзя.
+func processRefund(ctx context.Context, payment *models.Payment, refund *models.Refund) error {
+    topUp := payment.Purpose == models.PaymentPurposeTopUp
+    var err error
+    if topUp {
+        err = debitTopUpRefund(payment, refund.Amount, refund.Reason)
+    }
+    if err == nil {
+        var provider PaymentProvider
+        provider, err = PaymentProviderFor(payment.Provider)
+        if err == nil {
+            err = provider.Refund(ctx, payment, refund.Amount)
+        }
+        if err != nil && topUp {
+            if rerr := revertTopUpRefund(payment, refund.Amount, "возврат не проведён провайдером"); rerr != nil {
+                log.Printf("Возврат %s: не удалось вернуть %.2f на кошелёк: %v", refund.ID, refund.Amount, rerr)
+            }
+        }
+    }
+    if err != nil {
+        refund.Status = models.RefundStatusFailed
+        refund.LastError = truncateRunes(err.Error(), 500)
+        if uerr := config.DB.Model(refund).Select("Status", "LastError", "UpdatedAt").Updates(refund).Error; uerr != nil {
+            log.Printf("Возврат %s: не удалось сохранить ошибку: %v", refund.ID, uerr)
+        }
+        return fmt.Errorf("провайдер %s не выполнил возврат: %w", payment.Provider, err)
+    }
+    return settleRefund(payment, refund)
+}
+
+// recordProviderRefund учитывает возврат, проведённый у провайдера без нашего запроса
+// (о нём сообщает уведомление со статусом refunded). refundedTotal — сколько всего
+// провайдер вернул по платежу, 0 — весь платёж. Записывается только разница с уже
+// известными возвратами, поэтому уведомление о возврате через IssueRefund его не удвоит.
+func recordProviderRefund(payment *models.Payment, refundedTotal float64) error {
+    var refund *models.Refund
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        var locked models.Payment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", payment.ID).Error; err != nil {
+            return err
+        }
+        if locked.Status == models.PaymentStatusRefunded {
+            return nil
+        }
+        if locked.Status != models.PaymentStatusSucceeded {
+            return &PaymentTransitionError{From: locked.Status, To: models.PaymentStatusRefunded}
+        }
+        if refundedTotal <= 0 || refundedTotal > locked.Amount {
+            refundedTotal = locked.Amount
+        }
+        reserved, err := reservedRefunds(tx, payment.ID)
+        if err != nil {
+            return err
+        }
+        amount := roundMoney(refundedTotal - reserved)
+        if amount < moneyEpsilon {
+            return nil
+        }
+        refund = &models.Refund{
+            PaymentID:  payment.ID,
+            PrintJobID: payment.PrintJobID,
+            Amount:     amount,
+            Status:     models.RefundStatusPending,
+            Reason:     "возврат проведён у провайдера " + payment.Provider,
+        }
+        return tx.Create(refund).Error
+    })
+    if err != nil || refund == nil {
+        return err
+    }
+
+    // Деньги уже ушли клиенту, поэтому возврат учитывается и при нехватке средств
+    // на кошельке — расхождение исправляет оператор
+    if payment.Purpose == models.PaymentPurposeTopUp {
+        if err := debitTopUpRefund(payment, refund.Amount, refund.Reason); err != nil {
+            log.Printf("Возврат %s: не удалось списать %.2f с кошелька, нужна корректировка: %v", refund.ID, refund.Amount, err)
+        }
+    }
+    return settleRefund(payment, refund)
+}
+
+// settleRefund отмечает возврат проведённым и учитывает его в платеже;
+// после возврата всей суммы платёж переходит в refunded
+func settleRefund(payment *models.Payment, refund *models.Refund) error {
+    // Сумма возвратов наращивается в БД, чтобы параллельные возвраты не затёрли друг друга
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        refund.Status = models.RefundStatusSucceeded
+        if err := tx.Model(refund).Select("Status", "UpdatedAt").Updates(refund).Error; err != nil {
+            return err
+        }
+        err := tx.Model(payment).Update("refunded_amount", gorm.Expr("refunded_amount + ?", refund.Amount)).Error
+        if err != nil {
+            return err
+        }
+        return tx.Select("refunded_amount").First(payment, "id = ?", payment.ID).Error
+    })
+    if err != nil {
+        return err
+    }
+
+    if payment.Amount-payment.RefundedAmount < moneyEpsilon {
+        return TransitionPayment(payment, models.PaymentStatusRefunded)
+    }
+    return nil
+}
+
+// refundUnprintedJob возвращает деньги и бесплатную квоту за ненапечатанную часть
+// задания, завершившегося со статусом failed или canceled. from — статус до завершения:
+// если задание уже печаталось (или его итог проверял оператор), а принтер не сообщает число оттисков, возврат
+// не делается автоматически — его проводит оператор.
+func refundUnprintedJob(job *models.PrintJob, from string) {
+    impressions := JobImpressions(job)
+    printed := 0
+    if from == models.JobStatusPrinting || from == models.JobStatusNeedsCheck {
+        if job.PagesPrinted == nil {
+            log.Printf("Задание %s прервано во время печати, принтер не сообщил число оттисков: возврат вручную", job.ID)
+            return
+        }
+        printed = *job.PagesPrinted
+    }
+    share := unprintedShare(impressions, printed)
+    if share == 0 {
+        return
+    }
+    reason := fmt.Sprintf("задание %s: не напечатано %d из %d оттисков", job.Status, impressions-printed, impressions)
+
+    if job.FreePagesUsed > 0 {
+        returnFreeQuotaPart(job, job.FreePagesUsed*(impressions-printed)/impressions)
+    }
+    if job.WalletCharge > 0 {
+        if err := refundToWallet(job, refundPart(job.WalletCharge, share, job.WalletCharge), reason); err != nil {
+            log.Printf("Задание %s: возврат на кошелёк не выполнен: %v", job.ID, err)
+        }
+    }
+
+    var payments []models.Payment
+    err := config.DB.Where("print_job_id = ? AND status = ?", job.ID, models.PaymentStatusSucceeded).
+        Order("created_at").Find(&payments).Error
+    if err != nil {
+        log.Printf("Задание %s: ошибка поиска платежей для возврата: %v", job.ID, err)
+        return
+    }
+    for i := range payments {
+        amount := refundPart(payments[i].Amount, share, RefundableAmount(&payments[i]))
+        if amount < moneyEpsilon {
+            continue
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), refundTimeout)
+        refund, err := IssueRefund(ctx, &payments[i], amount, reason)
+        cancel()
+        if err != nil {
+            log.Printf("Задание %s: возврат %.2f по платежу %s не выполнен: %v", job.ID, amount, payments[i].ID, err)
+            continue
+        }
+        log.Printf("Задание %s: возврат %s на %.2f по платежу %s", job.ID, refund.ID, amount, payments[i].ID)
+    }
+}
+
+// unprintedShare — доля ненапечатанных оттисков задания
+func unprintedShare(impressions, printed int) float64 {
+    if printed < 0 {
+        printed = 0
+    }
+    if printed >= impressions {
+        return 0
+    }
+    return float64(impressions-printed) / float64(impressions)
+}
+
+// refundPart — доля share от списанной суммы, округлённая до копеек и не больше refundable
+func refundPart(amount, share, refundable float64) float64 {
+    part := roundMoney(amount * share)
+    if part > refundable {
+        part = refundable
+    }
+    return part
+}
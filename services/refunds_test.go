@@ -0,0 +1,104 @@
+package services
+
+import (
+    "math"
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestUnprintedShare(t *testing.T) {
+    tests := []struct {
+        name        string
+        impressions int
+        printed     int
+        want        float64
+    }{
+        {"nothing printed", 10, 0, 1},
+        {"half printed", 10, 5, 0.5},
+        {"one of three", 3, 1, 2.0 / 3},
+        {"all printed", 10, 10, 0},
+        {"printer counted more", 10, 12, 0},
+        {"negative counter", 4, -1, 1},
+        {"empty job", 0, 0, 0},
+    }
+    for _, tt := range tests {
+        if got := unprintedShare(tt.impressions, tt.printed); math.Abs(got-tt.want) > 1e-9 {
+            t.Errorf("%s: unprintedShare(%d, %d) = %v, want %v", tt.name, tt.impressions, tt.printed, got, tt.want)
+        }
+    }
+}
+
+func TestRefundPart(t *testing.T) {
+    tests := []struct {
+        name       string
+        amount     float64
+        share      float64
+        refundable float64
+        want       float64
+    }{
+        {"full refund", 12.35, 1, 12.35, 12.35},
+        {"half rounded to cents", 12.35, 0.5, 12.35, 6.18},
+        {"two thirds", 10, 2.0 / 3, 10, 6.67},
+        {"capped by earlier refunds", 10, 0.5, 3, 3},
+        {"nothing left", 10, 0.5, 0, 0},
+        {"nothing unprinted", 10, 0, 10, 0},
+    }
+    for _, tt := range tests {
+        if got := refundPart(tt.amount, tt.share, tt.refundable); math.Abs(got-tt.want) >= moneyEpsilon {
+            t.Errorf("%s: refundPart(%.2f, %v, %.2f) = %.2f, want %.2f", tt.name, tt.amount, tt.share, tt.refundable, got, tt.want)
+        }
+    }
+}
+
+// Кошелёк пополнен, задание оплачено с кошелька, за ненапечатанную часть сделан возврат:
+// остатки счетов считаются по проводкам так же, как accountBalance
+func TestLedgerBalanceAfterChargeAndRefund(t *testing.T) {
+    user := models.UserAccount("u1")
+    tests := []struct {
+        name        string
+        cost        float64
+        impressions int
+        printed     int
+        wantWallet  float64
+        wantRevenue float64
+    }{
+        {"nothing printed", 12.35, 10, 0, 100, 0},
+        {"half printed", 12.35, 10, 5, 93.83, 6.17},
+        {"one of three printed", 10, 3, 1, 96.67, 3.33},
+        {"all printed", 12.35, 10, 10, 87.65, 12.35},
+    }
+    for _, tt := range tests {
+        var entries []models.LedgerEntry
+        post := func(kind string, legs []LedgerLeg) {
+            if math.Abs(legs[0].Amount) < moneyEpsilon {
+                return
+            }
+            e, err := ledgerEntries(ledgerOperation{Kind: kind}, legs)
+            if err != nil {
+                t.Fatalf("%s: %s: %v", tt.name, kind, err)
+            }
+            entries = append(entries, e...)
+        }
+        post(models.LedgerKindTopUp, []LedgerLeg{{user, 100}, {models.LedgerAccountCash, -100}})
+        post(models.LedgerKindJobCharge, jobChargeLegs("u1", tt.cost))
+        refund := refundPart(tt.cost, unprintedShare(tt.impressions, tt.printed), tt.cost)
+        post(models.LedgerKindRefund, jobChargeLegs("u1", -refund))
+
+        balances := map[string]float64{}
+        total := 0.0
+        for _, e := range entries {
+            balances[e.Account] += e.Amount
+            total += e.Amount
+        }
+        if got := roundMoney(balances[user]); math.Abs(got-tt.wantWallet) >= moneyEpsilon {
+            t.Errorf("%s: wallet balance %.2f, want %.2f", tt.name, got, tt.wantWallet)
+        }
+        if got := roundMoney(balances[models.LedgerAccountRevenue]); math.Abs(got-tt.wantRevenue) >= moneyEpsilon {
+            t.Errorf("%s: revenue %.2f, want %.2f", tt.name, got, tt.wantRevenue)
+        }
+        if math.Abs(total) >= moneyEpsilon {
+            t.Errorf("%s: ledger does not balance: %.2f", tt.name, total)
+        }
+    }
+}
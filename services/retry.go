@@ -0,0 +1,115 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "math/rand"
+    "net"
+    "os"
+    "syscall"
+    "time"
+)
+
+// RetryPolicy — правила повторной отправки задания после ошибки
+type RetryPolicy struct {
+    // MaxAttempts — сколько всего попыток отправки делается для задания
+    MaxAttempts int
+    // BaseDelay удваивается с каждой попыткой, но не превышает MaxDelay
+    BaseDelay time.Duration
+    MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy используется, если параметры не заданы
+var DefaultRetryPolicy = RetryPolicy{
+    MaxAttempts: 5,
+    BaseDelay:   10 * time.Second,
+    MaxDelay:    10 * time.Minute,
+}
+
+// Backoff возвращает паузу перед следующей попыткой (attempt — номер неудавшейся попытки, с 1).
+// Половина паузы фиксирована, половина случайна, чтобы задания не повторялись синхронно.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+    if attempt < 1 {
+        attempt = 1
+    }
+    delay := p.BaseDelay
+    for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+        delay *= 2
+    }
+    if delay > p.MaxDelay {
+        delay = p.MaxDelay
+    }
+    if delay <= 0 {
+        return 0
+    }
+    half := delay / 2
+    return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// PermanentError — ошибка, повтор которой не имеет смысла
+type PermanentError struct {
+    Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent помечает ошибку как неустранимую повтором
+func Permanent(err error) error {
+    if err == nil {
+        return nil
+    }
+    return &PermanentError{Err: err}
+}
+
+// HTTPStatusError — ответ сервера документа с ошибочным HTTP-кодом
+type HTTPStatusError struct {
+    URL  string
+    Code int
+}
+
+func (e *HTTPStatusError) Error() string {
+    return fmt.Sprintf("сервер [%s] вернул HTTP %d", e.URL, e.Code)
+}
+
+// IsTransientSendError решает, стоит ли повторить отправку после ошибки
+func IsTransientSendError(err error) bool {
+    if err == nil {
+        return false
+    }
+
+    var permanent *PermanentError
+    if errors.As(err, &permanent) {
+        return false
+    }
+
+    // Ответ IPP: 0x05xx (занят, недоступен) — временная ошибка, 0x04xx — ошибка запроса
+    var ippErr *IPPStatusError
+    if errors.As(err, &ippErr) {
+        return !ippErr.IsClientError()
+    }
+
+    var httpErr *HTTPStatusError
+    if errors.As(err, &httpErr) {
+        return httpErr.Code >= 500 || httpErr.Code == 429
+    }
+
+    // Отсутствующий файл повтором не исправить
+    if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+        return false
+    }
+
+    // Сеть: отказ в соединении, таймаут, обрыв посреди передачи
+    var netErr net.Error
+    if errors.As(err, &netErr) {
+        return true
+    }
+    if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+        errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.EHOSTUNREACH) ||
+        errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+        return true
+    }
+
+    return false
+}
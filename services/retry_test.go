@@ -0,0 +1,66 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "syscall"
+    "testing"
+    "time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+    policy := RetryPolicy{BaseDelay: 10 * time.Second, MaxDelay: time.Minute}
+    tests := []struct {
+        attempt int
+        ceiling time.Duration
+    }{
+        {0, 10 * time.Second},
+        {1, 10 * time.Second},
+        {2, 20 * time.Second},
+        {3, 40 * time.Second},
+        {4, time.Minute},
+        {20, time.Minute},
+    }
+    for _, tt := range tests {
+        // Половина паузы случайна — проверяем границы на нескольких розыгрышах
+        for i := 0; i < 50; i++ {
+            got := policy.Backoff(tt.attempt)
+            if got < tt.ceiling/2 || got > tt.ceiling {
+                t.Fatalf("Backoff(%d) = %s, want within [%s, %s]", tt.attempt, got, tt.ceiling/2, tt.ceiling)
+            }
+        }
+    }
+
+    if got := (RetryPolicy{}).Backoff(3); got != 0 {
+        t.Errorf("zero policy Backoff = %s, want 0", got)
+    }
+}
+
+func TestIsTransientSendError(t *testing.T) {
+    tests := []struct {
+        name string
+        err  error
+        want bool
+    }{
+        {"nil", nil, false},
+        {"permanent", Permanent(errors.New("у задания нет документа")), false},
+        {"wrapped permanent", fmt.Errorf("отправка: %w", Permanent(io.EOF)), false},
+        {"IPP server busy", &IPPStatusError{Code: 0x0507}, true},
+        {"IPP bad request", &IPPStatusError{Code: 0x0400}, false},
+        {"HTTP 503", &HTTPStatusError{Code: 503}, true},
+        {"HTTP 429", &HTTPStatusError{Code: 429}, true},
+        {"HTTP 404", &HTTPStatusError{Code: 404}, false},
+        {"missing file", fmt.Errorf("открытие: %w", os.ErrNotExist), false},
+        {"connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+        {"connection reset", syscall.ECONNRESET, true},
+        {"unexpected EOF", io.ErrUnexpectedEOF, true},
+        {"other", errors.New("неизвестная ошибка"), false},
+    }
+    for _, tt := range tests {
+        if got := IsTransientSendError(tt.err); got != tt.want {
+            t.Errorf("%s: IsTransientSendError = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// MaxJobDeferral — насколько вперёд можно отложить печать задания
+const MaxJobDeferral = 30 * 24 * time.Hour
+
+// DefaultPriorityAging — за какое ожидание в очереди задание поднимается на уровень
+const DefaultPriorityAging = 15 * time.Minute
+
+// ErrNotReschedulable — задание уже передаётся на принтер или завершено
+var ErrNotReschedulable = errors.New("приоритет и время печати можно менять только у ожидающего задания")
+
+// schedulableStatuses — статусы, в которых задание ещё не ушло на принтер
+var schedulableStatuses = []string{
+    models.JobStatusCreated,
+    models.JobStatusAwaitingPayment,
+    models.JobStatusQueued,
+    models.JobStatusHeld,
+}
+
+// IsJobPriority сообщает, что уровень приоритета известен
+func IsJobPriority(priority int) bool {
+    return priority >= models.JobPriorityLow && priority <= models.JobPriorityUrgent
+}
+
+// NormalizeJobSchedule проверяет приоритет и отложенное время задания.
+// Пустой приоритет — обычный; срочный разрешён только при canExpedite.
+// Время в прошлом означает «печатать сразу».
+func NormalizeJobSchedule(job *models.PrintJob, canExpedite bool) error {
+    if job.Priority == 0 {
+        job.Priority = models.JobPriorityNormal
+    }
+    if !IsJobPriority(job.Priority) {
+        return &JobOptionsError{Field: "priority", Message: fmt.Sprintf("приоритет должен быть от %d до %d", models.JobPriorityLow, models.JobPriorityUrgent)}
+    }
+    if job.Priority == models.JobPriorityUrgent && !canExpedite {
+        return &JobOptionsError{Field: "priority", Message: "срочный приоритет назначает оператор"}
+    }
+
+    if job.NotBefore != nil {
+        now := time.Now()
+        if !job.NotBefore.After(now) {
+            job.NotBefore = nil
+        } else if job.NotBefore.After(now.Add(MaxJobDeferral)) {
+            return &JobOptionsError{Field: "not_before", Message: fmt.Sprintf("печать можно отложить не более чем на %d дней", int(MaxJobDeferral/(24*time.Hour)))}
+        }
+    }
+    return nil
+}
+
+// RescheduleJob меняет приоритет и время печати ожидающего задания и записывает
+// это в историю. Обновление условное: задание, которое успели взять в печать,
+// не меняется.
+func RescheduleJob(job *models.PrintJob, priority int, notBefore *time.Time, actor, reason string) error {
+    prevPriority, prevNotBefore := job.Priority, job.NotBefore
+    job.Priority = priority
+    job.NotBefore = notBefore
+    if err := NormalizeJobSchedule(job, true); err != nil {
+        job.Priority, job.NotBefore = prevPriority, prevNotBefore
+        return err
+    }
+
+    note := fmt.Sprintf("приоритет %d → %d", prevPriority, job.Priority)
+    if job.NotBefore != nil {
+        note += ", печать не раньше " + job.NotBefore.Format(time.RFC3339)
+    }
+    if reason != "" {
+        note += ": " + reason
+    }
+
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        res := tx.Model(job).
+            Where("status IN ?", schedulableStatuses).
+            Select("Priority", "NotBefore", "UpdatedAt").
+            Updates(job)
+        if res.Error != nil {
+            return res.Error
+        }
+        if res.RowsAffected == 0 {
+            return ErrNotReschedulable
+        }
+        return RecordJobEvent(tx, job.ID, job.Status, job.Status, actor, note)
+    })
+    if err != nil {
+        job.Priority, job.NotBefore = prevPriority, prevNotBefore
+        return err
+    }
+    if printQueue != nil {
+        printQueue.notify()
+    }
+    return nil
+}
+
+// waitingSince — с какого момента задание ждёт печати: отложенное начинает
+// ожидание с NotBefore, а не с постановки в очередь
+func waitingSince(job *models.PrintJob) time.Time {
+    var since time.Time
+    if job.QueuedAt != nil {
+        since = *job.QueuedAt
+    }
+    if job.NotBefore != nil && job.NotBefore.After(since) {
+        since = *job.NotBefore
+    }
+    return since
+}
+
+// effectivePriority — приоритет с учётом ожидания: каждые aging в очереди
+// поднимают задание на уровень (не выше срочного), чтобы низкоприоритетные
+// задания не ждали бесконечно
+func effectivePriority(job *models.PrintJob, now time.Time, aging time.Duration) int {
+    priority := job.Priority
+    if aging > 0 && priority < models.JobPriorityUrgent {
+        if waited := now.Sub(waitingSince(job)); waited > 0 {
+            priority += int(waited / aging)
+        }
+        if priority > models.JobPriorityUrgent {
+            priority = models.JobPriorityUrgent
+        }
+    }
+    return priority
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+    "errors"
+    "testing"
+    "time"
+
+    "print-automation/models"
+)
+
+func TestNormalizeJobSchedule(t *testing.T) {
+    now := time.Now()
+    past := now.Add(-time.Hour)
+    soon := now.Add(time.Hour)
+    tooLate := now.Add(MaxJobDeferral + time.Hour)
+
+    tests := []struct {
+        name        string
+        job         models.PrintJob
+        canExpedite bool
+        priority    int
+        deferred    bool
+        errField    string
+    }{
+        {name: "defaults to normal", job: models.PrintJob{}, priority: models.JobPriorityNormal},
+        {name: "low", job: models.PrintJob{Priority: models.JobPriorityLow}, priority: models.JobPriorityLow},
+        {name: "high", job: models.PrintJob{Priority: models.JobPriorityHigh}, priority: models.JobPriorityHigh},
+        {name: "urgent by an operator", job: models.PrintJob{Priority: models.JobPriorityUrgent}, canExpedite: true, priority: models.JobPriorityUrgent},
+        {name: "urgent by a customer", job: models.PrintJob{Priority: models.JobPriorityUrgent}, errField: "priority"},
+        {name: "unknown level", job: models.PrintJob{Priority: 9}, canExpedite: true, errField: "priority"},
+        {name: "negative level", job: models.PrintJob{Priority: -1}, errField: "priority"},
+        {name: "time in the past prints now", job: models.PrintJob{NotBefore: &past}, priority: models.JobPriorityNormal},
+        {name: "deferred", job: models.PrintJob{NotBefore: &soon}, priority: models.JobPriorityNormal, deferred: true},
+        {name: "deferred too far", job: models.PrintJob{NotBefore: &tooLate}, errField: "not_before"},
+    }
+    for _, tt := range tests {
+        err := NormalizeJobSchedule(&tt.job, tt.canExpedite)
+        if tt.errField != "" {
+            var optErr *JobOptionsError
+            if !errors.As(err, &optErr) || optErr.Field != tt.errField {
+                t.Errorf("%s: err = %v, want a %s error", tt.name, err, tt.errField)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("%s: %v", tt.name, err)
+            continue
+        }
+        if tt.job.Priority != tt.priority || (tt.job.NotBefore != nil) != tt.deferred {
+            t.Errorf("%s: priority %d, not_before %v; want %d, deferred %v", tt.name, tt.job.Priority, tt.job.NotBefore, tt.priority, tt.deferred)
+        }
+    }
+}
+
+func TestWaitingSince(t *testing.T) {
+    queued := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+    earlier := queued.Add(-time.Hour)
+    later := queued.Add(time.Hour)
+
+    tests := []struct {
+        name string
+        job  models.PrintJob
+        want time.Time
+    }{
+        {"queued", models.PrintJob{QueuedAt: &queued}, queued},
+        {"deferred past queueing", models.PrintJob{QueuedAt: &queued, NotBefore: &later}, later},
+        {"deferral already passed", models.PrintJob{QueuedAt: &queued, NotBefore: &earlier}, queued},
+        {"not queued yet", models.PrintJob{NotBefore: &later}, later},
+    }
+    for _, tt := range tests {
+        if got := waitingSince(&tt.job); !got.Equal(tt.want) {
+            t.Errorf("%s: waitingSince = %s, want %s", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestEffectivePriority(t *testing.T) {
+    now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+    ago := func(d time.Duration) *time.Time {
+        at := now.Add(-d)
+        return &at
+    }
+    aging := 15 * time.Minute
+
+    tests := []struct {
+        name  string
+        job   models.PrintJob
+        aging time.Duration
+        want  int
+    }{
+        {"just queued", models.PrintJob{Priority: models.JobPriorityLow, QueuedAt: ago(0)}, aging, models.JobPriorityLow},
+        {"under one step", models.PrintJob{Priority: models.JobPriorityLow, QueuedAt: ago(14 * time.Minute)}, aging, models.JobPriorityLow},
+        {"one step", models.PrintJob{Priority: models.JobPriorityLow, QueuedAt: ago(15 * time.Minute)}, aging, models.JobPriorityNormal},
+        {"two steps", models.PrintJob{Priority: models.JobPriorityLow, QueuedAt: ago(31 * time.Minute)}, aging, models.JobPriorityHigh},
+        {"capped at urgent", models.PrintJob{Priority: models.JobPriorityNormal, QueuedAt: ago(24 * time.Hour)}, aging, models.JobPriorityUrgent},
+        {"aging disabled", models.PrintJob{Priority: models.JobPriorityLow, QueuedAt: ago(24 * time.Hour)}, 0, models.JobPriorityLow},
+        {"deferred job ages from not_before", models.PrintJob{Priority: models.JobPriorityLow, QueuedAt: ago(2 * time.Hour), NotBefore: ago(10 * time.Minute)}, aging, models.JobPriorityLow},
+        {"not_before in the future", models.PrintJob{Priority: models.JobPriorityHigh, QueuedAt: ago(time.Hour), NotBefore: ago(-time.Hour)}, aging, models.JobPriorityHigh},
+    }
+    for _, tt := range tests {
+        if got := effectivePriority(&tt.job, now, tt.aging); got != tt.want {
+            t.Errorf("%s: effectivePriority = %d, want %d", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "gorm.io/gorm"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+var (
+    // ErrReleaseCodeInvalid — PIN не подходит ни к одному удержанному заданию
+    ErrReleaseCodeInvalid = errors.New("неверный или просроченный PIN-код")
+    // ErrKioskTokenInvalid — QR-код киоска подделан или устарел
+    ErrKioskTokenInvalid = errors.New("QR-код недействителен или устарел, обновите страницу киоска")
+    // ErrTooManyReleaseAttempts — слишком много неверных PIN-кодов на принтере
+    ErrTooManyReleaseAttempts = errors.New("слишком много неверных попыток, повторите позже")
+    // ErrNotReleasable — задание не ждёт выпуска у принтера
+    ErrNotReleasable = errors.New("задание не ожидает выпуска у принтера")
+)
+
+const (
+    // releasePINDigits — длина одноразового PIN-кода
+    releasePINDigits = 8
+    // kioskTokenTTL — срок действия QR-кода на странице киоска
+    kioskTokenTTL = 2 * time.Minute
+    // За releaseFailureWindow допускается не больше releaseMaxFailures неверных PIN
+    // на киоске принтера (у принтера один ключ киоска). Сумма по всем киоскам
+    // не блокирует выпуск — на releaseAlertFailures пишется предупреждение.
+    releaseMaxFailures   = 5
+    releaseAlertFailures = 50
+    releaseFailureWindow = 5 * time.Minute
+)
+
+// secureReleaseTTL — сколько задание ждёт выпуска; потом оно отменяется с возвратом оплаты
+func secureReleaseTTL() time.Duration {
+    if d, err := time.ParseDuration(os.Getenv("SECURE_RELEASE_TTL")); err == nil && d > 0 {
+        return d
+    }
+    return 24 * time.Hour
+}
+
+// holdForRelease удерживает оплаченное задание до выпуска владельцем у принтера
+func holdForRelease(job *models.PrintJob, actor, reason string) error {
+    expires := time.Now().Add(secureReleaseTTL())
+    prevExpires := job.ReleaseExpiresAt
+    job.ReleaseExpiresAt = &expires
+    err := TransitionJob(job, models.JobStatusHeld, actor, reason+"; ожидает выпуска у принтера", "ReleaseExpiresAt")
+    if err != nil {
+        job.ReleaseExpiresAt = prevExpires
+    }
+    return err
+}
+
+// isAwaitingRelease сообщает, что задание удержано до выпуска владельцем
+func isAwaitingRelease(job *models.PrintJob) bool {
+    return job.SecureRelease && job.Status == models.JobStatusHeld && job.ReleaseExpiresAt != nil
+}
+
+// IssueReleasePIN выдаёт новый одноразовый PIN для удержанного задания.
+// Прежний PIN перестаёт действовать; в базе хранится только его HMAC.
+func IssueReleasePIN(job *models.PrintJob) (string, error) {
+    if !isAwaitingRelease(job) {
+        return "", ErrNotReleasable
+    }
+
+    // PIN должен однозначно указывать на задание среди удержанных
+    for attempt := 0; attempt < 10; attempt++ {
+        pin, err := randomPIN()
+        if err != nil {
+            return "", err
+        }
+        hash := releaseCodeHash(pin)
+        var count int64
+        err = config.DB.Model(&models.PrintJob{}).
+            Where("release_code_hash = ? AND status = ?", hash, models.JobStatusHeld).
+            Count(&count).Error
+        if err != nil {
+            return "", err
+        }
+        if count > 0 {
+            continue
+        }
+        res := config.DB.Model(job).Where("status = ?", models.JobStatusHeld).Update("release_code_hash", hash)
+        if res.Error != nil {
+            return "", res.Error
+        }
+        if res.RowsAffected == 0 {
+            return "", ErrNotReleasable
+        }
+        job.ReleaseCodeHash = hash
+        return pin, nil
+    }
+    return "", errors.New("не удалось подобрать уникальный PIN-код")
+}
+
+// ReleaseByPIN выпускает задание по PIN-коду на принтер, у которого стоит владелец.
+// PIN действует только на принтере, которому адресовано задание, и на принтерах
+// того же пула: угаданный код не выпустит чужое задание в другом месте.
+func ReleaseByPIN(printer models.Printer, pin string) (*models.PrintJob, error) {
+    allowed, err := releaseAllowed(printer.ID)
+    if err != nil {
+        return nil, err
+    }
+    if !allowed {
+        return nil, ErrTooManyReleaseAttempts
+    }
+
+    pin = strings.TrimSpace(pin)
+    var job models.PrintJob
+    err = releasableAt(printer).
+        Where("release_code_hash = ? AND status = ? AND release_expires_at > ?", releaseCodeHash(pin), models.JobStatusHeld, time.Now()).
+        First(&job).Error
+    if err != nil || len(pin) != releasePINDigits {
+        recordReleaseFailure(printer.ID)
+        return nil, ErrReleaseCodeInvalid
+    }
+
+    if err := dispatchToPrinter(&job, printer, ReleaseActor(printer.ID), "выпущено PIN-кодом"); err != nil {
+        return nil, err
+    }
+    return &job, nil
+}
+
+// releasableAt ограничивает задания теми, что можно выпустить PIN-кодом на принтере:
+// адресованные ему, его пулу или другому принтеру того же пула
+func releasableAt(printer models.Printer) *gorm.DB {
+    if printer.Pool == "" {
+        return config.DB.Where("printer_id = ?", printer.ID)
+    }
+    poolPrinters := config.DB.Model(&models.Printer{}).Select("id").Where("pool = ?", printer.Pool)
+    return config.DB.Where("printer_id = ? OR pool = ? OR printer_id IN (?)", printer.ID, printer.Pool, poolPrinters)
+}
+
+// releaseAllowed проверяет ограничение подбора PIN-кодов на киоске принтера.
+// Неверные попытки хранятся в базе, поэтому лимит переживает перезапуск и общий
+// для всех экземпляров сервера. Подбор на одном киоске не закрывает выпуск на других.
+func releaseAllowed(printerID string) (bool, error) {
+    var local int64
+    err := config.DB.Model(&models.ReleaseFailure{}).
+        Where("printer_id = ? AND created_at > ?", printerID, time.Now().Add(-releaseFailureWindow)).
+        Count(&local).Error
+    if err != nil {
+        return false, err
+    }
+    return local < releaseMaxFailures, nil
+}
+
+// recordReleaseFailure записывает неверный PIN и предупреждает, когда неверных
+// попыток на всех киосках вместе становится подозрительно много
+func recordReleaseFailure(printerID string) {
+    if err := config.DB.Create(&models.ReleaseFailure{PrinterID: printerID}).Error; err != nil {
+        log.Printf("Выпуск на принтере %s: не удалось записать неверную попытку: %v", printerID, err)
+        return
+    }
+    var total int64
+    err := config.DB.Model(&models.ReleaseFailure{}).Where("created_at > ?", time.Now().Add(-releaseFailureWindow)).Count(&total).Error
+    if err == nil && total == releaseAlertFailures {
+        log.Printf("Выпуск у принтера: %d неверных PIN-кодов на всех киосках за %s — возможен подбор", total, releaseFailureWindow)
+    }
+}
+
+// purgeReleaseFailures удаляет неверные попытки, вышедшие за окно ограничения
+func purgeReleaseFailures() {
+    err := config.DB.Where("created_at < ?", time.Now().Add(-releaseFailureWindow)).Delete(&models.ReleaseFailure{}).Error
+    if err != nil {
+        log.Printf("Выпуск у принтера: ошибка очистки неверных попыток: %v", err)
+    }
+}
+
+// ReleaseForUser выпускает удержанные задания пользователя (или одно jobID)
+// на принтер, QR-код которого он отсканировал
+func ReleaseForUser(printer models.Printer, userID, jobID string) ([]models.PrintJob, error) {
+    query := config.DB.
+        Where("user_id = ? AND secure_release = ? AND status = ? AND release_expires_at > ?", userID, true, models.JobStatusHeld, time.Now()).
+        Order("created_at")
+    if jobID != "" {
+        query = query.Where("id = ?", jobID)
+    }
+    var jobs []models.PrintJob
+    if err := query.Find(&jobs).Error; err != nil {
+        return nil, err
+    }
+    if len(jobs) == 0 {
+        return nil, ErrNotReleasable
+    }
+
+    released := make([]models.PrintJob, 0, len(jobs))
+    var errs []error
+    for i := range jobs {
+        if err := dispatchToPrinter(&jobs[i], printer, UserActor(userID), "выпущено по QR-коду"); err != nil {
+            errs = append(errs, fmt.Errorf("задание %s: %w", jobs[i].ID, err))
+            continue
+        }
+        released = append(released, jobs[i])
+    }
+    if len(released) == 0 {
+        return nil, errors.Join(errs...)
+    }
+    for _, err := range errs {
+        log.Printf("Выпуск на принтере %s: %v", printer.ID, err)
+    }
+    return released, nil
+}
+
+// dispatchToPrinter переносит удержанное задание на принтер выпуска и ставит его в очередь.
+// Принтер должен поддерживать параметры задания, а печать на нём — стоить не дороже оплаченной.
+func dispatchToPrinter(job *models.PrintJob, printer models.Printer, actor, reason string) error {
+    if !isAwaitingRelease(job) {
+        return ErrNotReleasable
+    }
+
+    if err := checkPrinterSwitch(job, printer, true); err != nil {
+        return err
+    }
+
+    // Задание печатается там, где стоит владелец, даже если было адресовано пулу
+    prevPrinter, prevPool, prevHash, prevExpires := job.PrinterID, job.Pool, job.ReleaseCodeHash, job.ReleaseExpiresAt
+    job.PrinterID = printer.ID
+    job.Pool = ""
+    job.ReleaseCodeHash = ""
+    job.ReleaseExpiresAt = nil
+    err := EnqueuePrintJob(job, actor, reason+" на принтере "+printer.Name, "PrinterID", "Pool", "ReleaseCodeHash", "ReleaseExpiresAt")
+    if err != nil {
+        job.PrinterID, job.Pool, job.ReleaseCodeHash, job.ReleaseExpiresAt = prevPrinter, prevPool, prevHash, prevExpires
+        return err
+    }
+    return nil
+}
+
+// CancelExpiredReleases отменяет задания, которые не выпустили вовремя (оплата
+// возвращается), и забывает старые неверные попытки ввода PIN
+func CancelExpiredReleases() {
+    purgeReleaseFailures()
+
+    var jobs []models.PrintJob
+    err := config.DB.
+        Where("secure_release = ? AND status = ? AND release_expires_at < ?", true, models.JobStatusHeld, time.Now()).
+        Find(&jobs).Error
+    if err != nil {
+        log.Printf("Выпуск у принтера: ошибка поиска просроченных заданий: %v", err)
+        return
+    }
+    for i := range jobs {
+        if err := TransitionJob(&jobs[i], models.JobStatusCanceled, ActorSystem, "задание не выпущено у принтера вовремя"); err != nil {
+            log.Printf("Выпуск у принтера: задание %s не отменено: %v", jobs[i].ID, err)
+        }
+    }
+}
+
+// ReleaseActor — инициатор выпуска задания на киоске принтера
+func ReleaseActor(printerID string) string {
+    return "kiosk:" + printerID
+}
+
+// KioskToken выдаёт содержимое QR-кода для страницы киоска принтера:
+// "<printer id>.<срок>.<подпись>", действует kioskTokenTTL
+func KioskToken(printerID string) (string, time.Time) {
+    expires := time.Now().Add(kioskTokenTTL)
+    payload := printerID + "." + strconv.FormatInt(expires.Unix(), 10)
+    return payload + "." + hex.EncodeToString(kioskSignature(payload)), expires
+}
+
+// VerifyKioskToken проверяет QR-код киоска и возвращает идентификатор принтера
+func VerifyKioskToken(token string) (string, error) {
+    i := strings.LastIndex(token, ".")
+    if i < 0 {
+        return "", ErrKioskTokenInvalid
+    }
+    payload, sig := token[:i], token[i+1:]
+    expected := hex.EncodeToString(kioskSignature(payload))
+    if !hmac.Equal([]byte(sig), []byte(expected)) {
+        return "", ErrKioskTokenInvalid
+    }
+    j := strings.LastIndex(payload, ".")
+    if j < 0 {
+        return "", ErrKioskTokenInvalid
+    }
+    expires, err := strconv.ParseInt(payload[j+1:], 10, 64)
+    if err != nil || time.Now().Unix() > expires {
+        return "", ErrKioskTokenInvalid
+    }
+    return payload[:j], nil
+}
+
+func kioskSignature(payload string) []byte {
+    mac := hmac.New(sha256.New, derivedKey("kiosk"))
+    mac.Write([]byte(payload))
+    return mac.Sum(nil)
+}
+
+// releaseCodeHash — HMAC PIN-кода: по нему задание находится без хранения самого PIN
+func releaseCodeHash(pin string) string {
+    mac := hmac.New(sha256.New, derivedKey("pin"))
+    mac.Write([]byte(pin))
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomPIN() (string, error) {
+    max := big.NewInt(1)
+    for i := 0; i < releasePINDigits; i++ {
+        max.Mul(max, big.NewInt(10))
+    }
+    n, err := rand.Int(rand.Reader, max)
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%0*d", releasePINDigits, n.Int64()), nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+    "encoding/hex"
+    "errors"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "print-automation/models"
+)
+
+// testKioskToken подписывает QR-код с произвольным сроком
+func testKioskToken(printerID string, expires time.Time) string {
+    payload := printerID + "." + strconv.FormatInt(expires.Unix(), 10)
+    return payload + "." + hex.EncodeToString(kioskSignature(payload))
+}
+
+func TestVerifyKioskToken(t *testing.T) {
+    withJWTSecret(t, "test-secret")
+    valid, expires := KioskToken("printer-1")
+    if d := time.Until(expires); d <= 0 || d > kioskTokenTTL {
+        t.Errorf("token expires in %s, want within %s", d, kioskTokenTTL)
+    }
+    jwtSecret = []byte("other-secret")
+    foreign, _ := KioskToken("printer-1")
+    jwtSecret = []byte("test-secret")
+    extended := strings.Split(valid, ".")
+    extended[1] = "9999999999"
+
+    tests := []struct {
+        name    string
+        token   string
+        printer string
+    }{
+        {"valid", valid, "printer-1"},
+        {"printer id with dots", testKioskToken("lab.floor2.p1", time.Now().Add(time.Minute)), "lab.floor2.p1"},
+        {"expired", testKioskToken("printer-1", time.Now().Add(-time.Second)), ""},
+        {"another printer", strings.Replace(valid, "printer-1", "printer-2", 1), ""},
+        {"expiry extended", strings.Join(extended, "."), ""},
+        {"signed with another key", foreign, ""},
+        {"no signature", "printer-1", ""},
+        {"no expiry", "printer-1." + hex.EncodeToString(kioskSignature("printer-1")), ""},
+        {"empty", "", ""},
+    }
+    for _, tt := range tests {
+        printer, err := VerifyKioskToken(tt.token)
+        if tt.printer == "" {
+            if !errors.Is(err, ErrKioskTokenInvalid) {
+                t.Errorf("%s: got %q, %v; want ErrKioskTokenInvalid", tt.name, printer, err)
+            }
+            continue
+        }
+        if err != nil || printer != tt.printer {
+            t.Errorf("%s: got %q, %v; want %q", tt.name, printer, err, tt.printer)
+        }
+    }
+}
+
+func TestRandomPIN(t *testing.T) {
+    seen := map[string]bool{}
+    for i := 0; i < 200; i++ {
+        pin, err := randomPIN()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if len(pin) != releasePINDigits || strings.Trim(pin, "0123456789") != "" {
+            t.Fatalf("randomPIN() = %q, want %d digits", pin, releasePINDigits)
+        }
+        seen[pin] = true
+    }
+    // 200 восьмизначных кодов почти наверняка различны
+    if len(seen) < 199 {
+        t.Errorf("only %d distinct PINs out of 200", len(seen))
+    }
+}
+
+func TestReleaseCodeHash(t *testing.T) {
+    withJWTSecret(t, "test-secret")
+    a := releaseCodeHash("12345678")
+    if a != releaseCodeHash("12345678") {
+        t.Error("hash is not deterministic")
+    }
+    if a == releaseCodeHash("12345679") {
+        t.Error("different PINs share a hash")
+    }
+    if strings.Contains(a, "12345678") || len(a) != 64 {
+        t.Errorf("unexpected hash %q", a)
+    }
+    // HMAC PIN-кода и подпись QR-кода не должны совпадать для одинаковых строк
+    if a == hex.EncodeToString(kioskSignature("12345678")) {
+        t.Error("release code hash collides with the kiosk signature")
+    }
+    jwtSecret = []byte("other-secret")
+    if a == releaseCodeHash("12345678") {
+        t.Error("hash does not depend on the server key")
+    }
+}
+
+func TestKioskKeyHash(t *testing.T) {
+    // SHA-256("abc"), FIPS 180-2
+    if got := kioskKeyHash("abc"); got != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+        t.Errorf("kioskKeyHash(abc) = %s", got)
+    }
+    if kioskKeyHash("a") == kioskKeyHash("b") {
+        t.Error("different keys share a hash")
+    }
+}
+
+func TestIsAwaitingRelease(t *testing.T) {
+    expires := time.Now().Add(time.Hour)
+    tests := []struct {
+        name string
+        job  models.PrintJob
+        want bool
+    }{
+        {"held for release", models.PrintJob{SecureRelease: true, Status: models.JobStatusHeld, ReleaseExpiresAt: &expires}, true},
+        {"held by an operator", models.PrintJob{Status: models.JobStatusHeld}, false},
+        {"released", models.PrintJob{SecureRelease: true, Status: models.JobStatusQueued, ReleaseExpiresAt: &expires}, false},
+        {"not yet held", models.PrintJob{SecureRelease: true, Status: models.JobStatusCreated}, false},
+    }
+    for _, tt := range tests {
+        if got := isAwaitingRelease(&tt.job); got != tt.want {
+            t.Errorf("%s: isAwaitingRelease = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
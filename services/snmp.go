@@ -0,0 +1,445 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "net"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Версии SNMP (значение поля version в сообщении)
+const (
+    SNMPv1  = 0
+    SNMPv2c = 1
+)
+
+// DefaultSNMPPort — стандартный порт агента SNMP
+const DefaultSNMPPort = 161
+
+// Теги BER/ASN.1, используемые SNMP
+const (
+    berInteger     = 0x02
+    berOctetString = 0x04
+    berNull        = 0x05
+    berOID         = 0x06
+    berSequence    = 0x30
+
+    snmpIPAddress = 0x40
+    snmpCounter32 = 0x41
+    snmpGauge32   = 0x42
+    snmpTimeTicks = 0x43
+    snmpOpaque    = 0x44
+    snmpCounter64 = 0x46
+
+    snmpNoSuchObject   = 0x80
+    snmpNoSuchInstance = 0x81
+    snmpEndOfMibView   = 0x82
+
+    snmpGetRequest     = 0xA0
+    snmpGetNextRequest = 0xA1
+    snmpResponse       = 0xA2
+)
+
+// ErrSNMPNoSuchName — агент не знает запрошенный OID
+var ErrSNMPNoSuchName = errors.New("SNMP: объект отсутствует")
+
+// SNMPError — ненулевой error-status в ответе агента
+type SNMPError struct {
+    Status int
+    Index  int
+}
+
+func (e *SNMPError) Error() string {
+    return fmt.Sprintf("SNMP: агент вернул ошибку %d (переменная %d)", e.Status, e.Index)
+}
+
+// Is сопоставляет noSuchName (2) из SNMPv1 с ErrSNMPNoSuchName
+func (e *SNMPError) Is(target error) bool {
+    return target == ErrSNMPNoSuchName && e.Status == 2
+}
+
+// SNMPVarBind — пара OID/значение из ответа агента
+type SNMPVarBind struct {
+    OID   string
+    Type  byte
+    Value interface{}
+}
+
+// Exists сообщает, что агент вернул значение, а не noSuchObject/endOfMibView
+func (v SNMPVarBind) Exists() bool {
+    return v.Type != snmpNoSuchObject && v.Type != snmpNoSuchInstance && v.Type != snmpEndOfMibView && v.Type != berNull
+}
+
+// Int возвращает числовое значение (INTEGER, Counter, Gauge, TimeTicks)
+func (v SNMPVarBind) Int() (int64, bool) {
+    switch n := v.Value.(type) {
+    case int64:
+        return n, true
+    case uint64:
+        return int64(n), true
+    }
+    return 0, false
+}
+
+// Bytes возвращает значение OCTET STRING
+func (v SNMPVarBind) Bytes() []byte {
+    b, _ := v.Value.([]byte)
+    return b
+}
+
+// String возвращает значение как текст
+func (v SNMPVarBind) String() string {
+    switch val := v.Value.(type) {
+    case []byte:
+        return strings.TrimRight(string(val), "\x00")
+    case nil:
+        return ""
+    default:
+        return fmt.Sprint(val)
+    }
+}
+
+// SNMPClient — клиент SNMPv1/v2c (только чтение: Get и GetNext)
+type SNMPClient struct {
+    Target    string
+    Port      int
+    Community string
+    Version   int
+    Timeout   time.Duration
+    Retries   int
+}
+
+// NewSNMPClient создаёт клиент с таймаутом 2 с и одним повтором
+func NewSNMPClient(target, community string, version int) *SNMPClient {
+    if community == "" {
+        community = "public"
+    }
+    return &SNMPClient{
+        Target:    target,
+        Port:      DefaultSNMPPort,
+        Community: community,
+        Version:   version,
+        Timeout:   2 * time.Second,
+        Retries:   1,
+    }
+}
+
+// Get запрашивает значения перечисленных OID
+func (c *SNMPClient) Get(ctx context.Context, oids ...string) ([]SNMPVarBind, error) {
+    return c.request(ctx, snmpGetRequest, oids)
+}
+
+// GetNext возвращает следующий за oid объект
+func (c *SNMPClient) GetNext(ctx context.Context, oid string) (SNMPVarBind, error) {
+    binds, err := c.request(ctx, snmpGetNextRequest, []string{oid})
+    if err != nil {
+        return SNMPVarBind{}, err
+    }
+    if len(binds) != 1 {
+        return SNMPVarBind{}, fmt.Errorf("SNMP: ожидалась одна переменная, получено %d", len(binds))
+    }
+    return binds[0], nil
+}
+
+// Walk обходит поддерево root через GetNext (совместимо с SNMPv1)
+func (c *SNMPClient) Walk(ctx context.Context, root string) ([]SNMPVarBind, error) {
+    var result []SNMPVarBind
+    prefix := root + "."
+    current := root
+    for i := 0; i < 10000; i++ {
+        bind, err := c.GetNext(ctx, current)
+        if errors.Is(err, ErrSNMPNoSuchName) {
+            break // SNMPv1 сообщает о конце MIB ошибкой noSuchName
+        }
+        if err != nil {
+            return result, err
+        }
+        if !bind.Exists() || !strings.HasPrefix(bind.OID, prefix) || bind.OID == current {
+            break
+        }
+        result = append(result, bind)
+        current = bind.OID
+    }
+    return result, nil
+}
+
+func (c *SNMPClient) request(ctx context.Context, pduType byte, oids []string) ([]SNMPVarBind, error) {
+    requestID := rand.Int31()
+    msg, err := c.encodeRequest(pduType, requestID, oids)
+    if err != nil {
+        return nil, err
+    }
+
+    port := c.Port
+    if port == 0 {
+        port = DefaultSNMPPort
+    }
+    var dialer net.Dialer
+    conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(c.Target, strconv.Itoa(port)))
+    if err != nil {
+        return nil, fmt.Errorf("SNMP: %w", err)
+    }
+    defer conn.Close()
+
+    buf := make([]byte, 65535)
+    var lastErr error
+    for attempt := 0; attempt <= c.Retries; attempt++ {
+        if err := ctx.Err(); err != nil {
+            return nil, err
+        }
+        deadline := time.Now().Add(c.Timeout)
+        if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+            deadline = d
+        }
+        conn.SetDeadline(deadline)
+
+        if _, err := conn.Write(msg); err != nil {
+            return nil, fmt.Errorf("SNMP: %w", err)
+        }
+        for {
+            n, err := conn.Read(buf)
+            if err != nil {
+                lastErr = fmt.Errorf("SNMP: агент %s не ответил: %w", c.Target, err)
+                break
+            }
+            id, binds, err := decodeSNMPResponse(buf[:n])
+            if err != nil {
+                var snmpErr *SNMPError
+                if errors.As(err, &snmpErr) && id == requestID {
+                    return nil, err
+                }
+                continue // чужой или повреждённый пакет — ждём дальше
+            }
+            if id != requestID {
+                continue
+            }
+            return binds, nil
+        }
+    }
+    return nil, lastErr
+}
+
+func (c *SNMPClient) encodeRequest(pduType byte, requestID int32, oids []string) ([]byte, error) {
+    var varbinds []byte
+    for _, oid := range oids {
+        encoded, err := berEncodeOID(oid)
+        if err != nil {
+            return nil, err
+        }
+        varbinds = append(varbinds, berTLV(berSequence, append(encoded, berNull, 0))...)
+    }
+
+    pdu := berInt(int64(requestID))
+    pdu = append(pdu, berInt(0)...) // error-status
+    pdu = append(pdu, berInt(0)...) // error-index
+    pdu = append(pdu, berTLV(berSequence, varbinds)...)
+
+    msg := berInt(int64(c.Version))
+    msg = append(msg, berTLV(berOctetString, []byte(c.Community))...)
+    msg = append(msg, berTLV(pduType, pdu)...)
+    return berTLV(berSequence, msg), nil
+}
+
+// decodeSNMPResponse разбирает GetResponse: request-id и переменные
+func decodeSNMPResponse(data []byte) (int32, []SNMPVarBind, error) {
+    tag, msg, _, err := berRead(data)
+    if err != nil || tag != berSequence {
+        return 0, nil, errors.New("SNMP: некорректное сообщение")
+    }
+    // version, community
+    for i := 0; i < 2; i++ {
+        if _, _, msg, err = berRead(msg); err != nil {
+            return 0, nil, err
+        }
+    }
+    tag, pdu, _, err := berRead(msg)
+    if err != nil || tag != snmpResponse {
+        return 0, nil, errors.New("SNMP: ожидался GetResponse")
+    }
+
+    var fields [3]int64
+    for i := range fields {
+        var raw []byte
+        if tag, raw, pdu, err = berRead(pdu); err != nil || tag != berInteger {
+            return 0, nil, errors.New("SNMP: некорректный заголовок PDU")
+        }
+        fields[i] = berParseInt(raw)
+    }
+    requestID := int32(fields[0])
+    if fields[1] != 0 {
+        return requestID, nil, &SNMPError{Status: int(fields[1]), Index: int(fields[2])}
+    }
+
+    tag, list, _, err := berRead(pdu)
+    if err != nil || tag != berSequence {
+        return requestID, nil, errors.New("SNMP: некорректный список переменных")
+    }
+    var binds []SNMPVarBind
+    for len(list) > 0 {
+        var item []byte
+        if tag, item, list, err = berRead(list); err != nil || tag != berSequence {
+            return requestID, nil, errors.New("SNMP: некорректная переменная")
+        }
+        tag, rawOID, rest, err := berRead(item)
+        if err != nil || tag != berOID {
+            return requestID, nil, errors.New("SNMP: некорректный OID")
+        }
+        valueTag, rawValue, _, err := berRead(rest)
+        if err != nil {
+            return requestID, nil, err
+        }
+        binds = append(binds, SNMPVarBind{
+            OID:   berParseOID(rawOID),
+            Type:  valueTag,
+            Value: berParseValue(valueTag, rawValue),
+        })
+    }
+    return requestID, binds, nil
+}
+
+func berParseValue(tag byte, raw []byte) interface{} {
+    switch tag {
+    case berInteger:
+        return berParseInt(raw)
+    case snmpCounter32, snmpGauge32, snmpTimeTicks, snmpCounter64:
+        var n uint64
+        for _, b := range raw {
+            n = n<<8 | uint64(b)
+        }
+        return n
+    case berOctetString, snmpOpaque:
+        return append([]byte(nil), raw...)
+    case snmpIPAddress:
+        if len(raw) == 4 {
+            return net.IP(raw).String()
+        }
+        return append([]byte(nil), raw...)
+    case berOID:
+        return berParseOID(raw)
+    }
+    return nil
+}
+
+// berRead читает один TLV и возвращает тег, значение и остаток
+func berRead(data []byte) (tag byte, value, rest []byte, err error) {
+    if len(data) < 2 {
+        return 0, nil, nil, errors.New("BER: неожиданный конец данных")
+    }
+    tag = data[0]
+    length := int(data[1])
+    offset := 2
+    if length&0x80 != 0 {
+        n := length & 0x7f
+        if n == 0 || n > 4 || len(data) < 2+n {
+            return 0, nil, nil, errors.New("BER: некорректная длина")
+        }
+        length = 0
+        for _, b := range data[2 : 2+n] {
+            length = length<<8 | int(b)
+        }
+        offset += n
+    }
+    if length < 0 || len(data)-offset < length {
+        return 0, nil, nil, errors.New("BER: длина больше данных")
+    }
+    return tag, data[offset : offset+length], data[offset+length:], nil
+}
+
+func berTLV(tag byte, value []byte) []byte {
+    out := []byte{tag}
+    switch n := len(value); {
+    case n < 0x80:
+        out = append(out, byte(n))
+    case n <= 0xff:
+        out = append(out, 0x81, byte(n))
+    case n <= 0xffff:
+        out = append(out, 0x82, byte(n>>8), byte(n))
+    default:
+        out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+    }
+    return append(out, value...)
+}
+
+// berInt кодирует INTEGER в минимальном дополнительном коде
+func berInt(v int64) []byte {
+    var b []byte
+    for {
+        b = append([]byte{byte(v)}, b...)
+        v >>= 8
+        if (v == 0 && b[0]&0x80 == 0) || (v == -1 && b[0]&0x80 != 0) {
+            break
+        }
+    }
+    return berTLV(berInteger, b)
+}
+
+func berParseInt(raw []byte) int64 {
+    var n int64
+    for i, b := range raw {
+        if i == 0 && b&0x80 != 0 {
+            n = -1
+        }
+        n = n<<8 | int64(b)
+    }
+    return n
+}
+
+func berEncodeOID(oid string) ([]byte, error) {
+    parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+    if len(parts) < 2 {
+        return nil, fmt.Errorf("некорректный OID %q", oid)
+    }
+    arcs := make([]uint64, len(parts))
+    for i, p := range parts {
+        n, err := strconv.ParseUint(p, 10, 32)
+        if err != nil {
+            return nil, fmt.Errorf("некорректный OID %q", oid)
+        }
+        arcs[i] = n
+    }
+
+    body := berBase128(arcs[0]*40 + arcs[1])
+    for _, arc := range arcs[2:] {
+        body = append(body, berBase128(arc)...)
+    }
+    return berTLV(berOID, body), nil
+}
+
+func berBase128(n uint64) []byte {
+    out := []byte{byte(n & 0x7f)}
+    for n >>= 7; n > 0; n >>= 7 {
+        out = append([]byte{byte(n&0x7f) | 0x80}, out...)
+    }
+    return out
+}
+
+func berParseOID(raw []byte) string {
+    if len(raw) == 0 {
+        return ""
+    }
+    var arcs []string
+    var n uint64
+    first := true
+    for _, b := range raw {
+        n = n<<7 | uint64(b&0x7f)
+        if b&0x80 != 0 {
+            continue
+        }
+        if first {
+            a := n / 40
+            if a > 2 {
+                a = 2
+            }
+            arcs = append(arcs, strconv.FormatUint(a, 10), strconv.FormatUint(n-a*40, 10))
+            first = false
+        } else {
+            arcs = append(arcs, strconv.FormatUint(n, 10))
+        }
+        n = 0
+    }
+    return strings.Join(arcs, ".")
+}
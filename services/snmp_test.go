@@ -0,0 +1,185 @@
+package services
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+)
+
+func TestBERInt(t *testing.T) {
+    tests := []struct {
+        v    int64
+        want []byte
+    }{
+        {0, []byte{0x02, 0x01, 0x00}},
+        {127, []byte{0x02, 0x01, 0x7f}},
+        {128, []byte{0x02, 0x02, 0x00, 0x80}},
+        {256, []byte{0x02, 0x02, 0x01, 0x00}},
+        {-1, []byte{0x02, 0x01, 0xff}},
+        {-128, []byte{0x02, 0x01, 0x80}},
+        {-129, []byte{0x02, 0x02, 0xff, 0x7f}},
+    }
+    for _, tt := range tests {
+        got := berInt(tt.v)
+        if !bytes.Equal(got, tt.want) {
+            t.Errorf("berInt(%d) = % x, want % x", tt.v, got, tt.want)
+        }
+        if back := berParseInt(got[2:]); back != tt.v {
+            t.Errorf("berParseInt(% x) = %d, want %d", got[2:], back, tt.v)
+        }
+    }
+}
+
+func TestBEROID(t *testing.T) {
+    tests := []struct {
+        oid  string
+        want []byte
+    }{
+        {"1.3.6.1.2.1.1.1.0", []byte{0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00}},
+        {".1.3.6.1.2.1.43.10.2.1.4.1.1", []byte{0x06, 0x0c, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x2b, 0x0a, 0x02, 0x01, 0x04, 0x01, 0x01}},
+        {"1.3.6.1.4.1.11.2.3.9.4.2.1.1.3.3.0", nil},
+        {"1.3.6.1.4.1.2699.1.2.1.2.1.1.2.1", nil}, // дуга больше 127 — два байта
+    }
+    for _, tt := range tests {
+        got, err := berEncodeOID(tt.oid)
+        if err != nil {
+            t.Errorf("berEncodeOID(%s): %v", tt.oid, err)
+            continue
+        }
+        if tt.want != nil && !bytes.Equal(got, tt.want) {
+            t.Errorf("berEncodeOID(%s) = % x, want % x", tt.oid, got, tt.want)
+        }
+        want := tt.oid
+        if want[0] == '.' {
+            want = want[1:]
+        }
+        if back := berParseOID(got[2:]); back != want {
+            t.Errorf("berParseOID round trip: %s, want %s", back, want)
+        }
+    }
+
+    for _, bad := range []string{"", "1", "1.3.x", "1.3.-1", "1.3.4294967296"} {
+        if _, err := berEncodeOID(bad); err == nil {
+            t.Errorf("berEncodeOID(%q) accepted", bad)
+        }
+    }
+}
+
+func TestBERRead(t *testing.T) {
+    long := bytes.Repeat([]byte{'x'}, 300)
+    tests := []struct {
+        name    string
+        data    []byte
+        value   []byte
+        rest    []byte
+        wantErr bool
+    }{
+        {"short form", []byte{0x04, 0x02, 'h', 'i', 0xff}, []byte("hi"), []byte{0xff}, false},
+        {"long form", berTLV(berOctetString, long), long, []byte{}, false},
+        {"empty value", []byte{0x05, 0x00}, []byte{}, []byte{}, false},
+        {"truncated value", []byte{0x04, 0x05, 'h', 'i'}, nil, nil, true},
+        {"truncated length", []byte{0x04, 0x82, 0x01}, nil, nil, true},
+        {"indefinite length", []byte{0x30, 0x80, 0x00, 0x00}, nil, nil, true},
+        {"too short", []byte{0x04}, nil, nil, true},
+    }
+    for _, tt := range tests {
+        _, value, rest, err := berRead(tt.data)
+        if tt.wantErr {
+            if err == nil {
+                t.Errorf("%s: want an error", tt.name)
+            }
+            continue
+        }
+        if err != nil || !bytes.Equal(value, tt.value) || !bytes.Equal(rest, tt.rest) {
+            t.Errorf("%s: value % x, rest % x, err %v", tt.name, value, rest, err)
+        }
+    }
+}
+
+// testSNMPResponse собирает GetResponse так, как его отправил бы агент
+func testSNMPResponse(requestID, errStatus, errIndex int64, binds ...[]byte) []byte {
+    pdu := append(berInt(requestID), berInt(errStatus)...)
+    pdu = append(pdu, berInt(errIndex)...)
+    pdu = append(pdu, berTLV(berSequence, bytes.Join(binds, nil))...)
+    msg := append(berInt(SNMPv2c), berTLV(berOctetString, []byte("public"))...)
+    msg = append(msg, berTLV(snmpResponse, pdu)...)
+    return berTLV(berSequence, msg)
+}
+
+func testVarBind(oid string, tag byte, value []byte) []byte {
+    encoded, _ := berEncodeOID(oid)
+    return berTLV(berSequence, append(encoded, berTLV(tag, value)...))
+}
+
+func TestDecodeSNMPResponse(t *testing.T) {
+    data := testSNMPResponse(42, 0, 0,
+        testVarBind("1.3.6.1.2.1.1.5.0", berOctetString, []byte("printer-1\x00")),
+        testVarBind("1.3.6.1.2.1.43.10.2.1.4.1.1", snmpCounter32, []byte{0x00, 0xff, 0xff, 0xff, 0xff}),
+        testVarBind("1.3.6.1.2.1.25.3.5.1.1.1", berInteger, []byte{0x03}),
+        testVarBind("1.3.6.1.2.1.25.3.2.1.3.1", snmpNoSuchInstance, nil),
+        testVarBind("1.3.6.1.2.1.4.20.1.1.0", snmpIPAddress, []byte{192, 168, 1, 10}),
+    )
+    id, binds, err := decodeSNMPResponse(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if id != 42 || len(binds) != 5 {
+        t.Fatalf("request id %d, %d bindings", id, len(binds))
+    }
+    if binds[0].OID != "1.3.6.1.2.1.1.5.0" || binds[0].String() != "printer-1" {
+        t.Errorf("sysName = %s %q", binds[0].OID, binds[0].String())
+    }
+    if n, ok := binds[1].Int(); !ok || n != 0xffffffff {
+        t.Errorf("page counter = %d, %v", n, ok)
+    }
+    if n, ok := binds[2].Int(); !ok || n != 3 {
+        t.Errorf("printer status = %d, %v", n, ok)
+    }
+    if binds[3].Exists() || !binds[2].Exists() {
+        t.Errorf("Exists: noSuchInstance %v, integer %v", binds[3].Exists(), binds[2].Exists())
+    }
+    if binds[4].String() != "192.168.1.10" {
+        t.Errorf("IpAddress = %q", binds[4].String())
+    }
+}
+
+func TestDecodeSNMPResponseErrors(t *testing.T) {
+    _, _, err := decodeSNMPResponse(testSNMPResponse(7, 2, 1))
+    var snmpErr *SNMPError
+    if !errors.As(err, &snmpErr) || snmpErr.Index != 1 || !errors.Is(err, ErrSNMPNoSuchName) {
+        t.Errorf("noSuchName: err = %v", err)
+    }
+    if _, _, err := decodeSNMPResponse(testSNMPResponse(7, 5, 1)); errors.Is(err, ErrSNMPNoSuchName) || err == nil {
+        t.Errorf("genErr: err = %v", err)
+    }
+
+    request, _ := NewSNMPClient("127.0.0.1", "", SNMPv1).encodeRequest(snmpGetRequest, 1, []string{"1.3.6.1.2.1.1.1.0"})
+    full := testSNMPResponse(1, 0, 0, testVarBind("1.3.6.1.2.1.1.1.0", berInteger, []byte{1}))
+    for name, data := range map[string][]byte{
+        "empty":     nil,
+        "request":   request,
+        "truncated": full[:len(full)-3],
+    } {
+        if _, _, err := decodeSNMPResponse(data); err == nil {
+            t.Errorf("%s: decoded without an error", name)
+        }
+    }
+}
+
+func TestSNMPEncodeRequest(t *testing.T) {
+    c := NewSNMPClient("127.0.0.1", "", SNMPv2c)
+    data, err := c.encodeRequest(snmpGetNextRequest, 9, []string{"1.3.6.1.2.1.43.11"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Тот же разборщик должен прочитать запрос, если подменить тип PDU на ответ
+    i := bytes.IndexByte(data, snmpGetNextRequest)
+    if i < 0 || !bytes.Contains(data, []byte("public")) {
+        t.Fatalf("unexpected request % x", data)
+    }
+    data[i] = snmpResponse
+    id, binds, err := decodeSNMPResponse(data)
+    if err != nil || id != 9 || len(binds) != 1 || binds[0].OID != "1.3.6.1.2.1.43.11" || binds[0].Exists() {
+        t.Errorf("request decoded as %d %+v %v", id, binds, err)
+    }
+}
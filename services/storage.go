@@ -0,0 +1,172 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "print-automation/config"
+)
+
+// ErrBlobNotFound — объект отсутствует в хранилище
+var ErrBlobNotFound = errors.New("документ не найден в хранилище")
+
+// BlobStore — хранилище загруженных документов
+type BlobStore interface {
+    Put(ctx context.Context, key string, r io.Reader, size int64) error
+    Get(ctx context.Context, key string) (io.ReadCloser, error)
+    Delete(ctx context.Context, key string) error
+}
+
+// localPather реализуют хранилища, которые могут отдать путь к файлу без копирования
+type localPather interface {
+    LocalPath(key string) string
+}
+
+var documentStore BlobStore
+
+// InitDocumentStore выбирает хранилище документов по STORAGE_DRIVER ("local" или "s3")
+func InitDocumentStore() error {
+    switch driver := config.GetEnv("STORAGE_DRIVER", "local"); driver {
+    case "local":
+        store, err := NewLocalBlobStore(config.GetEnv("STORAGE_PATH", "./storage"))
+        if err != nil {
+            return err
+        }
+        documentStore = store
+    case "s3":
+        store, err := NewS3BlobStore(S3Config{
+            Endpoint:  config.GetEnv("S3_ENDPOINT", ""),
+            Region:    config.GetEnv("S3_REGION", "us-east-1"),
+            Bucket:    config.GetEnv("S3_BUCKET", ""),
+            AccessKey: config.GetEnv("S3_ACCESS_KEY", ""),
+            SecretKey: config.GetEnv("S3_SECRET_KEY", ""),
+        })
+        if err != nil {
+            return err
+        }
+        documentStore = store
+    default:
+        return fmt.Errorf("неизвестный STORAGE_DRIVER %q", driver)
+    }
+    return nil
+}
+
+// DocumentStore возвращает текущее хранилище документов
+func DocumentStore() BlobStore {
+    return documentStore
+}
+
+// SetDocumentStore подменяет хранилище (например, в тестах)
+func SetDocumentStore(store BlobStore) {
+    documentStore = store
+}
+
+// LocalBlobStore хранит документы в каталоге на диске
+type LocalBlobStore struct {
+    Root string
+}
+
+// NewLocalBlobStore создаёт каталог хранилища, если его нет
+func NewLocalBlobStore(root string) (*LocalBlobStore, error) {
+    if err := os.MkdirAll(root, 0o750); err != nil {
+        return nil, fmt.Errorf("не удалось создать каталог хранилища %s: %w", root, err)
+    }
+    return &LocalBlobStore{Root: root}, nil
+}
+
+func (s *LocalBlobStore) LocalPath(key string) string {
+    // Clean от корня не даёт ключу выйти за пределы каталога хранилища
+    return filepath.Join(s.Root, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+    path := s.LocalPath(key)
+    if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+        return err
+    }
+
+    // Пишем во временный файл и переименовываем, чтобы не оставить обрезанный документ
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+    if err != nil {
+        return err
+    }
+    if _, err := io.Copy(tmp, r); err != nil {
+        tmp.Close()
+        os.Remove(tmp.Name())
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        os.Remove(tmp.Name())
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    f, err := os.Open(s.LocalPath(key))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, ErrBlobNotFound
+    }
+    return f, err
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+    err := os.Remove(s.LocalPath(key))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil
+    }
+    return err
+}
+
+// OpenBlobAsFile даёт путь к документу на локальном диске.
+// Для удалённых хранилищ документ копируется во временный файл, который удалит cleanup.
+func OpenBlobAsFile(ctx context.Context, store BlobStore, key string) (path string, cleanup func(), err error) {
+    if lp, ok := store.(localPather); ok {
+        path = lp.LocalPath(key)
+        if _, err := os.Stat(path); err != nil {
+            return "", nil, Permanent(ErrBlobNotFound)
+        }
+        return path, func() {}, nil
+    }
+
+    rc, err := store.Get(ctx, key)
+    if err != nil {
+        if errors.Is(err, ErrBlobNotFound) {
+            return "", nil, Permanent(err)
+        }
+        return "", nil, err
+    }
+    defer rc.Close()
+
+    tmp, err := os.CreateTemp("", "print-*"+filepath.Ext(key))
+    if err != nil {
+        return "", nil, err
+    }
+    if _, err := io.Copy(tmp, rc); err != nil {
+        tmp.Close()
+        os.Remove(tmp.Name())
+        return "", nil, err
+    }
+    tmp.Close()
+    return tmp.Name(), func() { os.Remove(tmp.Name()) }, nil
+}
+
+// sanitizeFileName оставляет только имя файла без пути и управляющих символов
+func sanitizeFileName(name string) string {
+    name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+    name = strings.Map(func(r rune) rune {
+        if r < 0x20 || r == 0x7f {
+            return -1
+        }
+        return r
+    }, name)
+    if name == "." || name == "/" {
+        return ""
+    }
+    return truncateRunes(name, 255)
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+)
+
+// S3Config — параметры S3-совместимого хранилища (AWS S3, MinIO, Ceph RGW)
+type S3Config struct {
+    Endpoint  string
+    Region    string
+    Bucket    string
+    AccessKey string
+    SecretKey string
+}
+
+// S3BlobStore хранит документы в бакете S3. Запросы подписываются AWS Signature V4,
+// адресация path-style (endpoint/bucket/key) — её поддерживают все совместимые серверы.
+type S3BlobStore struct {
+    cfg        S3Config
+    endpoint   *url.URL
+    HTTPClient *http.Client
+}
+
+// NewS3BlobStore проверяет конфигурацию и создаёт хранилище
+func NewS3BlobStore(cfg S3Config) (*S3BlobStore, error) {
+    if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+        return nil, fmt.Errorf("для S3 нужны S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY и S3_SECRET_KEY")
+    }
+    endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+    if err != nil || endpoint.Host == "" {
+        return nil, fmt.Errorf("некорректный S3_ENDPOINT %q", cfg.Endpoint)
+    }
+    if cfg.Region == "" {
+        cfg.Region = "us-east-1"
+    }
+    return &S3BlobStore{
+        cfg:        cfg,
+        endpoint:   endpoint,
+        HTTPClient: &http.Client{Timeout: 10 * time.Minute},
+    }, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+    req, err := s.newRequest(ctx, http.MethodPut, key, r)
+    if err != nil {
+        return err
+    }
+    req.ContentLength = size
+    resp, err := s.do(req)
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    return nil
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := s.do(req)
+    if err != nil {
+        return nil, err
+    }
+    return resp.Body, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+    req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+    if err != nil {
+        return err
+    }
+    resp, err := s.do(req)
+    if err != nil {
+        if err == ErrBlobNotFound {
+            return nil
+        }
+        return err
+    }
+    resp.Body.Close()
+    return nil
+}
+
+func (s *S3BlobStore) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+    u := *s.endpoint
+    u.Path = s.endpoint.Path + "/" + s.cfg.Bucket + "/" + strings.TrimLeft(key, "/")
+    u.RawPath = s3URIEncode(u.Path)
+    req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+    if err != nil {
+        return nil, err
+    }
+    s.sign(req, time.Now().UTC())
+    return req, nil
+}
+
+func (s *S3BlobStore) do(req *http.Request) (*http.Response, error) {
+    resp, err := s.HTTPClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка запроса к S3: %w", err)
+    }
+    if resp.StatusCode == http.StatusNotFound {
+        resp.Body.Close()
+        return nil, ErrBlobNotFound
+    }
+    if resp.StatusCode >= 300 {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+        resp.Body.Close()
+        return nil, fmt.Errorf("S3 вернул HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+    }
+    return resp, nil
+}
+
+// sign добавляет заголовок Authorization по схеме AWS Signature V4.
+// Тело не хэшируется (UNSIGNED-PAYLOAD), чтобы документы можно было передавать потоком.
+func (s *S3BlobStore) sign(req *http.Request, now time.Time) {
+    amzDate := now.Format("20060102T150405Z")
+    day := now.Format("20060102")
+    const payloadHash = "UNSIGNED-PAYLOAD"
+
+    req.Header.Set("x-amz-date", amzDate)
+    req.Header.Set("x-amz-content-sha256", payloadHash)
+
+    canonicalHeaders := "host:" + req.URL.Host + "\n" +
+        "x-amz-content-sha256:" + payloadHash + "\n" +
+        "x-amz-date:" + amzDate + "\n"
+    signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+    canonicalRequest := strings.Join([]string{
+        req.Method,
+        req.URL.EscapedPath(),
+        req.URL.RawQuery,
+        canonicalHeaders,
+        signedHeaders,
+        payloadHash,
+    }, "\n")
+
+    scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+    stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+    key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), day)
+    key = hmacSHA256(key, s.cfg.Region)
+    key = hmacSHA256(key, "s3")
+    key = hmacSHA256(key, "aws4_request")
+    signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+    req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// s3URIEncode кодирует путь по правилам SigV4: всё, кроме A-Z a-z 0-9 - _ . ~ и "/"
+func s3URIEncode(path string) string {
+    var b strings.Builder
+    for i := 0; i < len(path); i++ {
+        c := path[i]
+        if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+            c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+            b.WriteByte(c)
+            continue
+        }
+        fmt.Fprintf(&b, "%%%02X", c)
+    }
+    return b.String()
+}
+
+func sha256Hex(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(data))
+    return mac.Sum(nil)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "io"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+    ctx := context.Background()
+    store, err := NewLocalBlobStore(filepath.Join(t.TempDir(), "blobs"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    const key = "jobs/42/abc.pdf"
+    if err := store.Put(ctx, key, strings.NewReader("%PDF-1.7"), 8); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    rc, err := store.Get(ctx, key)
+    if err != nil {
+        t.Fatalf("Get: %v", err)
+    }
+    data, _ := io.ReadAll(rc)
+    rc.Close()
+    if string(data) != "%PDF-1.7" {
+        t.Errorf("Get = %q", data)
+    }
+
+    path, cleanup, err := OpenBlobAsFile(ctx, store, key)
+    if err != nil {
+        t.Fatalf("OpenBlobAsFile: %v", err)
+    }
+    cleanup()
+    if path != store.LocalPath(key) {
+        t.Errorf("OpenBlobAsFile path = %s, want the stored file", path)
+    }
+
+    if err := store.Delete(ctx, key); err != nil {
+        t.Fatalf("Delete: %v", err)
+    }
+    if err := store.Delete(ctx, key); err != nil {
+        t.Errorf("second Delete: %v", err)
+    }
+    if _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+        t.Errorf("Get after Delete: err = %v, want ErrBlobNotFound", err)
+    }
+    if _, _, err := OpenBlobAsFile(ctx, store, key); IsTransientSendError(err) || !errors.Is(err, ErrBlobNotFound) {
+        t.Errorf("OpenBlobAsFile after Delete: err = %v, want permanent ErrBlobNotFound", err)
+    }
+}
+
+func TestLocalBlobStoreKeepsKeysInsideRoot(t *testing.T) {
+    root := t.TempDir()
+    store := &LocalBlobStore{Root: root}
+    for _, key := range []string{"../../etc/passwd", "/abs/path", "jobs/../../x", "a/./b"} {
+        path := store.LocalPath(key)
+        rel, err := filepath.Rel(root, path)
+        if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+            t.Errorf("LocalPath(%q) = %s escapes %s", key, path, root)
+        }
+    }
+}
+
+func TestSanitizeFileName(t *testing.T) {
+    tests := []struct {
+        in, want string
+    }{
+        {"report.pdf", "report.pdf"},
+        {"../../etc/passwd", "passwd"},
+        {`C:\Users\me\отчёт.pdf`, "отчёт.pdf"},
+        {"bad\nname\x00.txt", "badname.txt"},
+        {"/", ""},
+        {strings.Repeat("я", 300) + ".pdf", strings.Repeat("я", 255)},
+    }
+    for _, tt := range tests {
+        if got := sanitizeFileName(tt.in); got != tt.want {
+            t.Errorf("sanitizeFileName(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
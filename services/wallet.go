@@ -0,0 +1,273 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "math"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+    "print-automation/config"
+    "print-automation/models"
+)
+
+// ErrInsufficientFunds — на балансе не хватает средств; это частный случай ErrPaymentRequired
+var ErrInsufficientFunds = fmt.Errorf("%w: недостаточно средств на балансе", ErrPaymentRequired)
+
+// LedgerLeg — одна сторона операции: счёт и изменение его остатка
+type LedgerLeg struct {
+    Account string
+    Amount  float64
+}
+
+// ledgerOperation — общие реквизиты проводок одной операции
+type ledgerOperation struct {
+    Kind        string
+    PrintJobID  string
+    PaymentID   string
+    Description string
+    Actor       string
+}
+
+// postLedger записывает операцию из нескольких проводок с нулевой суммой
+func postLedger(tx *gorm.DB, op ledgerOperation, legs ...LedgerLeg) error {
+    entries, err := ledgerEntries(op, legs)
+    if err != nil {
+        return err
+    }
+    for i := range entries {
+        if err := tx.Create(&entries[i]).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// ledgerEntries собирает проводки операции. Баланс проверяется уже после
+// округления до копеек: именно эти суммы попадут в журнал.
+func ledgerEntries(op ledgerOperation, legs []LedgerLeg) ([]models.LedgerEntry, error) {
+    if len(legs) < 2 {
+        return nil, fmt.Errorf("операция %s должна состоять хотя бы из двух проводок", op.Kind)
+    }
+    if op.Actor == "" {
+        op.Actor = ActorSystem
+    }
+
+    transactionID := uuid.New().String()
+    entries := make([]models.LedgerEntry, 0, len(legs))
+    sum := 0.0
+    for _, leg := range legs {
+        amount := roundMoney(leg.Amount)
+        if math.Abs(amount) < moneyEpsilon {
+            return nil, fmt.Errorf("нулевая проводка по счёту %s в операции %s", leg.Account, op.Kind)
+        }
+        sum += amount
+        entries = append(entries, models.LedgerEntry{
+            TransactionID: transactionID,
+            Account:       leg.Account,
+            Amount:        amount,
+            Kind:          op.Kind,
+            PrintJobID:    op.PrintJobID,
+            PaymentID:     op.PaymentID,
+            Description:   truncateRunes(op.Description, 500),
+            CreatedBy:     op.Actor,
+        })
+    }
+    if math.Abs(sum) >= moneyEpsilon {
+        return nil, fmt.Errorf("несбалансированная операция %s: сумма проводок %.2f", op.Kind, sum)
+    }
+    return entries, nil
+}
+
+// accountBalance — остаток счёта как сумма его проводок
+func accountBalance(tx *gorm.DB, account string) (float64, error) {
+    var balance float64
+    err := tx.Model(&models.LedgerEntry{}).
+        Select("COALESCE(SUM(amount), 0)").
+        Where("account = ?", account).
+        Scan(&balance).Error
+    return roundMoney(balance), err
+}
+
+// lockWallet блокирует строку пользователя до конца транзакции,
+// чтобы параллельные списания не ушли в минус
+func lockWallet(tx *gorm.DB, userID string) error {
+    var user models.User
+    return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, "id = ?", userID).Error
+}
+
+// WalletBalance возвращает баланс кошелька пользователя
+func WalletBalance(userID string) (float64, error) {
+    return accountBalance(config.DB, models.UserAccount(userID))
+}
+
+// creditTopUp зачисляет успешное пополнение на кошелёк. Повторный вызов
+// для того же платежа ничего не делает.
+func creditTopUp(payment *models.Payment) error {
+    return config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := lockWallet(tx, payment.UserID); err != nil {
+            return err
+        }
+        var count int64
+        err := tx.Model(&models.LedgerEntry{}).
+            Where("payment_id = ? AND kind = ?", payment.ID, models.LedgerKindTopUp).
+            Count(&count).Error
+        if err != nil || count > 0 {
+            return err
+        }
+        return postLedger(tx, ledgerOperation{
+            Kind:        models.LedgerKindTopUp,
+            PaymentID:   payment.ID,
+            Description: "пополнение через " + payment.Provider,
+        },
+            LedgerLeg{Account: models.UserAccount(payment.UserID), Amount: payment.Amount},
+            LedgerLeg{Account: models.LedgerAccountCash, Amount: -payment.Amount},
+        )
+    })
+}
+
+// debitTopUpRefund списывает с кошелька сумму, возвращаемую по пополнению
+func debitTopUpRefund(payment *models.Payment, amount float64, reason string) error {
+    return config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := lockWallet(tx, payment.UserID); err != nil {
+            return err
+        }
+        balance, err := accountBalance(tx, models.UserAccount(payment.UserID))
+        if err != nil {
+            return err
+        }
+        if balance+moneyEpsilon < amount {
+            return ErrInsufficientFunds
+        }
+        return postLedger(tx, ledgerOperation{
+            Kind:        models.LedgerKindRefund,
+            PaymentID:   payment.ID,
+            Description: reason,
+        },
+            LedgerLeg{Account: models.UserAccount(payment.UserID), Amount: -amount},
+            LedgerLeg{Account: models.LedgerAccountCash, Amount: amount},
+        )
+    })
+}
+
+// revertTopUpRefund возвращает на кошелёк сумму, возврат которой провайдер не провёл
+func revertTopUpRefund(payment *models.Payment, amount float64, reason string) error {
+    return config.DB.Transaction(func(tx *gorm.DB) error {
+        return postLedger(tx, ledgerOperation{
+            Kind:        models.LedgerKindAdjustment,
+            PaymentID:   payment.ID,
+            Description: reason,
+        },
+            LedgerLeg{Account: models.UserAccount(payment.UserID), Amount: amount},
+            LedgerLeg{Account: models.LedgerAccountCash, Amount: -amount},
+        )
+    })
+}
+
+// chargeWallet списывает стоимость задания с кошелька владельца.
+// Пустой кошелёк не используется (false, nil); неполного остатка — ErrInsufficientFunds.
+func chargeWallet(job *models.PrintJob, actor string) (bool, error) {
+    charged := false
+    err := config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := lockWallet(tx, job.UserID); err != nil {
+            return err
+        }
+        balance, err := accountBalance(tx, models.UserAccount(job.UserID))
+        if err != nil {
+            return err
+        }
+        if balance < moneyEpsilon {
+            return nil
+        }
+        if balance+moneyEpsilon < job.Cost {
+            return ErrInsufficientFunds
+        }
+
+        err = postLedger(tx, ledgerOperation{
+            Kind:        models.LedgerKindJobCharge,
+            PrintJobID:  job.ID,
+            Description: fmt.Sprintf("печать задания %s", job.ID),
+            Actor:       actor,
+        }, jobChargeLegs(job.UserID, job.Cost)...)
+        if err != nil {
+            return err
+        }
+        job.WalletCharge = job.Cost
+        if err := tx.Model(job).Select("WalletCharge", "UpdatedAt").Updates(job).Error; err != nil {
+            job.WalletCharge = 0
+            return err
+        }
+        charged = true
+        return nil
+    })
+    return charged, err
+}
+
+// refundToWallet возвращает на кошелёк часть списанной за задание суммы.
+// Вернуть больше, чем было списано, нельзя: учитываются прежние возвраты.
+func refundToWallet(job *models.PrintJob, amount float64, reason string) error {
+    if job.WalletCharge < moneyEpsilon {
+        return nil
+    }
+    return config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := lockWallet(tx, job.UserID); err != nil {
+            return err
+        }
+        var refunded float64
+        err := tx.Model(&models.LedgerEntry{}).
+            Select("COALESCE(SUM(amount), 0)").
+            Where("account = ? AND print_job_id = ? AND kind = ?", models.UserAccount(job.UserID), job.ID, models.LedgerKindRefund).
+            Scan(&refunded).Error
+        if err != nil {
+            return err
+        }
+        if left := roundMoney(job.WalletCharge - refunded); amount > left {
+            amount = left
+        }
+        if amount < moneyEpsilon {
+            return nil
+        }
+        return postLedger(tx, ledgerOperation{
+            Kind:        models.LedgerKindRefund,
+            PrintJobID:  job.ID,
+            Description: reason,
+        }, jobChargeLegs(job.UserID, -amount)...)
+    })
+}
+
+// jobChargeLegs — проводки оплаты задания с кошелька; отрицательная сумма — возврат
+func jobChargeLegs(userID string, amount float64) []LedgerLeg {
+    return []LedgerLeg{
+        {Account: models.UserAccount(userID), Amount: -amount},
+        {Account: models.LedgerAccountRevenue, Amount: amount},
+    }
+}
+
+// AdjustBalance — ручная корректировка баланса администратором. Уйти в минус нельзя.
+func AdjustBalance(userID string, amount float64, reason, actor string) error {
+    amount = roundMoney(amount)
+    if math.Abs(amount) < moneyEpsilon {
+        return errors.New("сумма корректировки не может быть нулевой")
+    }
+    return config.DB.Transaction(func(tx *gorm.DB) error {
+        if err := lockWallet(tx, userID); err != nil {
+            return err
+        }
+        balance, err := accountBalance(tx, models.UserAccount(userID))
+        if err != nil {
+            return err
+        }
+        if balance+amount < -moneyEpsilon {
+            return ErrInsufficientFunds
+        }
+        return postLedger(tx, ledgerOperation{
+            Kind:        models.LedgerKindAdjustment,
+            Description: reason,
+            Actor:       actor,
+        },
+            LedgerLeg{Account: models.UserAccount(userID), Amount: amount},
+            LedgerLeg{Account: models.LedgerAccountAdjustments, Amount: -amount},
+        )
+    })
+}
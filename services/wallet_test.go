@@ -0,0 +1,90 @@
+package services
+
+import (
+    "errors"
+    "math"
+    "strings"
+    "testing"
+
+    "print-automation/models"
+)
+
+func TestLedgerEntries(t *testing.T) {
+    user := models.UserAccount("u1")
+    tests := []struct {
+        name  string
+        legs  []LedgerLeg
+        valid bool
+    }{
+        {"top-up", []LedgerLeg{{user, 100}, {models.LedgerAccountCash, -100}}, true},
+        {"job charge", []LedgerLeg{{user, -12.35}, {models.LedgerAccountRevenue, 12.35}}, true},
+        {"three legs", []LedgerLeg{{user, -10}, {models.LedgerAccountRevenue, 7.5}, {models.LedgerAccountAdjustments, 2.5}}, true},
+        {"float noise is rounded away", []LedgerLeg{{user, 0.1 + 0.2}, {models.LedgerAccountCash, -0.3}}, true},
+        {"single leg", []LedgerLeg{{user, 5}}, false},
+        {"no legs", nil, false},
+        {"unbalanced", []LedgerLeg{{user, 10}, {models.LedgerAccountCash, -9.99}}, false},
+        // Каждая сторона в пределах копейки, но после округления остаётся копейка
+        {"balanced only before rounding", []LedgerLeg{{user, 0.333}, {user, 0.333}, {models.LedgerAccountCash, -0.666}}, false},
+        {"zero leg", []LedgerLeg{{user, 0.001}, {models.LedgerAccountCash, -0.001}}, false},
+    }
+    for _, tt := range tests {
+        entries, err := ledgerEntries(ledgerOperation{Kind: models.LedgerKindTopUp}, tt.legs)
+        if !tt.valid {
+            if err == nil {
+                t.Errorf("%s: got %d entries, want an error", tt.name, len(entries))
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("%s: %v", tt.name, err)
+            continue
+        }
+
+        // Инвариант журнала: сумма проводок операции в копейках равна нулю
+        cents := 0
+        for _, e := range entries {
+            cents += int(math.Round(e.Amount * 100))
+            if e.TransactionID != entries[0].TransactionID || e.TransactionID == "" {
+                t.Errorf("%s: entries do not share a transaction: %q vs %q", tt.name, e.TransactionID, entries[0].TransactionID)
+            }
+        }
+        if cents != 0 || len(entries) != len(tt.legs) {
+            t.Errorf("%s: %d entries summing to %d cents", tt.name, len(entries), cents)
+        }
+    }
+}
+
+func TestLedgerEntriesFields(t *testing.T) {
+    op := ledgerOperation{
+        Kind:        models.LedgerKindJobCharge,
+        PrintJobID:  "job-1",
+        Description: strings.Repeat("я", 600),
+    }
+    entries, err := ledgerEntries(op, []LedgerLeg{{models.UserAccount("u1"), -1}, {models.LedgerAccountRevenue, 1}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, e := range entries {
+        if e.CreatedBy != ActorSystem {
+            t.Errorf("CreatedBy = %q, want %q", e.CreatedBy, ActorSystem)
+        }
+        if e.Kind != models.LedgerKindJobCharge || e.PrintJobID != "job-1" {
+            t.Errorf("operation details lost: %+v", e)
+        }
+        if n := len([]rune(e.Description)); n != 500 {
+            t.Errorf("description has %d runes, want 500", n)
+        }
+    }
+
+    first, _ := ledgerEntries(op, []LedgerLeg{{"a", 1}, {"b", -1}})
+    second, _ := ledgerEntries(op, []LedgerLeg{{"a", 1}, {"b", -1}})
+    if first[0].TransactionID == second[0].TransactionID {
+        t.Error("two operations share a transaction id")
+    }
+}
+
+func TestInsufficientFundsRequiresPayment(t *testing.T) {
+    if !errors.Is(ErrInsufficientFunds, ErrPaymentRequired) {
+        t.Error("ErrInsufficientFunds must be an ErrPaymentRequired")
+    }
+}